@@ -0,0 +1,22 @@
+package main
+
+import (
+	"flag"
+	"log/slog"
+	"net/http"
+	"os"
+
+	"github.com/paluras/product-recall-system/internal/scraper/scrapertest"
+)
+
+// fakeansvsa serves the recorded ANSVSA pages so the scraper and notifier
+// can be run against a local database without touching the real site.
+func main() {
+	addr := flag.String("addr", "localhost:8089", "Address to listen on")
+	flag.Parse()
+
+	slog.Info("serving the fake listing", "url", "http://"+*addr+scrapertest.ListingPath)
+	err := http.ListenAndServe(*addr, scrapertest.NewHandler())
+	slog.Error("server stopped", "err", err)
+	os.Exit(1)
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strconv"
+
+	"github.com/paluras/product-recall-system/configs"
+)
+
+// configCommand prints the merged configuration as a config file, with
+// secrets left out, or the value of one setting.
+func configCommand(args []string) error {
+	switch len(args) {
+	case 0:
+		flag.VisitAll(func(f *flag.Flag) {
+			switch {
+			case f.Name == "config":
+			case configs.Secret(f.Name):
+				set := f.Value.String() != ""
+				fmt.Printf("# %s is set: %t\n", f.Name, set)
+			default:
+				fmt.Printf("%s = %s\n", f.Name, strconv.Quote(f.Value.String()))
+			}
+		})
+		return nil
+	case 1:
+		f := flag.Lookup(args[0])
+		if f == nil || f.Name == "config" {
+			return fmt.Errorf("unknown setting %q", args[0])
+		}
+		if configs.Secret(f.Name) {
+			return fmt.Errorf("%s is a secret and is not printed", f.Name)
+		}
+		fmt.Println(f.Value.String())
+		return nil
+	}
+	return errUsage
+}
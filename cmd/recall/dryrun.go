@@ -0,0 +1,49 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"text/tabwriter"
+
+	"github.com/paluras/product-recall-system/configs"
+	"github.com/paluras/product-recall-system/internal/scraper"
+)
+
+// dryRun scrapes the listing and prints what was parsed. It needs no
+// database: nothing is stored and the listing is always fetched in full.
+func dryRun(ctx context.Context, conf *configs.Config, logger *slog.Logger) error {
+	client := scraper.NewClient(scraper.ClientConfig{
+		UserAgent:  conf.UserAgent,
+		Timeout:    conf.HTTPTimeout,
+		Retries:    conf.HTTPRetries,
+		CrawlDelay: conf.CrawlDelay,
+		Logger:     logger,
+	})
+	items, err := scraper.New(client, conf.ListingURL).Scrape(ctx)
+	if err != nil {
+		return err
+	}
+
+	switch conf.Output {
+	case "json":
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(items)
+	case "table":
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "DATE\tTITLE\tLINK")
+		for _, item := range items {
+			date := "-"
+			if !item.Date.IsZero() {
+				date = item.Date.Format("2006-01-02")
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\n", date, item.Title, item.Link)
+		}
+		return w.Flush()
+	default:
+		return fmt.Errorf("unknown output %q, want table or json", conf.Output)
+	}
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/paluras/product-recall-system/configs"
+	"github.com/paluras/product-recall-system/internal/models"
+)
+
+// gdprCommand handles data requests received by other channels than the
+// preferences page. Erasure is subscribers remove.
+func gdprCommand(ctx context.Context, conf *configs.Config, logger *slog.Logger, args []string) error {
+	if len(args) == 0 {
+		return errUsage
+	}
+
+	db, err := models.NewDB(conf.DSN(), logger)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	switch args[0] {
+	case "export":
+		return exportSubscriber(ctx, db, args[1:])
+	case "audit":
+		return listAudit(ctx, db, args[1:])
+	}
+	return errUsage
+}
+
+func exportSubscriber(ctx context.Context, db *models.DB, args []string) error {
+	if len(args) != 1 {
+		return errUsage
+	}
+
+	sub, err := db.GetSubscriberByEmail(ctx, args[0])
+	if errors.Is(err, models.ErrNoRecord) {
+		return fmt.Errorf("%s is not subscribed", args[0])
+	}
+	if err != nil {
+		return err
+	}
+	export, err := db.ExportSubscriber(ctx, sub.ID)
+	if err != nil {
+		return fmt.Errorf("exporting subscriber: %w", err)
+	}
+	if err := db.RecordAudit(ctx, "export", sub.ID, "admin"); err != nil {
+		return fmt.Errorf("recording audit entry: %w", err)
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(export)
+}
+
+func listAudit(ctx context.Context, db *models.DB, args []string) error {
+	limit := 50
+	switch len(args) {
+	case 0:
+	case 1:
+		n, err := strconv.Atoi(args[0])
+		if err != nil || n <= 0 {
+			return fmt.Errorf("invalid limit %q", args[0])
+		}
+		limit = n
+	default:
+		return errUsage
+	}
+
+	entries, err := db.GetAuditLog(ctx, limit)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "TIME\tACTION\tSUBSCRIBER\tACTOR")
+	for _, e := range entries {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", e.CreatedAt.Format("2006-01-02 15:04:05"), e.Action, e.SubscriberID, e.Actor)
+	}
+	return w.Flush()
+}
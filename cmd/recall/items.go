@@ -0,0 +1,218 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/paluras/product-recall-system/configs"
+	"github.com/paluras/product-recall-system/internal/classify"
+	"github.com/paluras/product-recall-system/internal/logging"
+	"github.com/paluras/product-recall-system/internal/models"
+	"github.com/paluras/product-recall-system/internal/notify"
+)
+
+func itemsCommand(ctx context.Context, conf *configs.Config, logger *slog.Logger, args []string) error {
+	if len(args) == 0 {
+		return errUsage
+	}
+
+	db, err := models.NewDB(conf.DSN(), logger)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	switch args[0] {
+	case "list":
+		return listItems(ctx, db, args[1:])
+	case "show":
+		return showItem(ctx, db, args[1:])
+	case "renotify":
+		return renotifyItem(ctx, conf, logger, db, args[1:])
+	case "reclassify":
+		return reclassifyItems(ctx, conf, logger, db, args[1:])
+	}
+	return errUsage
+}
+
+func listItems(ctx context.Context, db *models.DB, args []string) error {
+	fs := flag.NewFlagSet("items list", flag.ContinueOnError)
+	tag := fs.String("tag", "", "only list recalls with this tag")
+	q := fs.String("q", "", "only list recalls mentioning this text")
+	limit := fs.Int("limit", 20, "maximum number of recalls to list")
+	if err := fs.Parse(args); err != nil || fs.NArg() > 0 {
+		return errUsage
+	}
+
+	var (
+		items []models.ScrapedItem
+		err   error
+	)
+	switch {
+	case *tag != "" && *q != "":
+		return errors.New("-tag and -q cannot be combined")
+	case *tag != "":
+		items, err = db.GetItemsByTag(ctx, *tag, *limit)
+	case *q != "":
+		items, err = db.SearchItems(ctx, *q, *limit)
+	default:
+		items, err = db.GetLatestItems(ctx, *limit)
+	}
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tDATE\tTITLE")
+	for _, item := range items {
+		fmt.Fprintf(w, "%d\t%s\t%s\n", item.ID, item.Date.Format(time.DateOnly), item.Title)
+	}
+	return w.Flush()
+}
+
+func showItem(ctx context.Context, db *models.DB, args []string) error {
+	item, err := itemArg(ctx, db, args, 1)
+	if err != nil {
+		return err
+	}
+	attachments, err := db.GetAttachments(ctx, item.ID)
+	if err != nil {
+		return err
+	}
+	revisions, err := db.GetRevisions(ctx, item.ID)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "ID\t%d\n", item.ID)
+	fmt.Fprintf(w, "Title\t%s\n", item.Title)
+	fmt.Fprintf(w, "Date\t%s\n", item.Date.Format(time.DateOnly))
+	fmt.Fprintf(w, "Link\t%s\n", item.Link)
+	fmt.Fprintf(w, "Stored\t%s\n", item.CreatedAt.Format(time.DateTime))
+	if item.Withdrawn() {
+		fmt.Fprintf(w, "Withdrawn\t%s\n", item.WithdrawnAt.Format(time.DateTime))
+	}
+	for _, tag := range item.Tags {
+		fmt.Fprintf(w, "Tag\t%s/%s\n", tag.Kind, tag.Slug)
+	}
+	for _, a := range attachments {
+		fmt.Fprintf(w, "Attachment\t%s (%d bytes)\n", a.URL, a.Size)
+	}
+	for _, r := range revisions {
+		fmt.Fprintf(w, "Change\t%s %s\n", r.CreatedAt.Format(time.DateTime), r.Change)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	if item.DetailText != "" {
+		fmt.Printf("\n%s\n", item.DetailText)
+	}
+	return nil
+}
+
+// renotifyItem queues an item for the next notify run, or with an email
+// argument sends its alert to that subscriber straight away.
+func renotifyItem(ctx context.Context, conf *configs.Config, logger *slog.Logger, db *models.DB, args []string) error {
+	item, err := itemArg(ctx, db, args, 2)
+	if err != nil {
+		return err
+	}
+
+	if len(args) == 1 {
+		if err := db.MarkAsUnnotified(ctx, item.ID); err != nil {
+			return err
+		}
+		fmt.Printf("%s will be announced with the next notify run\n", item.Title)
+		return nil
+	}
+
+	sub, err := db.GetSubscriberByEmail(ctx, args[1])
+	if errors.Is(err, models.ErrNoRecord) {
+		return fmt.Errorf("%s is not subscribed", args[1])
+	}
+	if err != nil {
+		return err
+	}
+	emailService, err := newEmailService(conf, db, logger)
+	if err != nil {
+		return err
+	}
+	if conf.DryRun {
+		subscribers := []models.Subscriber{*sub}
+		alerts, err := emailService.PreviewBatchNotification(subscribers, []models.ScrapedItem{*item})
+		if err != nil {
+			return err
+		}
+		return preview(logger, emailService, conf.PreviewDir, subscribers, []notify.Preview{{Channel: models.ChannelEmail, Messages: alerts}}, []models.ScrapedItem{*item}, nil)
+	}
+	if err := emailService.SendBatchNotification(ctx, []models.Subscriber{*sub}, []models.ScrapedItem{*item}); err != nil {
+		return fmt.Errorf("sending alert: %w", err)
+	}
+
+	fmt.Printf("Sent %s to %s\n", item.Title, sub.Email)
+	return nil
+}
+
+// reclassifyItems re-tags every stored item and re-extracts its barcodes and
+// lot numbers, e.g. after the keyword dictionary has been edited.
+func reclassifyItems(ctx context.Context, conf *configs.Config, logger *slog.Logger, db *models.DB, args []string) error {
+	if len(args) > 0 {
+		return errUsage
+	}
+
+	dict, err := classify.LoadDictionary(conf.Dictionary)
+	if err != nil {
+		return fmt.Errorf("loading dictionary: %w", err)
+	}
+	classifier := classify.New(dict)
+
+	items, err := db.GetItemsForClassification(ctx)
+	if err != nil {
+		return err
+	}
+
+	tagged := 0
+	for _, item := range items {
+		if ctx.Err() != nil {
+			return fmt.Errorf("reclassification interrupted: %w", ctx.Err())
+		}
+
+		attachments, err := db.GetAttachments(ctx, item.ID)
+		if err != nil {
+			logger.Error("loading attachments failed", logging.ItemID(item.ID), logging.Err(err))
+			continue
+		}
+		if classifyItem(ctx, logger, db, classifier, item, models.AttachmentText(attachments)) {
+			tagged++
+		}
+	}
+
+	fmt.Printf("Reclassified %d recalls, %d with tags\n", len(items), tagged)
+	return nil
+}
+
+// itemArg loads the item whose ID is the first of at most max arguments.
+func itemArg(ctx context.Context, db *models.DB, args []string, max int) (*models.ScrapedItem, error) {
+	if len(args) == 0 || len(args) > max {
+		return nil, errUsage
+	}
+	id, err := strconv.Atoi(args[0])
+	if err != nil {
+		return nil, fmt.Errorf("%q is not an item ID", args[0])
+	}
+
+	item, err := db.GetItem(ctx, id)
+	if errors.Is(err, models.ErrNoRecord) {
+		return nil, fmt.Errorf("no item has the ID %d", id)
+	}
+	return item, err
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/paluras/product-recall-system/configs"
+	"github.com/paluras/product-recall-system/internal/logging"
+	"github.com/paluras/product-recall-system/internal/models"
+	"github.com/paluras/product-recall-system/internal/web"
+	"github.com/paluras/product-recall-system/scripts/migrations"
+)
+
+const usage = `usage: recall [flags] <command> [args]
+
+commands:
+  scrape [link]                       store new and changed recalls, or refresh the details of one stored recall
+  notify                              send alerts about new recalls and changes to subscribers, webhooks, Telegram and browsers
+  subscribers list [-pending]         list subscribers, or only those who have not confirmed
+  subscribers add <email> [locale]    add a confirmed subscriber
+  subscribers confirm <email>         confirm a pending subscriber
+  subscribers remove <email>          erase a subscriber and everything held about them
+  items list [-tag t] [-q text] [-limit n]
+                                      list recalls, newest first
+  items show <id>                     print a recall with its tags, attachments and history
+  items renotify <id> [email]         announce a recall again with the next notify run, or now to one subscriber
+  items reclassify                    re-tag every recall and extract its barcodes and lots again
+  gdpr export <email>                 print everything held about a subscriber as JSON
+  gdpr audit [limit]                  list the most recent exports and erasures
+  webhooks list                       list webhooks with their failure counts
+  webhooks add <url>                  register a webhook and print its signing secret
+  webhooks remove <id>                delete a webhook and its delivery log
+  webhooks enable <id>                turn a disabled webhook back on
+  telegram                            run the Telegram bot that lets chats subscribe and choose filters
+  webpush keys                        generate a VAPID key pair for browser notifications
+  migrate                             create or update the database schema
+  config [setting]                    print the configuration, or the value of one setting
+  serve                               run the website
+
+Flags go before the command; run recall -h to list them. Every flag can also
+be set in a -config file, through its environment variable, or through a
+file named by that variable with _FILE appended.`
+
+func main() {
+	conf := configs.ParseFlags()
+
+	args := flag.Args()
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	logger, err := logging.New(os.Stderr, conf.LogFormat, conf.LogLevel)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	logger = logger.With("run_id", logging.NewRunID(), "command", args[0])
+	slog.SetDefault(logger)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	switch args[0] {
+	case "scrape":
+		err = scrape(ctx, conf, logger, args[1:])
+	case "notify":
+		err = notifyCommand(ctx, conf, logger)
+	case "subscribers":
+		err = subscribersCommand(ctx, conf, logger, args[1:])
+	case "items":
+		err = itemsCommand(ctx, conf, logger, args[1:])
+	case "gdpr":
+		err = gdprCommand(ctx, conf, logger, args[1:])
+	case "webhooks":
+		err = webhooksCommand(ctx, conf, logger, args[1:])
+	case "telegram":
+		err = telegramCommand(ctx, conf, logger)
+	case "webpush":
+		err = webpushCommand(args[1:])
+	case "migrate":
+		err = migrate(ctx, conf, logger)
+	case "config":
+		err = configCommand(args[1:])
+	case "serve":
+		err = web.Serve(conf, logger)
+	default:
+		err = errUsage
+	}
+
+	if errors.Is(err, errUsage) {
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+	if err != nil {
+		logger.Error("command failed", logging.Err(err))
+		os.Exit(1)
+	}
+}
+
+// errUsage makes main print the usage and exit with status 2.
+var errUsage = errors.New("usage")
+
+func migrate(ctx context.Context, conf *configs.Config, logger *slog.Logger) error {
+	db, err := models.NewDB(conf.DSN(), logger)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	applied, err := db.Migrate(ctx, migrations.Files)
+	for _, name := range applied {
+		logger.Info("migration applied", "file", name)
+	}
+	if err != nil {
+		return err
+	}
+	if len(applied) == 0 {
+		logger.Info("schema is up to date")
+	}
+	return nil
+}
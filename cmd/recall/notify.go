@@ -0,0 +1,156 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/paluras/product-recall-system/configs"
+	"github.com/paluras/product-recall-system/internal/logging"
+	"github.com/paluras/product-recall-system/internal/models"
+	"github.com/paluras/product-recall-system/internal/notify"
+)
+
+// notifyCommand tells subscribers on every channel about the recalls they
+// have not been told about yet, and emails them the changes.
+func notifyCommand(ctx context.Context, conf *configs.Config, logger *slog.Logger) error {
+	db, err := models.NewDB(conf.DSN(), logger)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	emailService, err := newEmailService(conf, db, logger)
+	if err != nil {
+		return err
+	}
+
+	items, err := db.GetUnnotifiedItems(ctx)
+	if err != nil {
+		return err
+	}
+	// the alert for a new item already shows its latest state
+	if !conf.DryRun {
+		if err := db.SkipUnannouncedRevisions(ctx); err != nil {
+			return err
+		}
+	}
+	revisions, err := db.GetUnnotifiedRevisions(ctx)
+	if err != nil {
+		return err
+	}
+	if conf.DryRun {
+		revisions = announcedOnly(revisions, items)
+	}
+
+	if len(items) == 0 && len(revisions) == 0 {
+		logger.Info("nothing to notify about")
+		return nil
+	}
+	subscribers, err := db.GetActiveSubscribers(ctx)
+	if err != nil {
+		return fmt.Errorf("fetching subscribers: %w", err)
+	}
+
+	dispatcher, err := newDispatcher(conf, db, logger, emailService)
+	if err != nil {
+		return err
+	}
+
+	if conf.DryRun {
+		alerts, err := dispatcher.Preview(ctx, items)
+		if err != nil {
+			return err
+		}
+		return preview(logger, emailService, conf.PreviewDir, subscribers, alerts, items, revisions)
+	}
+
+	if len(revisions) > 0 {
+		notifyUpdates(ctx, logger, db, emailService, subscribers, revisions)
+	}
+	if len(items) == 0 {
+		return nil
+	}
+
+	// every channel remembers what it delivered, so the next run only
+	// sends what a failure left out
+	if err := dispatcher.Dispatch(ctx, items); err != nil {
+		return fmt.Errorf("sending alerts: %w", err)
+	}
+
+	for _, item := range items {
+		if err := db.MarkAsNotified(ctx, item.ID); err != nil {
+			logger.Error("marking item as notified failed", logging.ItemID(item.ID), logging.Err(err))
+		}
+	}
+	return nil
+}
+
+// newDispatcher sets up the channels alerts go out on: email, webhooks,
+// and Telegram and Web Push once they are configured.
+func newDispatcher(conf *configs.Config, db *models.DB, logger *slog.Logger, emailService *notify.EmailService) (*notify.Dispatcher, error) {
+	channels := []notify.Channel{
+		notify.NewWebhookService(notify.WebhookConfig{
+			BaseURL:     conf.BaseURL,
+			Timeout:     conf.WebhookTimeout,
+			Retries:     conf.WebhookRetries,
+			MaxFailures: conf.WebhookMaxFailures,
+		}, db, logger),
+	}
+	if conf.TelegramToken != "" {
+		telegramService, err := newTelegramService(conf, db, logger)
+		if err != nil {
+			return nil, err
+		}
+		channels = append(channels, telegramService)
+	}
+	if conf.VAPIDPrivateKey != "" {
+		webPushService, err := newWebPushService(conf, db, logger)
+		if err != nil {
+			return nil, err
+		}
+		channels = append(channels, webPushService)
+	}
+	channels = append(channels, emailService)
+	return notify.NewDispatcher(logger, channels...), nil
+}
+
+// newEmailService sets up Resend, which is only optional in a dry run.
+func newEmailService(conf *configs.Config, db *models.DB, logger *slog.Logger) (*notify.EmailService, error) {
+	if conf.ResendAPIKey == "" && !conf.DryRun {
+		return nil, errors.New("resend-api-key (RESEND_API_KEY) is required")
+	}
+
+	emailConfig := notify.EmailConfig{
+		APIKey:    conf.ResendAPIKey,
+		FromEmail: conf.MailFrom,
+		BaseURL:   conf.BaseURL,
+		Rate:      conf.EmailRate,
+		Workers:   conf.EmailWorkers,
+		Retries:   conf.EmailRetries,
+	}
+
+	emailService, err := notify.NewEmailService(emailConfig, db, logger)
+	if err != nil {
+		return nil, fmt.Errorf("creating email service: %w", err)
+	}
+	return emailService, nil
+}
+
+// notifyUpdates sends the edits and withdrawals of recalls that were already
+// announced.
+func notifyUpdates(ctx context.Context, logger *slog.Logger, db *models.DB, emailService *notify.EmailService, subscribers []models.Subscriber, revisions []models.Revision) {
+	if err := emailService.SendUpdateNotification(ctx, subscribers, revisions); err != nil {
+		logger.Error("sending update notifications failed", logging.Err(err))
+		return
+	}
+
+	for _, r := range revisions {
+		if err := db.MarkRevisionNotified(ctx, r.ID); err != nil {
+			logger.Error("marking revision as notified failed", logging.ItemID(r.Item.ID), "revision_id", r.ID, logging.Err(err))
+		}
+	}
+
+	logger.Info("update notifications sent", "revisions", len(revisions), "subscribers", len(subscribers))
+}
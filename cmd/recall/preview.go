@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+
+	"github.com/paluras/product-recall-system/internal/models"
+	"github.com/paluras/product-recall-system/internal/notify"
+)
+
+// preview writes the alert and update emails a run would send to dir as
+// .eml files and prints how many recipients of each channel would hear
+// about each recall. Nothing is sent and nothing is marked as notified.
+func preview(logger *slog.Logger, emailService *notify.EmailService, dir string, subscribers []models.Subscriber, alerts []notify.Preview, items []models.ScrapedItem, revisions []models.Revision) error {
+	updates, err := emailService.PreviewUpdateNotification(subscribers, revisions)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	emails := map[string][]*notify.Message{"update": updates}
+	for _, p := range alerts {
+		if p.Channel == models.ChannelEmail {
+			emails["alert"] = p.Messages
+		}
+	}
+	for kind, messages := range emails {
+		for i, m := range messages {
+			if err := writeEML(filepath.Join(dir, fmt.Sprintf("%s-%03d.eml", kind, i+1)), m); err != nil {
+				return err
+			}
+		}
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "KIND\tITEM\tRECIPIENTS\tTITLE")
+	reached := make([]map[int]int, len(alerts))
+	for i, p := range alerts {
+		reached[i] = recipients(p.Messages)
+	}
+	for _, item := range items {
+		for i, p := range alerts {
+			// the other channels are only listed when they reach someone
+			n := reached[i][item.ID]
+			if n > 0 || p.Channel == models.ChannelEmail {
+				fmt.Fprintf(w, "%s\t%d\t%d\t%s\n", p.Channel, item.ID, n, item.Title)
+			}
+		}
+	}
+	updated := recipients(updates)
+	for _, r := range revisions {
+		fmt.Fprintf(w, "%s\t%d\t%d\t%s\n", r.Change, r.Item.ID, updated[r.Item.ID], r.Item.Title)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	logger.Info("dry run previews written", "alerts", len(emails["alert"]), "updates", len(updates), "subscribers", len(subscribers), "dir", dir)
+	return nil
+}
+
+// recipients counts the messages that mention each item.
+func recipients(messages []*notify.Message) map[int]int {
+	counts := make(map[int]int)
+	for _, m := range messages {
+		for _, item := range m.Items {
+			counts[item.ID]++
+		}
+	}
+	return counts
+}
+
+func writeEML(path string, m *notify.Message) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := m.WriteEML(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// announcedOnly leaves out the revisions of items that have not been
+// announced yet, as SkipUnannouncedRevisions would, without writing.
+func announcedOnly(revisions []models.Revision, unannounced []models.ScrapedItem) []models.Revision {
+	pending := make(map[int]bool, len(unannounced))
+	for _, item := range unannounced {
+		pending[item.ID] = true
+	}
+	var announced []models.Revision
+	for _, r := range revisions {
+		if !pending[r.Item.ID] {
+			announced = append(announced, r)
+		}
+	}
+	return announced
+}
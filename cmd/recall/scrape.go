@@ -0,0 +1,211 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/paluras/product-recall-system/configs"
+	"github.com/paluras/product-recall-system/internal/barcode"
+	"github.com/paluras/product-recall-system/internal/classify"
+	"github.com/paluras/product-recall-system/internal/logging"
+	"github.com/paluras/product-recall-system/internal/models"
+	"github.com/paluras/product-recall-system/internal/scraper"
+)
+
+// scrape stores the recalls on the listing, or with a link argument fetches
+// the article of one stored recall again and refreshes its text,
+// attachments, tags and codes.
+func scrape(ctx context.Context, conf *configs.Config, logger *slog.Logger, args []string) error {
+	if len(args) > 1 {
+		return errUsage
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, conf.ScrapeTimeout)
+	defer cancel()
+
+	if conf.DryRun {
+		return dryRun(ctx, conf, logger)
+	}
+
+	db, err := models.NewDB(conf.DSN(), logger)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	dict, err := classify.LoadDictionary(conf.Dictionary)
+	if err != nil {
+		return fmt.Errorf("loading dictionary: %w", err)
+	}
+	classifier := classify.New(dict)
+
+	client := scraper.NewClient(scraper.ClientConfig{
+		UserAgent:  conf.UserAgent,
+		Timeout:    conf.HTTPTimeout,
+		Retries:    conf.HTTPRetries,
+		CrawlDelay: conf.CrawlDelay,
+		Validators: db,
+		Logger:     logger,
+	})
+	s := scraper.New(client, conf.ListingURL)
+
+	if len(args) == 1 {
+		return rescrape(ctx, logger, db, s, classifier, args[0])
+	}
+
+	logger.Info("starting scrape", "url", conf.ListingURL)
+
+	items, err := s.Scrape(ctx)
+	if errors.Is(err, scraper.ErrNotModified) {
+		logger.Info("listing has not changed since the last scrape")
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("scraping failed: %w", err)
+	}
+
+	logger.Info("listing parsed", "items", len(items))
+
+	links := make([]string, len(items))
+	for i, data := range items {
+		links[i] = data.Link
+	}
+	stored, err := db.StoredLinks(ctx, links)
+	if err != nil {
+		return fmt.Errorf("checking stored items: %w", err)
+	}
+
+	// details of new items are fetched up front so the transaction below
+	// holds no locks while waiting on the network
+	records := make([]models.ScrapedItem, 0, len(items))
+	details := make(map[string]scraper.Detail)
+	for _, data := range items {
+		item := models.FromScraperData(data)
+		if !stored[data.Link] {
+			detail, err := s.ScrapeDetail(ctx, data.Link)
+			if err != nil {
+				logger.Error("fetching details failed", "url", data.Link, logging.Err(err))
+			}
+			item.DetailText = detail.Text
+			details[data.Link] = detail
+		}
+		records = append(records, item)
+	}
+	if ctx.Err() != nil {
+		return fmt.Errorf("scrape interrupted: %w", ctx.Err())
+	}
+
+	// every item in the listing is stamped with the same time, so anything
+	// stamped earlier was missing from this scrape
+	seen := time.Now().UTC().Truncate(time.Second)
+	result, err := db.UpsertItems(ctx, records, seen)
+	if err != nil {
+		return fmt.Errorf("storing items: %w", err)
+	}
+
+	// a changed listing entry may come with a changed article, so its text,
+	// tags and codes are refreshed too
+	for _, item := range result.Updated {
+		logger.Info("item changed", logging.ItemID(item.ID), "title", item.Title)
+		if _, err := refresh(ctx, logger, db, s, classifier, item); err != nil {
+			logger.Error("refreshing changed item failed", logging.ItemID(item.ID), logging.Err(err))
+		}
+	}
+	for _, item := range result.New {
+		logger.Info("stored new item", logging.ItemID(item.ID), "title", item.Title)
+		enrich(ctx, logger, db, s, classifier, item, details[item.Link])
+	}
+	logger.Info("items stored", "new", len(result.New), "updated", len(result.Updated), "unchanged", len(result.Unchanged))
+
+	if since := scraper.Covered(items); !since.IsZero() {
+		withdrawn, err := db.MarkWithdrawn(ctx, since, seen)
+		if err != nil {
+			logger.Error("marking withdrawn items failed", logging.Err(err))
+		} else if withdrawn > 0 {
+			logger.Info("items no longer listed were marked as withdrawn", "withdrawn", withdrawn)
+		}
+	}
+
+	// the listing is fetched again next time unless every item was stored
+	if err := client.SaveValidators(ctx); err != nil {
+		logger.Error("saving page validators failed", logging.Err(err))
+	}
+
+	logger.Info("scrape completed")
+	return nil
+}
+
+func rescrape(ctx context.Context, logger *slog.Logger, db *models.DB, s *scraper.Scraper, classifier *classify.Classifier, link string) error {
+	id, err := db.GetItemIDByLink(ctx, link)
+	if errors.Is(err, models.ErrNoRecord) {
+		return fmt.Errorf("no stored recall has the link %s", link)
+	}
+	if err != nil {
+		return err
+	}
+	item, err := db.GetItem(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	detail, err := refresh(ctx, logger, db, s, classifier, *item)
+	if err != nil {
+		return err
+	}
+
+	logger.Info("item refreshed", logging.ItemID(item.ID), "attachments", len(detail.Attachments))
+	return nil
+}
+
+// refresh fetches the article of a stored item again, stores its text and
+// enriches the item with it.
+func refresh(ctx context.Context, logger *slog.Logger, db *models.DB, s *scraper.Scraper, classifier *classify.Classifier, item models.ScrapedItem) (scraper.Detail, error) {
+	detail, err := s.ScrapeDetail(ctx, item.Link)
+	if err != nil {
+		return detail, fmt.Errorf("fetching details: %w", err)
+	}
+	if err := db.SetItemDetail(ctx, item.ID, detail.Text); err != nil {
+		return detail, err
+	}
+	item.DetailText = detail.Text
+	enrich(ctx, logger, db, s, classifier, item, detail)
+	return detail, nil
+}
+
+// enrich stores the attachments of an item and tags it and its codes
+// using everything it was published with.
+func enrich(ctx context.Context, logger *slog.Logger, db *models.DB, s *scraper.Scraper, classifier *classify.Classifier, item models.ScrapedItem, detail scraper.Detail) {
+	var attachments []models.Attachment
+	for _, link := range detail.Attachments {
+		a, err := s.FetchAttachment(ctx, link)
+		if err != nil {
+			logger.Error("fetching attachment failed", logging.ItemID(item.ID), "url", link, logging.Err(err))
+			continue
+		}
+		attachment := models.FromScraperAttachment(a)
+		if err := db.AddAttachment(ctx, item.ID, attachment); err != nil {
+			logger.Error("storing attachment failed", logging.ItemID(item.ID), "url", link, logging.Err(err))
+			continue
+		}
+		attachments = append(attachments, attachment)
+	}
+	classifyItem(ctx, logger, db, classifier, item, models.AttachmentText(attachments))
+}
+
+// classifyItem replaces the tags and codes of an item with those found in
+// its title, article and attachments, and reports whether it got any tags.
+func classifyItem(ctx context.Context, logger *slog.Logger, db *models.DB, classifier *classify.Classifier, item models.ScrapedItem, attachmentText string) bool {
+	tags := models.FromClassifierTags(classifier.Classify(item.Title, item.DetailText, attachmentText))
+	if err := db.SetItemTags(ctx, item.ID, tags); err != nil {
+		logger.Error("tagging item failed", logging.ItemID(item.ID), logging.Err(err))
+		tags = nil
+	}
+	gtins, lots := barcode.Extract(item.Title, item.DetailText, attachmentText)
+	if err := db.SetItemCodes(ctx, item.ID, gtins, lots); err != nil {
+		logger.Error("storing codes failed", logging.ItemID(item.ID), logging.Err(err))
+	}
+	return len(tags) > 0
+}
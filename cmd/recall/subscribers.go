@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/paluras/product-recall-system/configs"
+	"github.com/paluras/product-recall-system/internal/i18n"
+	"github.com/paluras/product-recall-system/internal/models"
+	"github.com/paluras/product-recall-system/internal/web"
+)
+
+// cliConsent is recorded for subscribers an operator added or confirmed on
+// their behalf.
+var cliConsent = models.Consent{FormVersion: "cli"}
+
+func subscribersCommand(ctx context.Context, conf *configs.Config, logger *slog.Logger, args []string) error {
+	if len(args) == 0 {
+		return errUsage
+	}
+
+	db, err := models.NewDB(conf.DSN(), logger)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	switch args[0] {
+	case "list":
+		return listSubscribers(ctx, db, args[1:])
+	case "add":
+		return addSubscriber(ctx, db, args[1:])
+	case "confirm":
+		return confirmSubscriber(ctx, db, args[1:])
+	case "remove":
+		return removeSubscriber(ctx, db, args[1:])
+	}
+	return errUsage
+}
+
+func listSubscribers(ctx context.Context, db *models.DB, args []string) error {
+	fs := flag.NewFlagSet("subscribers list", flag.ContinueOnError)
+	pending := fs.Bool("pending", false, "only list subscribers who have not confirmed")
+	if err := fs.Parse(args); err != nil || fs.NArg() > 0 {
+		return errUsage
+	}
+
+	subscribers, err := db.ListSubscribers(ctx, *pending)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tEMAIL\tLOCALE\tSTATUS\tSINCE")
+	for _, sub := range subscribers {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", sub.ID, sub.Email, sub.Locale, subscriberStatus(sub), sub.CreatedAt.Format("2006-01-02"))
+	}
+	return w.Flush()
+}
+
+func subscriberStatus(sub models.Subscriber) string {
+	switch {
+	case !sub.Confirmed:
+		return "pending"
+	case sub.Paused():
+		return "paused until " + sub.PausedUntil.Format(time.DateOnly)
+	}
+	return "active"
+}
+
+func addSubscriber(ctx context.Context, db *models.DB, args []string) error {
+	if len(args) == 0 || len(args) > 2 {
+		return errUsage
+	}
+	email, locale := args[0], i18n.Default
+	if len(args) == 2 {
+		locale = args[1]
+	}
+	if !web.Match(email, web.EmailRegex) {
+		return fmt.Errorf("%q is not a valid email address", email)
+	}
+
+	token, err := db.AddSubscriber(ctx, email, locale, cliConsent)
+	if err != nil {
+		return fmt.Errorf("adding subscriber: %w", err)
+	}
+	if err := db.ConfirmSubscriber(ctx, token, cliConsent); err != nil {
+		return fmt.Errorf("confirming subscriber: %w", err)
+	}
+
+	fmt.Printf("Added %s\n", email)
+	return nil
+}
+
+func confirmSubscriber(ctx context.Context, db *models.DB, args []string) error {
+	if len(args) != 1 {
+		return errUsage
+	}
+
+	token, err := db.ConfirmationToken(ctx, args[0])
+	if errors.Is(err, models.ErrNoRecord) {
+		return fmt.Errorf("%s is not waiting for confirmation", args[0])
+	}
+	if err != nil {
+		return err
+	}
+	if err := db.ConfirmSubscriber(ctx, token, cliConsent); err != nil {
+		return err
+	}
+
+	fmt.Printf("Confirmed %s\n", args[0])
+	return nil
+}
+
+func removeSubscriber(ctx context.Context, db *models.DB, args []string) error {
+	if len(args) != 1 {
+		return errUsage
+	}
+
+	sub, err := db.GetSubscriberByEmail(ctx, args[0])
+	if errors.Is(err, models.ErrNoRecord) {
+		return fmt.Errorf("%s is not subscribed", args[0])
+	}
+	if err != nil {
+		return err
+	}
+	if err := db.EraseSubscriber(ctx, sub.ID, "admin"); err != nil {
+		return err
+	}
+
+	fmt.Printf("Removed %s\n", args[0])
+	return nil
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/paluras/product-recall-system/configs"
+	"github.com/paluras/product-recall-system/internal/classify"
+	"github.com/paluras/product-recall-system/internal/models"
+	"github.com/paluras/product-recall-system/internal/notify"
+)
+
+// telegramCommand runs the bot that lets chats subscribe and choose their
+// filters.
+func telegramCommand(ctx context.Context, conf *configs.Config, logger *slog.Logger) error {
+	if conf.TelegramToken == "" {
+		return errors.New("telegram-token (TELEGRAM_BOT_TOKEN) is required")
+	}
+
+	db, err := models.NewDB(conf.DSN(), logger)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	telegramService, err := newTelegramService(conf, db, logger)
+	if err != nil {
+		return err
+	}
+
+	logger.Info("telegram bot started")
+	return telegramService.RunBot(ctx)
+}
+
+// newTelegramService sets up the bot, whose filters are the tags of the
+// dictionary the scraper classifies with.
+func newTelegramService(conf *configs.Config, db *models.DB, logger *slog.Logger) (*notify.TelegramService, error) {
+	dict, err := classify.LoadDictionary(conf.Dictionary)
+	if err != nil {
+		return nil, fmt.Errorf("loading dictionary: %w", err)
+	}
+
+	telegramService, err := notify.NewTelegramService(notify.TelegramConfig{
+		Token:   conf.TelegramToken,
+		APIURL:  conf.TelegramAPIURL,
+		BaseURL: conf.BaseURL,
+		Timeout: conf.TelegramTimeout,
+		Retries: conf.TelegramRetries,
+		Tags:    dict.Tags(),
+	}, db, logger)
+	if err != nil {
+		return nil, fmt.Errorf("creating telegram service: %w", err)
+	}
+	return telegramService, nil
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/paluras/product-recall-system/configs"
+	"github.com/paluras/product-recall-system/internal/models"
+)
+
+func webhooksCommand(ctx context.Context, conf *configs.Config, logger *slog.Logger, args []string) error {
+	if len(args) == 0 {
+		return errUsage
+	}
+
+	db, err := models.NewDB(conf.DSN(), logger)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	switch args[0] {
+	case "list":
+		if len(args) != 1 {
+			return errUsage
+		}
+		return listWebhooks(ctx, db)
+	case "add":
+		if len(args) != 2 {
+			return errUsage
+		}
+		return addWebhook(ctx, db, args[1])
+	case "remove", "enable":
+		if len(args) != 2 {
+			return errUsage
+		}
+		id, err := strconv.Atoi(args[1])
+		if err != nil {
+			return fmt.Errorf("%q is not a webhook ID", args[1])
+		}
+		if args[0] == "remove" {
+			err = db.RemoveWebhook(ctx, id)
+		} else {
+			err = db.EnableWebhook(ctx, id)
+		}
+		if errors.Is(err, models.ErrNoRecord) {
+			return fmt.Errorf("no webhook has the ID %d", id)
+		}
+		return err
+	}
+	return errUsage
+}
+
+func listWebhooks(ctx context.Context, db *models.DB) error {
+	webhooks, err := db.GetWebhooks(ctx, false)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tURL\tSTATUS\tFAILURES\tSINCE")
+	for _, h := range webhooks {
+		status := "active"
+		if h.Disabled() {
+			status = "disabled " + h.DisabledAt.Format(time.DateTime)
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%d\t%s\n", h.ID, h.URL, status, h.Failures, h.CreatedAt.Format(time.DateOnly))
+	}
+	return w.Flush()
+}
+
+func addWebhook(ctx context.Context, db *models.DB, rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return fmt.Errorf("%q is not an http or https URL", rawURL)
+	}
+
+	id, secret, err := db.AddWebhook(ctx, u.String())
+	if err != nil {
+		return err
+	}
+
+	// the secret is not shown again; the receiver needs it to check
+	// signatures
+	fmt.Printf("Added webhook %d\nSecret: %s\n", id, secret)
+	return nil
+}
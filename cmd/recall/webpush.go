@@ -0,0 +1,39 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+
+	"github.com/paluras/product-recall-system/configs"
+	"github.com/paluras/product-recall-system/internal/models"
+	"github.com/paluras/product-recall-system/internal/notify"
+)
+
+func webpushCommand(args []string) error {
+	if len(args) != 1 || args[0] != "keys" {
+		return errUsage
+	}
+
+	privateKey, publicKey, err := notify.GenerateVAPIDKeys()
+	if err != nil {
+		return err
+	}
+	// browsers bind their subscriptions to the public key, so replacing
+	// the pair makes every subscription stop working
+	fmt.Printf("VAPID_PRIVATE_KEY=%s\n# public key: %s\n", privateKey, publicKey)
+	return nil
+}
+
+func newWebPushService(conf *configs.Config, db *models.DB, logger *slog.Logger) (*notify.WebPushService, error) {
+	webPushService, err := notify.NewWebPushService(notify.WebPushConfig{
+		PrivateKey: conf.VAPIDPrivateKey,
+		Subject:    conf.VAPIDSubject,
+		BaseURL:    conf.BaseURL,
+		Timeout:    conf.PushTimeout,
+		Retries:    conf.PushRetries,
+	}, db, logger)
+	if err != nil {
+		return nil, fmt.Errorf("creating web push service: %w", err)
+	}
+	return webPushService, nil
+}
@@ -3,6 +3,11 @@ package configs
 import (
 	"flag"
 	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/paluras/product-recall-system/internal/scraper"
 )
 
 type Config struct {
@@ -11,19 +16,261 @@ type Config struct {
 	DBHost     string
 	DBPort     string
 	DBName     string
+
+	Addr    string
+	BaseURL string
+	Dev     bool
+
+	ResendAPIKey    string
+	MailFrom        string
+	EmailRate       int
+	EmailWorkers    int
+	EmailRetries    int
+	TokenSigningKey string
+
+	Schedule string
+
+	WebhookTimeout     time.Duration
+	WebhookRetries     int
+	WebhookMaxFailures int
+
+	TelegramToken   string
+	TelegramAPIURL  string
+	TelegramTimeout time.Duration
+	TelegramRetries int
+
+	VAPIDPrivateKey string
+	VAPIDSubject    string
+	PushTimeout     time.Duration
+	PushRetries     int
+
+	LogLevel  string
+	LogFormat string
+
+	RateLimitStore string
+	TrustedProxies string
+	IPLimit        int
+	IPBanAfter     int
+	EmailLimit     int
+	GlobalLimit    int
+
+	ChallengeMode       string
+	ChallengeKey        string
+	ChallengeThreshold  int
+	ChallengeDifficulty int
+
+	ConfirmCooldown time.Duration
+
+	Dictionary string
+
+	ListingURL    string
+	UserAgent     string
+	HTTPTimeout   time.Duration
+	HTTPRetries   int
+	CrawlDelay    time.Duration
+	ScrapeTimeout time.Duration
+
+	DryRun     bool
+	Output     string
+	PreviewDir string
 }
 
+// ParseFlags loads the configuration into the command-line flag set, so
+// flag.Args holds the remaining arguments afterwards. Each setting is taken
+// from the first of these that has it: a command-line flag, the file named
+// by its environment variable with _FILE appended, the environment
+// variable, the config file, and the default. It exits if the result is
+// invalid.
 func ParseFlags() *Config {
+	conf, err := load(flag.CommandLine, os.Args[1:], os.LookupEnv)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid configuration: %v\n", err)
+		os.Exit(2)
+	}
+	return conf
+}
+
+// secrets are the settings recall config does not print.
+var secrets = map[string]bool{
+	"dbpass":            true,
+	"resend-api-key":    true,
+	"token-signing-key": true,
+	"telegram-token":    true,
+	"vapid-private-key": true,
+	"challenge-key":     true,
+}
+
+// Secret reports whether the flag name holds a password or key.
+func Secret(name string) bool {
+	return secrets[name]
+}
+
+// define registers every setting on fs with its default, and returns the
+// environment variable of each flag.
+func (c *Config) define(fs *flag.FlagSet) map[string]string {
+	env := make(map[string]string)
+	str := func(p *string, name, envName, value, usage string) {
+		fs.StringVar(p, name, value, usage)
+		env[name] = envName
+	}
+	num := func(p *int, name, envName string, value int, usage string) {
+		fs.IntVar(p, name, value, usage)
+		env[name] = envName
+	}
+	dur := func(p *time.Duration, name, envName string, value time.Duration, usage string) {
+		fs.DurationVar(p, name, value, usage)
+		env[name] = envName
+	}
+
+	str(&c.DBUser, "dbuser", "DB_USER", "", "Database user")
+	str(&c.DBPassword, "dbpass", "DB_PASSWORD", "", "Database password")
+	str(&c.DBHost, "dbhost", "DB_HOST", "localhost", "Database host")
+	str(&c.DBPort, "dbport", "DB_PORT", "3307", "Database port")
+	str(&c.DBName, "dbname", "DB_NAME", "scraper_db", "Database name")
+
+	str(&c.Addr, "addr", "RECALL_ADDR", ":54321", "Address the website listens on")
+	str(&c.BaseURL, "base-url", "RECALL_BASE_URL", "https://produseretrase.eu", "Public URL of the website, used for links in emails")
+	fs.BoolVar(&c.Dev, "dev", false, "Website: reload templates from ./ui on every request, for working on them")
+	env["dev"] = "RECALL_DEV"
+
+	str(&c.ResendAPIKey, "resend-api-key", "RESEND_API_KEY", "", "Resend API key; emails are not sent without one")
+	str(&c.MailFrom, "mail-from", "RECALL_MAIL_FROM", "Latest Alert <alert@latest.produseretrase.eu>", "Sender of all emails")
+	num(&c.EmailRate, "email-rate", "RECALL_EMAIL_RATE", 2, "Requests a second to Resend, at most the account's quota")
+	num(&c.EmailWorkers, "email-workers", "RECALL_EMAIL_WORKERS", 2, "Requests to Resend in flight at once")
+	num(&c.EmailRetries, "email-retries", "RECALL_EMAIL_RETRIES", 3, "Retries of a Resend request after a 429 response")
+	str(&c.TokenSigningKey, "token-signing-key", "TOKEN_SIGNING_KEY", "", "Key for signing preference links and hashing IP addresses; required by serve")
+
+	str(&c.Schedule, "schedule", "RECALL_SCHEDULE", "0 */2 * * *", "Cron schedule of the scheduled scrape and notify job")
+
+	dur(&c.WebhookTimeout, "webhook-timeout", "RECALL_WEBHOOK_TIMEOUT", 10*time.Second, "Timeout of each webhook request")
+	num(&c.WebhookRetries, "webhook-retries", "RECALL_WEBHOOK_RETRIES", 3, "Retries of a webhook request after a network error, 5xx, 408 or 429 response")
+	num(&c.WebhookMaxFailures, "webhook-max-failures", "RECALL_WEBHOOK_MAX_FAILURES", 5, "Recalls in a row a webhook may fail to accept before it is disabled")
+
+	str(&c.TelegramToken, "telegram-token", "TELEGRAM_BOT_TOKEN", "", "Telegram bot token; alerts are not sent to Telegram without one")
+	str(&c.TelegramAPIURL, "telegram-api-url", "RECALL_TELEGRAM_API_URL", "https://api.telegram.org", "Telegram Bot API server, e.g. a local fake server for testing")
+	dur(&c.TelegramTimeout, "telegram-timeout", "RECALL_TELEGRAM_TIMEOUT", 10*time.Second, "Timeout of each Telegram request")
+	num(&c.TelegramRetries, "telegram-retries", "RECALL_TELEGRAM_RETRIES", 3, "Retries of a Telegram message after a network error, 5xx or 429 response")
+
+	str(&c.VAPIDPrivateKey, "vapid-private-key", "VAPID_PRIVATE_KEY", "", "VAPID private key from recall webpush keys; browser notifications are off without one")
+	str(&c.VAPIDSubject, "vapid-subject", "RECALL_VAPID_SUBJECT", "", "mailto: or https: contact sent to push services (base-url if empty)")
+	dur(&c.PushTimeout, "push-timeout", "RECALL_PUSH_TIMEOUT", 10*time.Second, "Timeout of each push service request")
+	num(&c.PushRetries, "push-retries", "RECALL_PUSH_RETRIES", 3, "Retries of a push message after a network error, 5xx or 429 response")
+
+	str(&c.LogLevel, "log-level", "RECALL_LOG_LEVEL", "info", "Lowest level logged: debug, info, warn or error")
+	str(&c.LogFormat, "log-format", "RECALL_LOG_FORMAT", "json", "Log output: json or text")
+
+	str(&c.RateLimitStore, "ratelimit-store", "RECALL_RATELIMIT_STORE", "memory", "Rate limit state backend: memory or db")
+	str(&c.TrustedProxies, "trusted-proxies", "RECALL_TRUSTED_PROXIES", "", "Comma-separated CIDRs of proxies allowed to set X-Forwarded-For")
+	num(&c.IPLimit, "ratelimit-ip", "RECALL_RATELIMIT_IP", 3, "Subscription attempts allowed per IP per hour")
+	num(&c.IPBanAfter, "ratelimit-ip-ban", "RECALL_RATELIMIT_IP_BAN", 10, "Attempts per hour after which an IP is banned for a day")
+	num(&c.EmailLimit, "ratelimit-email", "RECALL_RATELIMIT_EMAIL", 3, "Confirmation emails allowed per address per day")
+	num(&c.GlobalLimit, "ratelimit-global", "RECALL_RATELIMIT_GLOBAL", 200, "Confirmation emails allowed in total per hour")
+
+	str(&c.ChallengeMode, "challenge", "RECALL_CHALLENGE", "auto", "Proof-of-work challenge on subscribe: off, auto or always")
+	str(&c.ChallengeKey, "challenge-key", "RECALL_CHALLENGE_KEY", "", "HMAC key for signing challenges, shared by all replicas")
+	num(&c.ChallengeThreshold, "challenge-threshold", "RECALL_CHALLENGE_THRESHOLD", 30, "Subscription attempts per hour after which auto mode requires a challenge")
+	num(&c.ChallengeDifficulty, "challenge-difficulty", "RECALL_CHALLENGE_DIFFICULTY", 100000, "Upper bound of the number the client has to find")
+
+	dur(&c.ConfirmCooldown, "confirm-cooldown", "RECALL_CONFIRM_COOLDOWN", 15*time.Minute, "Minimum time between confirmation emails to a pending address")
+
+	str(&c.Dictionary, "dictionary", "RECALL_DICTIONARY", "", "Path to a JSON keyword dictionary for tagging recalls (built-in if empty)")
+
+	str(&c.ListingURL, "listing-url", "RECALL_LISTING_URL", scraper.ScraperURL, "URL of the ANSVSA recall listing, e.g. a local fake server for testing")
+	str(&c.UserAgent, "user-agent", "RECALL_USER_AGENT", scraper.DefaultUserAgent, "User-Agent sent by the scraper")
+	dur(&c.HTTPTimeout, "http-timeout", "RECALL_HTTP_TIMEOUT", 30*time.Second, "Timeout of each scraper HTTP request")
+	num(&c.HTTPRetries, "http-retries", "RECALL_HTTP_RETRIES", 3, "Retries of a scraper request after a network error or 5xx response")
+	dur(&c.CrawlDelay, "crawl-delay", "RECALL_CRAWL_DELAY", time.Second, "Minimum delay between requests to the same host (robots.txt may raise it)")
+	dur(&c.ScrapeTimeout, "scrape-timeout", "RECALL_SCRAPE_TIMEOUT", 10*time.Minute, "Deadline for a whole scraper run")
+
+	fs.BoolVar(&c.DryRun, "dry-run", false, "Scraper and notifier: show what would be stored or sent without writing or sending anything")
+	env["dry-run"] = "RECALL_DRY_RUN"
+	str(&c.Output, "output", "RECALL_OUTPUT", "table", "Scraper dry run: print the parsed items as table or json")
+	str(&c.PreviewDir, "preview-dir", "RECALL_PREVIEW_DIR", "previews", "Notifier dry run: directory for .eml previews of the emails")
+
+	return env
+}
+
+// load parses args into fs and fills in every flag that was not given from
+// the lower layers.
+func load(fs *flag.FlagSet, args []string, lookupEnv func(string) (string, bool)) (*Config, error) {
 	conf := &Config{}
+	env := conf.define(fs)
 
-	flag.StringVar(&conf.DBUser, "dbuser", "", "Database user")
-	flag.StringVar(&conf.DBPassword, "dbpass", "", "Database password")
-	flag.StringVar(&conf.DBHost, "dbhost", "localhost", "Database host")
-	flag.StringVar(&conf.DBPort, "dbport", "3307", "Database port")
-	flag.StringVar(&conf.DBName, "dbname", "scraper_db", "Database name")
+	var configFile string
+	fs.StringVar(&configFile, "config", "", "Path to a TOML config file whose keys are the flag names (env RECALL_CONFIG)")
 
-	flag.Parse()
-	return conf
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	given := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) { given[f.Name] = true })
+
+	if configFile == "" {
+		configFile, _ = lookupEnv("RECALL_CONFIG")
+	}
+	var file map[string]string
+	if configFile != "" {
+		var err error
+		if file, err = readFile(configFile); err != nil {
+			return nil, err
+		}
+		for key := range file {
+			if _, ok := env[key]; !ok {
+				return nil, fmt.Errorf("%s: unknown setting %q", configFile, key)
+			}
+		}
+	}
+
+	var err error
+	fs.VisitAll(func(f *flag.Flag) {
+		envName, ok := env[f.Name]
+		if err != nil || !ok || given[f.Name] {
+			return
+		}
+		value, source, found := "", "", false
+		if v, ok := file[f.Name]; ok {
+			value, source, found = v, configFile, true
+		}
+		if v, ok := lookupEnv(envName); ok {
+			value, source, found = v, envName, true
+		}
+		if path, ok := lookupEnv(envName + "_FILE"); ok {
+			b, readErr := os.ReadFile(path)
+			if readErr != nil {
+				err = fmt.Errorf("%s_FILE: %w", envName, readErr)
+				return
+			}
+			value, source, found = trimNewline(string(b)), envName+"_FILE", true
+		}
+		if !found {
+			return
+		}
+		if setErr := f.Value.Set(value); setErr != nil {
+			err = fmt.Errorf("%s: invalid value for %s: %w", source, f.Name, setErr)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	conf.BaseURL = strings.TrimSuffix(conf.BaseURL, "/")
+	conf.TelegramAPIURL = strings.TrimSuffix(conf.TelegramAPIURL, "/")
+	if err := conf.Validate(); err != nil {
+		return nil, err
+	}
+	return conf, nil
+}
+
+// trimNewline drops the line break editors and echo leave at the end of a
+// secret file.
+func trimNewline(s string) string {
+	if len(s) > 0 && s[len(s)-1] == '\n' {
+		s = s[:len(s)-1]
+	}
+	if len(s) > 0 && s[len(s)-1] == '\r' {
+		s = s[:len(s)-1]
+	}
+	return s
 }
 
 func (c *Config) DSN() string {
@@ -0,0 +1,202 @@
+package configs
+
+import (
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testLoad loads a configuration from args and env alone.
+func testLoad(args []string, env map[string]string) (*Config, error) {
+	fs := flag.NewFlagSet("recall", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	return load(fs, args, func(name string) (string, bool) {
+		v, ok := env[name]
+		return v, ok
+	})
+}
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadDefaults(t *testing.T) {
+	conf, err := testLoad(nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conf.IPLimit != 3 || conf.ConfirmCooldown != 15*time.Minute || conf.Dev {
+		t.Errorf("defaults not applied: %+v", conf)
+	}
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	config := writeFile(t, "recall.toml", `ratelimit-ip = 1
+ratelimit-email = 1
+ratelimit-global = 1
+confirm-cooldown = "1m"
+`)
+	secret := writeFile(t, "global", "4\n")
+	env := map[string]string{
+		"RECALL_CONFIG":                config,
+		"RECALL_RATELIMIT_EMAIL":       "2",
+		"RECALL_RATELIMIT_GLOBAL":      "2",
+		"RECALL_RATELIMIT_GLOBAL_FILE": secret,
+	}
+	conf, err := testLoad([]string{"-ratelimit-ip", "5"}, env)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct {
+		setting   string
+		got, want int
+	}{
+		{"flag over everything", conf.IPLimit, 5},
+		{"environment over file", conf.EmailLimit, 2},
+		{"secret file over environment", conf.GlobalLimit, 4},
+		{"default", conf.IPBanAfter, 10},
+	} {
+		if tt.got != tt.want {
+			t.Errorf("%s: got %d, want %d", tt.setting, tt.got, tt.want)
+		}
+	}
+	if conf.ConfirmCooldown != time.Minute {
+		t.Errorf("config file: confirm-cooldown = %v, want 1m", conf.ConfirmCooldown)
+	}
+}
+
+func TestLoadConfigFlag(t *testing.T) {
+	flagFile := writeFile(t, "flag.toml", "dbname = 'from_flag'\n")
+	envFile := writeFile(t, "env.toml", "dbname = 'from_env'\n")
+	conf, err := testLoad([]string{"-config", flagFile}, map[string]string{"RECALL_CONFIG": envFile})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conf.DBName != "from_flag" {
+		t.Errorf("dbname = %q, want the one from -config", conf.DBName)
+	}
+}
+
+func TestLoadSecretFile(t *testing.T) {
+	for _, content := range []string{"s3cret", "s3cret\n", "s3cret\r\n"} {
+		path := writeFile(t, "password", content)
+		conf, err := testLoad(nil, map[string]string{"DB_PASSWORD_FILE": path})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if conf.DBPassword != "s3cret" {
+			t.Errorf("secret file %q: password = %q", content, conf.DBPassword)
+		}
+	}
+
+	// only one line break is an editor's; the rest are part of the secret
+	path := writeFile(t, "password", "s3cret\n\n")
+	conf, err := testLoad(nil, map[string]string{"DB_PASSWORD_FILE": path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conf.DBPassword != "s3cret\n" {
+		t.Errorf("password = %q, want one newline kept", conf.DBPassword)
+	}
+
+	_, err = testLoad(nil, map[string]string{"DB_PASSWORD_FILE": filepath.Join(t.TempDir(), "missing")})
+	if err == nil || !strings.Contains(err.Error(), "DB_PASSWORD_FILE") {
+		t.Errorf("missing secret file: got %v", err)
+	}
+}
+
+func TestLoadInvalidValue(t *testing.T) {
+	_, err := testLoad(nil, map[string]string{"RECALL_RATELIMIT_IP": "many"})
+	if err == nil || !strings.Contains(err.Error(), "RECALL_RATELIMIT_IP") {
+		t.Errorf("got %v, want an error naming RECALL_RATELIMIT_IP", err)
+	}
+}
+
+func TestLoadConfigFileErrors(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{"unknown key", "ratelimit-everything = 3\n", `unknown setting "ratelimit-everything"`},
+		{"duplicate key", "dbname = 'a'\ndbname = 'b'\n", ":2: dbname is set twice"},
+		{"table", "[database]\ndbname = 'a'\n", ":1: tables are not supported"},
+		{"array", "trusted-proxies = ['10.0.0.0/8']\n", "arrays and tables are not supported"},
+		{"inline table", "dbname = { a = 1 }\n", "arrays and tables are not supported"},
+		{"no value", "dbname =\n", "missing value"},
+		{"no equals sign", "dbname\n", "expected key = value"},
+		{"unterminated string", "dbname = \"recall\n", "unterminated string"},
+		{"bare word", "dbname = recall\n", "is not a string, number or boolean"},
+		{"text after value", "dbname = 'a' 'b'\n", "unexpected"},
+	} {
+		path := writeFile(t, "recall.toml", tt.content)
+		_, err := testLoad([]string{"-config", path}, nil)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: got %v, want an error containing %q", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestReadFile(t *testing.T) {
+	path := writeFile(t, "recall.toml", `# settings
+dbname = "recall_db"  # comment
+"dbuser" = 'raw\string'
+ratelimit-global = 1_000
+dev = true
+mail-from = "Alerts <a@example.org> # not a comment"
+`)
+	values, err := readFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"dbname":           "recall_db",
+		"dbuser":           `raw\string`,
+		"ratelimit-global": "1000",
+		"dev":              "true",
+		"mail-from":        "Alerts <a@example.org> # not a comment",
+	}
+	if len(values) != len(want) {
+		t.Errorf("got %d values, want %d: %v", len(values), len(want), values)
+	}
+	for key, v := range want {
+		if values[key] != v {
+			t.Errorf("%s = %q, want %q", key, values[key], v)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	_, err := testLoad([]string{
+		"-dbport", "99999",
+		"-email-rate", "0",
+		"-log-format", "xml",
+		"-trusted-proxies", "10.0.0.0/8, 10.0.0.1",
+		"-schedule", "every hour",
+	}, nil)
+	if err == nil {
+		t.Fatal("invalid configuration accepted")
+	}
+	// every problem is reported at once
+	for _, want := range []string{
+		`dbport "99999"`,
+		"email-rate must be positive",
+		`log-format must be json or text, not "xml"`,
+		`trusted-proxies: "10.0.0.1" is not a CIDR`,
+		`schedule "every hour"`,
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not mention %q", err, want)
+		}
+	}
+}
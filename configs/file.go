@@ -0,0 +1,113 @@
+package configs
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// readFile reads a config file in the subset of TOML that flat settings
+// need: one key = value pair per line, where the value is a quoted string,
+// a number or a boolean, and # starts a comment. Durations are strings
+// such as "15m". Tables and arrays are rejected.
+func readFile(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	values := make(map[string]string)
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		if line[0] == '[' {
+			return nil, fmt.Errorf("%s:%d: tables are not supported", path, n)
+		}
+
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("%s:%d: expected key = value", path, n)
+		}
+		key = strings.Trim(strings.TrimSpace(key), `"`)
+		value, err := parseValue(strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %s: %w", path, n, key, err)
+		}
+		if _, ok := values[key]; ok {
+			return nil, fmt.Errorf("%s:%d: %s is set twice", path, n, key)
+		}
+		values[key] = value
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+// parseValue returns the text of a TOML value with any trailing comment
+// removed.
+func parseValue(s string) (string, error) {
+	switch {
+	case s == "":
+		return "", fmt.Errorf("missing value")
+	case s[0] == '"':
+		end := closingQuote(s)
+		if end < 0 {
+			return "", fmt.Errorf("unterminated string")
+		}
+		if err := onlyComment(s[end+1:]); err != nil {
+			return "", err
+		}
+		return strconv.Unquote(s[:end+1])
+	case s[0] == '\'':
+		end := strings.IndexByte(s[1:], '\'')
+		if end < 0 {
+			return "", fmt.Errorf("unterminated string")
+		}
+		if err := onlyComment(s[end+2:]); err != nil {
+			return "", err
+		}
+		return s[1 : end+1], nil
+	case s[0] == '[' || s[0] == '{':
+		return "", fmt.Errorf("arrays and tables are not supported")
+	}
+
+	if i := strings.IndexByte(s, '#'); i >= 0 {
+		s = strings.TrimSpace(s[:i])
+	}
+	if s == "true" || s == "false" {
+		return s, nil
+	}
+	if _, err := strconv.ParseFloat(strings.ReplaceAll(s, "_", ""), 64); err != nil {
+		return "", fmt.Errorf("%q is not a string, number or boolean", s)
+	}
+	return strings.ReplaceAll(s, "_", ""), nil
+}
+
+// closingQuote returns the index of the quote that ends the basic string
+// at the start of s.
+func closingQuote(s string) int {
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '"':
+			return i
+		}
+	}
+	return -1
+}
+
+func onlyComment(rest string) error {
+	rest = strings.TrimSpace(rest)
+	if rest != "" && rest[0] != '#' {
+		return fmt.Errorf("unexpected %q after value", rest)
+	}
+	return nil
+}
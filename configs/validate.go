@@ -0,0 +1,109 @@
+package configs
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/mail"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Validate reports every setting that is missing or out of range. Secrets
+// only some commands need are checked by those commands.
+func (c *Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	check(c.DBHost != "", "dbhost is required")
+	check(c.DBName != "", "dbname is required")
+	port, err := strconv.Atoi(c.DBPort)
+	check(err == nil && port > 0 && port < 65536, "dbport %q is not a port number", c.DBPort)
+
+	_, _, err = net.SplitHostPort(c.Addr)
+	check(err == nil, "addr %q is not a host:port address", c.Addr)
+	check(isHTTPURL(c.BaseURL), "base-url %q is not an http or https URL", c.BaseURL)
+	check(isHTTPURL(c.ListingURL), "listing-url %q is not an http or https URL", c.ListingURL)
+
+	_, err = mail.ParseAddress(c.MailFrom)
+	check(err == nil, "mail-from %q is not an email address", c.MailFrom)
+	check(c.EmailRate > 0, "email-rate must be positive")
+	check(c.EmailWorkers > 0, "email-workers must be positive")
+	check(c.EmailRetries >= 0, "email-retries must not be negative")
+
+	check(validSchedule(c.Schedule), "schedule %q is not a five-field cron expression", c.Schedule)
+
+	check(c.WebhookTimeout > 0, "webhook-timeout must be positive")
+	check(c.WebhookRetries >= 0, "webhook-retries must not be negative")
+	check(c.WebhookMaxFailures > 0, "webhook-max-failures must be positive")
+
+	check(isHTTPURL(c.TelegramAPIURL), "telegram-api-url %q is not an http or https URL", c.TelegramAPIURL)
+	check(c.TelegramTimeout > 0, "telegram-timeout must be positive")
+	check(c.TelegramRetries >= 0, "telegram-retries must not be negative")
+
+	check(c.VAPIDSubject == "" || strings.HasPrefix(c.VAPIDSubject, "mailto:") || strings.HasPrefix(c.VAPIDSubject, "https://"),
+		"vapid-subject %q is not a mailto: or https: URL", c.VAPIDSubject)
+	check(c.PushTimeout > 0, "push-timeout must be positive")
+	check(c.PushRetries >= 0, "push-retries must not be negative")
+
+	var level slog.Level
+	check(level.UnmarshalText([]byte(c.LogLevel)) == nil, "log-level must be debug, info, warn or error, not %q", c.LogLevel)
+	check(c.LogFormat == "json" || c.LogFormat == "text", "log-format must be json or text, not %q", c.LogFormat)
+
+	check(c.RateLimitStore == "memory" || c.RateLimitStore == "db", "ratelimit-store must be memory or db, not %q", c.RateLimitStore)
+	for _, cidr := range strings.Split(c.TrustedProxies, ",") {
+		if cidr = strings.TrimSpace(cidr); cidr != "" {
+			_, _, err := net.ParseCIDR(cidr)
+			check(err == nil, "trusted-proxies: %q is not a CIDR", cidr)
+		}
+	}
+	check(c.IPLimit > 0, "ratelimit-ip must be positive")
+	check(c.IPBanAfter > 0, "ratelimit-ip-ban must be positive")
+	check(c.EmailLimit > 0, "ratelimit-email must be positive")
+	check(c.GlobalLimit > 0, "ratelimit-global must be positive")
+
+	switch c.ChallengeMode {
+	case "off", "auto", "always":
+	default:
+		check(false, "challenge must be off, auto or always, not %q", c.ChallengeMode)
+	}
+	check(c.ChallengeThreshold > 0, "challenge-threshold must be positive")
+	check(c.ChallengeDifficulty > 0, "challenge-difficulty must be positive")
+	check(c.ConfirmCooldown >= 0, "confirm-cooldown must not be negative")
+
+	check(c.UserAgent != "", "user-agent is required")
+	check(c.HTTPTimeout > 0, "http-timeout must be positive")
+	check(c.HTTPRetries >= 0, "http-retries must not be negative")
+	check(c.CrawlDelay >= 0, "crawl-delay must not be negative")
+	check(c.ScrapeTimeout > 0, "scrape-timeout must be positive")
+
+	check(c.Output == "table" || c.Output == "json", "output must be table or json, not %q", c.Output)
+
+	return errors.Join(errs...)
+}
+
+func isHTTPURL(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// validSchedule checks that s has the five fields of a crontab line and
+// only the characters they may contain.
+func validSchedule(s string) bool {
+	fields := strings.Fields(s)
+	if len(fields) != 5 {
+		return false
+	}
+	for _, field := range fields {
+		if strings.Trim(field, "0123456789*,/-") != "" {
+			return false
+		}
+	}
+	return true
+}
@@ -3,8 +3,13 @@ set -eu
 
 # Cron does not inherit the container environment. Keep only the variables
 # needed by the scheduled job in a root-readable runtime file.
-env | grep -E '^(DB_USER|DB_PASSWORD|DB_HOST|DB_NAME|DB_PORT|RESEND_API_KEY)=' > /etc/product-recall.env
+# export -p quotes the values, some of which contain spaces.
+export -p | grep -E '^export (DB_[A-Z_]*|RESEND_API_KEY(_FILE)?|TELEGRAM_BOT_TOKEN(_FILE)?|VAPID_PRIVATE_KEY(_FILE)?|RECALL_[A-Z_]*)=' > /etc/product-recall.env
 chmod 600 /etc/product-recall.env
 
-echo '0 */2 * * * . /etc/product-recall.env; /app/run-scraper-and-notify.sh >> /proc/1/fd/1 2>&1' | crontab -
+# recall validates the whole configuration, so a bad setting stops the
+# container here instead of failing every scheduled run
+schedule=$(/app/recall config schedule)
+
+echo "$schedule . /etc/product-recall.env; /app/run-scraper-and-notify.sh >> /proc/1/fd/1 2>&1" | crontab -
 exec cron -f
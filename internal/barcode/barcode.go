@@ -0,0 +1,116 @@
+// Package barcode finds GTIN/EAN barcodes and lot numbers in recall notices.
+package barcode
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+)
+
+var (
+	// digit runs, optionally printed in groups as on the package
+	// ("5 941234 567890")
+	gtinRx = regexp.MustCompile(`\b\d(?:[ ]?\d){7,13}\b`)
+
+	// an 8 digit run is only taken for an EAN-8 when it is labelled as a
+	// barcode, otherwise too many other numbers pass the check digit
+	labelledRx = regexp.MustCompile(`(?i)\b(?:ean|gtin|cod(?:ul)? de bare)\W{0,5}(\d{8})\b`)
+
+	// a phone number written as "0040 722 123 458" can pass the check digit
+	// of an EAN-13, so digits right after a phone label are skipped
+	phoneRx = regexp.MustCompile(`(?i)\b(?:tel|telefon|fax|mobil)\W{0,5}$`)
+
+	// "lot 123", "LOT: L2305", "lotul nr. 123", "loturile 12, 34 și 56"
+	lotRx = regexp.MustCompile(`(?i)\blot(?:ul|urile|uri|s)?\b\s*(?:(?:nr|număr|numar|number)\.?\s*)?[:\-]?\s*` +
+		`([a-z0-9][a-z0-9\-/.]*(?:(?:\s*[,;]\s*|\s+(?:și|si|and)\s+)[a-z0-9][a-z0-9\-/.]*)*)`)
+	lotSepRx = regexp.MustCompile(`(?i)\s*[,;]\s*|\s+(?:și|si|and)\s+`)
+)
+
+// Extract returns the valid GTINs, normalised with NormalizeGTIN, and the lot
+// numbers, normalised with NormalizeLot, found in texts.
+func Extract(texts ...string) (gtins, lots []string) {
+	for _, text := range texts {
+		for _, loc := range gtinRx.FindAllStringIndex(text, -1) {
+			code := strings.ReplaceAll(text[loc[0]:loc[1]], " ", "")
+			if len(code) == 8 || phoneRx.MatchString(text[:loc[0]]) {
+				continue
+			}
+			if gtin, ok := NormalizeGTIN(code); ok {
+				gtins = append(gtins, gtin)
+			}
+		}
+		for _, m := range labelledRx.FindAllStringSubmatch(text, -1) {
+			if gtin, ok := NormalizeGTIN(m[1]); ok {
+				gtins = append(gtins, gtin)
+			}
+		}
+		for _, m := range lotRx.FindAllStringSubmatch(text, -1) {
+			for _, part := range lotSepRx.Split(m[1], -1) {
+				lot := NormalizeLot(part)
+				// lot codes always carry a digit; this skips "lot de produse"
+				if strings.ContainsAny(lot, "0123456789") {
+					lots = append(lots, lot)
+				}
+			}
+		}
+	}
+	return compact(gtins), compact(lots)
+}
+
+// NormalizeGTIN checks the length and check digit of an EAN-8, UPC-A,
+// EAN-13 or GTIN-14 and returns it zero-padded to 14 digits, so the same
+// product matches however it was printed.
+func NormalizeGTIN(s string) (string, bool) {
+	s = strings.Map(func(r rune) rune {
+		if r == ' ' || r == '-' {
+			return -1
+		}
+		return r
+	}, s)
+
+	switch len(s) {
+	case 8, 12, 13, 14:
+	default:
+		return "", false
+	}
+
+	sum := 0
+	for i := range s {
+		c := s[len(s)-1-i]
+		if c < '0' || c > '9' {
+			return "", false
+		}
+		d := int(c - '0')
+		if i == 0 {
+			continue
+		}
+		if i%2 == 1 {
+			d *= 3
+		}
+		sum += d
+	}
+	if (10-sum%10)%10 != int(s[len(s)-1]-'0') {
+		return "", false
+	}
+
+	return strings.Repeat("0", 14-len(s)) + s, true
+}
+
+// NormalizeLot upper-cases a lot number and drops everything but letters and
+// digits, since notices and packages disagree on separators.
+func NormalizeLot(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= '0' && r <= '9', r >= 'A' && r <= 'Z':
+			return r
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		}
+		return -1
+	}, s)
+}
+
+func compact(s []string) []string {
+	slices.Sort(s)
+	return slices.Compact(s)
+}
@@ -0,0 +1,86 @@
+package barcode
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestNormalizeGTIN(t *testing.T) {
+	for _, tt := range []struct {
+		in   string
+		want string
+		ok   bool
+	}{
+		{"96385074", "00000096385074", true},
+		{"036000291452", "00036000291452", true},
+		{"5941234567899", "05941234567899", true},
+		{"10012345678902", "10012345678902", true},
+		{"5 941234 567899", "05941234567899", true},
+		{"5941234-567899", "05941234567899", true},
+		{"5941234567898", "", false},
+		{"96385075", "", false},
+		{"036000291453", "", false},
+		{"594123456789", "", false},
+		{"123456789", "", false},
+		{"594123456789X", "", false},
+		{"", "", false},
+	} {
+		got, ok := NormalizeGTIN(tt.in)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("NormalizeGTIN(%q) = %q, %v, want %q, %v", tt.in, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestNormalizeLot(t *testing.T) {
+	for in, want := range map[string]string{
+		"L2305":      "L2305",
+		"l-2305/a":   "L2305A",
+		"12.03.2025": "12032025",
+		" ab 12 ":    "AB12",
+		"":           "",
+	} {
+		if got := NormalizeLot(in); got != want {
+			t.Errorf("NormalizeLot(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestExtract(t *testing.T) {
+	for _, tt := range []struct {
+		text  string
+		gtins []string
+		lots  []string
+	}{
+		{"Cod de bare 5941234567899, lot L2305", []string{"05941234567899"}, []string{"L2305"}},
+		{"EAN 5 941234 567899", []string{"05941234567899"}, nil},
+		{"EAN: 96385074", []string{"00000096385074"}, nil},
+		{"cod de bare: 96385074", []string{"00000096385074"}, nil},
+		{"UPC 036000291452 și GTIN 10012345678902", []string{"00036000291452", "10012345678902"}, nil},
+		{"cod 5941234567898", nil, nil},
+		{"loturile 12, 34 și 56", nil, []string{"12", "34", "56"}},
+		{"LOT: L-23/05; L-24/05 and L-25/05", nil, []string{"L2305", "L2405", "L2505"}},
+		{"lotul nr. 2305A", nil, []string{"2305A"}},
+		{"retragerea unui lot de produse", nil, nil},
+		{"de la 5941234567899 la 5941234567899", []string{"05941234567899"}, nil},
+		// numbers that are not barcodes
+		{"data expirării 20250317", nil, nil},
+		{"expiră la 17.03.2025", nil, nil},
+		{"Tel: 0040 722 123 458", nil, nil},
+		{"telefon 0040722123458", nil, nil},
+		{"sunați la 0722 123 456", nil, nil},
+		{"sunați la 0040 722 123 456", nil, nil},
+	} {
+		gtins, lots := Extract(tt.text)
+		if !slices.Equal(gtins, tt.gtins) || !slices.Equal(lots, tt.lots) {
+			t.Errorf("Extract(%q) = %v, %v, want %v, %v", tt.text, gtins, lots, tt.gtins, tt.lots)
+		}
+	}
+}
+
+func TestExtractJoinsTexts(t *testing.T) {
+	gtins, lots := Extract("EAN 5941234567899", "lot 12", "EAN 5941234567899")
+	if !slices.Equal(gtins, []string{"05941234567899"}) || !slices.Equal(lots, []string{"12"}) {
+		t.Errorf("got %v, %v", gtins, lots)
+	}
+}
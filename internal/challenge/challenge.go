@@ -0,0 +1,40 @@
+package challenge
+
+import "errors"
+
+var ErrInvalid = errors.New("invalid challenge solution")
+
+// Challenge is sent to the browser, which has to find Number such that
+// sha256(Salt + Number) equals Challenge. The fields follow the ALTCHA
+// payload format so the widget script stays small.
+type Challenge struct {
+	Algorithm string `json:"algorithm"`
+	Challenge string `json:"challenge"`
+	MaxNumber int    `json:"maxnumber"`
+	Salt      string `json:"salt"`
+	Signature string `json:"signature"`
+}
+
+// Verifier issues challenges and checks the solutions posted back with the
+// subscription form.
+type Verifier interface {
+	Issue() (Challenge, error)
+	Verify(payload string) error
+}
+
+// Stub accepts exactly Answer. It lets the subscription flow be exercised
+// without a browser solving real challenges.
+type Stub struct {
+	Answer string
+}
+
+func (s Stub) Issue() (Challenge, error) {
+	return Challenge{Algorithm: "stub", Challenge: s.Answer}, nil
+}
+
+func (s Stub) Verify(payload string) error {
+	if payload == "" || payload != s.Answer {
+		return ErrInvalid
+	}
+	return nil
+}
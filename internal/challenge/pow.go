@@ -0,0 +1,131 @@
+package challenge
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"math/big"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ProofOfWork is a self-hosted, ALTCHA-compatible verifier. Challenges are
+// stateless: the server signs them and only remembers solved ones until they
+// expire, to stop a single solution from being replayed.
+type ProofOfWork struct {
+	key       []byte
+	maxNumber int
+	ttl       time.Duration
+
+	mu   sync.Mutex
+	used map[string]time.Time
+}
+
+func NewProofOfWork(key []byte, maxNumber int, ttl time.Duration) *ProofOfWork {
+	return &ProofOfWork{
+		key:       key,
+		maxNumber: maxNumber,
+		ttl:       ttl,
+		used:      make(map[string]time.Time),
+	}
+}
+
+func (p *ProofOfWork) Issue() (Challenge, error) {
+	b := make([]byte, 12)
+	if _, err := rand.Read(b); err != nil {
+		return Challenge{}, err
+	}
+	n, err := rand.Int(rand.Reader, big.NewInt(int64(p.maxNumber)+1))
+	if err != nil {
+		return Challenge{}, err
+	}
+
+	expires := time.Now().Add(p.ttl).Unix()
+	salt := hex.EncodeToString(b) + "?expires=" + strconv.FormatInt(expires, 10)
+	challenge := hashHex(salt + n.String())
+
+	return Challenge{
+		Algorithm: "SHA-256",
+		Challenge: challenge,
+		MaxNumber: p.maxNumber,
+		Salt:      salt,
+		Signature: p.sign(challenge),
+	}, nil
+}
+
+// Verify checks a base64 JSON payload as produced by the widget.
+func (p *ProofOfWork) Verify(payload string) error {
+	raw, err := base64.StdEncoding.DecodeString(payload)
+	if err != nil {
+		return ErrInvalid
+	}
+	var sol struct {
+		Algorithm string `json:"algorithm"`
+		Challenge string `json:"challenge"`
+		Number    int    `json:"number"`
+		Salt      string `json:"salt"`
+		Signature string `json:"signature"`
+	}
+	if err := json.Unmarshal(raw, &sol); err != nil {
+		return ErrInvalid
+	}
+
+	if sol.Algorithm != "SHA-256" || sol.Number < 0 || sol.Number > p.maxNumber {
+		return ErrInvalid
+	}
+	if !hmac.Equal([]byte(sol.Signature), []byte(p.sign(sol.Challenge))) {
+		return ErrInvalid
+	}
+	if hashHex(sol.Salt+strconv.Itoa(sol.Number)) != sol.Challenge {
+		return ErrInvalid
+	}
+
+	expires, err := saltExpiry(sol.Salt)
+	if err != nil || time.Now().After(expires) {
+		return ErrInvalid
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := time.Now()
+	for sig, exp := range p.used {
+		if now.After(exp) {
+			delete(p.used, sig)
+		}
+	}
+	if _, ok := p.used[sol.Signature]; ok {
+		return ErrInvalid
+	}
+	p.used[sol.Signature] = expires
+	return nil
+}
+
+func (p *ProofOfWork) sign(challenge string) string {
+	mac := hmac.New(sha256.New, p.key)
+	mac.Write([]byte(challenge))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func hashHex(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+func saltExpiry(salt string) (time.Time, error) {
+	_, query, _ := strings.Cut(salt, "?")
+	params, err := url.ParseQuery(query)
+	if err != nil {
+		return time.Time{}, err
+	}
+	unix, err := strconv.ParseInt(params.Get("expires"), 10, 64)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Unix(unix, 0), nil
+}
@@ -0,0 +1,102 @@
+package challenge
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// solve finds the number for c the way the widget does and returns the
+// payload it would post.
+func solve(t *testing.T, c Challenge) string {
+	t.Helper()
+	return payload(t, c, number(t, c))
+}
+
+func number(t *testing.T, c Challenge) int {
+	t.Helper()
+	for n := 0; n <= c.MaxNumber; n++ {
+		if hashHex(c.Salt+strconv.Itoa(n)) == c.Challenge {
+			return n
+		}
+	}
+	t.Fatal("challenge has no solution")
+	return 0
+}
+
+func payload(t *testing.T, c Challenge, number int) string {
+	t.Helper()
+	raw, err := json.Marshal(map[string]any{
+		"algorithm": c.Algorithm,
+		"challenge": c.Challenge,
+		"number":    number,
+		"salt":      c.Salt,
+		"signature": c.Signature,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return base64.StdEncoding.EncodeToString(raw)
+}
+
+func issue(t *testing.T, p *ProofOfWork) Challenge {
+	t.Helper()
+	c, err := p.Issue()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestProofOfWork(t *testing.T) {
+	p := NewProofOfWork([]byte("key"), 100, time.Minute)
+	if err := p.Verify(solve(t, issue(t, p))); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestProofOfWorkReplay(t *testing.T) {
+	p := NewProofOfWork([]byte("key"), 100, time.Minute)
+	solution := solve(t, issue(t, p))
+	if err := p.Verify(solution); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Verify(solution); err != ErrInvalid {
+		t.Errorf("replayed solution: got %v, want ErrInvalid", err)
+	}
+}
+
+func TestProofOfWorkExpired(t *testing.T) {
+	p := NewProofOfWork([]byte("key"), 100, -time.Minute)
+	if err := p.Verify(solve(t, issue(t, p))); err != ErrInvalid {
+		t.Errorf("expired challenge: got %v, want ErrInvalid", err)
+	}
+}
+
+func TestProofOfWorkRejects(t *testing.T) {
+	p := NewProofOfWork([]byte("key"), 100, time.Minute)
+	c := issue(t, p)
+
+	wrong := c
+	wrong.Challenge = hashHex("other")
+	forged := NewProofOfWork([]byte("other key"), 100, time.Minute)
+
+	for _, tt := range []struct {
+		name    string
+		payload string
+	}{
+		{"wrong number", payload(t, c, (number(t, c)+1)%101)},
+		{"number out of range", payload(t, c, 101)},
+		{"unsigned challenge", payload(t, wrong, 0)},
+		{"other key", solve(t, issue(t, forged))},
+		{"not base64", "%%%"},
+		{"not JSON", base64.StdEncoding.EncodeToString([]byte("{"))},
+		{"empty", ""},
+	} {
+		if err := p.Verify(tt.payload); err != ErrInvalid {
+			t.Errorf("%s: got %v, want ErrInvalid", tt.name, err)
+		}
+	}
+}
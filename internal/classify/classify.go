@@ -0,0 +1,155 @@
+package classify
+
+import (
+	_ "embed"
+	"encoding/json"
+	"os"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+const (
+	KindCategory = "category"
+	KindHazard   = "hazard"
+	KindAllergen = "allergen"
+
+	HazardUndeclaredAllergens = "undeclared_allergens"
+)
+
+// Allergens are the 14 allergens EU Regulation 1169/2011 requires to be
+// declared, in the order they are listed to subscribers.
+var Allergens = []string{
+	"gluten", "crustaceans", "eggs", "fish", "peanuts", "soy", "milk",
+	"nuts", "celery", "mustard", "sesame", "sulphites", "lupin", "molluscs",
+}
+
+type Tag struct {
+	Kind string
+	Slug string
+}
+
+// Dictionary maps each tag slug to the Romanian keywords that indicate it.
+// Keywords are matched against the start of words after lowercasing and
+// removing diacritics, so a stem such as "branz" matches "brânză" and
+// "brânzeturi". A keyword ending in "$" only matches whole words, for short
+// words that start longer unrelated ones, e.g. "vita$" for "vită" but not
+// "vitamină".
+type Dictionary struct {
+	Categories map[string][]string `json:"categories"`
+	Hazards    map[string][]string `json:"hazards"`
+	Allergens  map[string][]string `json:"allergens"`
+}
+
+//go:embed dictionary.json
+var defaultDictionary []byte
+
+// LoadDictionary reads a dictionary from path, or returns the built-in one
+// when path is empty.
+func LoadDictionary(path string) (Dictionary, error) {
+	b := defaultDictionary
+	if path != "" {
+		var err error
+		b, err = os.ReadFile(path)
+		if err != nil {
+			return Dictionary{}, err
+		}
+	}
+
+	var d Dictionary
+	err := json.Unmarshal(b, &d)
+	return d, err
+}
+
+// Tags returns every tag the dictionary can assign, by kind and slug.
+func (d Dictionary) Tags() []Tag {
+	var tags []Tag
+	for kind, m := range map[string]map[string][]string{KindCategory: d.Categories, KindHazard: d.Hazards, KindAllergen: d.Allergens} {
+		for slug := range m {
+			tags = append(tags, Tag{Kind: kind, Slug: slug})
+		}
+	}
+	slices.SortFunc(tags, func(a, b Tag) int {
+		return strings.Compare(a.Kind+a.Slug, b.Kind+b.Slug)
+	})
+	return tags
+}
+
+type rule struct {
+	tag      Tag
+	keywords []string
+}
+
+type Classifier struct {
+	rules []rule
+}
+
+func New(d Dictionary) *Classifier {
+	c := &Classifier{}
+	add := func(kind string, m map[string][]string) {
+		for slug, keywords := range m {
+			r := rule{tag: Tag{Kind: kind, Slug: slug}}
+			for _, kw := range keywords {
+				k := " " + normalize(kw)
+				if strings.HasSuffix(kw, "$") {
+					k += " "
+				}
+				r.keywords = append(r.keywords, k)
+			}
+			c.rules = append(c.rules, r)
+		}
+	}
+	add(KindCategory, d.Categories)
+	add(KindHazard, d.Hazards)
+	add(KindAllergen, d.Allergens)
+
+	slices.SortFunc(c.rules, func(a, b rule) int {
+		return strings.Compare(a.tag.Kind+a.tag.Slug, b.tag.Kind+b.tag.Slug)
+	})
+	return c
+}
+
+// Classify returns every tag with at least one keyword in any of texts.
+// Ingredient lists mention allergens in almost every recall, so allergen tags
+// are only kept when the recall is about undeclared allergens.
+func (c *Classifier) Classify(texts ...string) []Tag {
+	text := " " + normalize(strings.Join(texts, " ")) + " "
+
+	var (
+		tags       []Tag
+		undeclared bool
+	)
+	for _, r := range c.rules {
+		for _, kw := range r.keywords {
+			if strings.Contains(text, kw) {
+				tags = append(tags, r.tag)
+				if r.tag == (Tag{Kind: KindHazard, Slug: HazardUndeclaredAllergens}) {
+					undeclared = true
+				}
+				break
+			}
+		}
+	}
+
+	if !undeclared {
+		tags = slices.DeleteFunc(tags, func(t Tag) bool {
+			return t.Kind == KindAllergen
+		})
+	}
+	return tags
+}
+
+var diacritics = strings.NewReplacer(
+	"ă", "a", "â", "a", "î", "i",
+	"ș", "s", "ş", "s", "ț", "t", "ţ", "t",
+	"á", "a", "é", "e", "í", "i", "ó", "o", "ö", "o", "ő", "o", "ú", "u", "ü", "u", "ű", "u",
+)
+
+// normalize lowercases s, strips diacritics and turns punctuation into
+// single spaces so keywords only have to match word starts.
+func normalize(s string) string {
+	s = diacritics.Replace(strings.ToLower(s))
+	return strings.Join(strings.FieldsFunc(s, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}), " ")
+}
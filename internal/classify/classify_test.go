@@ -0,0 +1,69 @@
+package classify
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestClassify(t *testing.T) {
+	d, err := LoadDictionary("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := New(d)
+
+	for _, tt := range []struct {
+		text string
+		want []string
+	}{
+		{"Salam de porc afumat", []string{"category/meat"}},
+		{"Carne de vită tocată", []string{"category/meat"}},
+		{"Vitamina C 1000 mg", []string{"category/supplements"}},
+		{"Supliment alimentar cu magneziu", []string{"category/supplements"}},
+		{"Suplimentele alimentare, 60 de comprimate", []string{"category/supplements"}},
+		{"Informații suplimentare pe site", nil},
+		{"Tabletă de ciocolată neagră", nil},
+		{"Brânzeturi maturate", []string{"category/dairy"}},
+		{"Mere Golden", []string{"category/produce"}},
+		{"Perele sunt mereu proaspete", []string{"category/produce"}},
+		{"Vopsea pentru perete", nil},
+		{"Unt cu 82% grăsime", []string{"category/dairy"}},
+		{"Untură de porc", []string{"category/meat"}},
+		{"Pateu de ficat, prezență de Listeria monocytogenes", []string{"category/meat", "hazard/listeria"}},
+		{"Ciocolată cu lapte și alune", []string{"category/dairy", "category/produce"}},
+		{"Biscuiți cu alergen nedeclarat: lapte și alune", []string{"allergen/milk", "allergen/nuts", "category/dairy", "category/produce", "hazard/undeclared_allergens"}},
+		{"Alergen nedeclarat: zer praf", []string{"allergen/milk", "hazard/undeclared_allergens"}},
+		{"Alergen nedeclarat în băutura zero zahăr", []string{"hazard/undeclared_allergens"}},
+		{"Lista de alergeni de pe etichetă", nil},
+		{"Fragmente de sticlă în borcan", []string{"hazard/foreign_bodies"}},
+		{"", nil},
+	} {
+		var got []string
+		for _, tag := range c.Classify(tt.text) {
+			got = append(got, tag.Kind+"/"+tag.Slug)
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("Classify(%q) = %v, want %v", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestClassifyJoinsTexts(t *testing.T) {
+	c := New(Dictionary{Hazards: map[string][]string{"foreign_bodies": {"corp strain"}}})
+	if tags := c.Classify("un corp", "strain"); len(tags) != 1 {
+		t.Errorf("keyword across texts: got %v", tags)
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	for in, want := range map[string]string{
+		"Brânză":            "branza",
+		"ȘUNCĂ, țară":       "sunca tara",
+		"  a--b  ":          "a b",
+		"Sajtos kenyér 5kg": "sajtos kenyer 5kg",
+	} {
+		if got := normalize(in); got != want {
+			t.Errorf("normalize(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
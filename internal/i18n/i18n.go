@@ -0,0 +1,101 @@
+package i18n
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+//go:embed locales/*.json
+var localeFiles embed.FS
+
+// Default is used when nothing better can be negotiated, and for any key a
+// catalog is missing.
+const Default = "ro"
+
+// Catalog holds the translated messages of every supported locale.
+type Catalog struct {
+	messages map[string]map[string]string
+	locales  []string
+}
+
+// Load reads the embedded catalogs, one JSON file per locale.
+func Load() (*Catalog, error) {
+	entries, err := localeFiles.ReadDir("locales")
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Catalog{messages: make(map[string]map[string]string)}
+	for _, entry := range entries {
+		locale := strings.TrimSuffix(entry.Name(), ".json")
+		b, err := localeFiles.ReadFile(path.Join("locales", entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		var msgs map[string]string
+		if err := json.Unmarshal(b, &msgs); err != nil {
+			return nil, fmt.Errorf("locale %s: %w", locale, err)
+		}
+		c.messages[locale] = msgs
+		c.locales = append(c.locales, locale)
+	}
+
+	if _, ok := c.messages[Default]; !ok {
+		return nil, fmt.Errorf("missing catalog for default locale %q", Default)
+	}
+	slices.Sort(c.locales)
+	return c, nil
+}
+
+func (c *Catalog) Locales() []string {
+	return c.locales
+}
+
+func (c *Catalog) Supported(locale string) bool {
+	_, ok := c.messages[locale]
+	return ok
+}
+
+// T returns the message for key in locale, formatted with args. Missing
+// messages fall back to the default locale and then to the key itself.
+func (c *Catalog) T(locale, key string, args ...any) string {
+	msg, ok := c.messages[locale][key]
+	if !ok {
+		msg, ok = c.messages[Default][key]
+	}
+	if !ok {
+		return key
+	}
+	if len(args) > 0 {
+		return fmt.Sprintf(msg, args...)
+	}
+	return msg
+}
+
+// Negotiate picks the supported locale the client prefers most from an
+// Accept-Language header.
+func (c *Catalog) Negotiate(acceptLanguage string) string {
+	best, bestQ := Default, 0.0
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+
+		base, _, _ := strings.Cut(strings.ToLower(tag), "-")
+		if c.Supported(base) && q > bestQ {
+			best, bestQ = base, q
+		}
+	}
+	return best
+}
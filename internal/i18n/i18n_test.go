@@ -0,0 +1,58 @@
+package i18n
+
+import "testing"
+
+func TestNegotiate(t *testing.T) {
+	c, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct {
+		header string
+		want   string
+	}{
+		{"", Default},
+		{"en", "en"},
+		{"hu-HU,hu;q=0.9", "hu"},
+		{"ro-RO", "ro"},
+		{"EN-gb", "en"},
+		{"de-DE,en;q=0.5", "en"},
+		{"en;q=0.3, hu;q=0.8", "hu"},
+		{"hu;q=0.8, en", "en"},
+		{"fr, de;q=0.9", Default},
+		{"en;q=0", Default},
+		{"en;q=abc, hu;q=0.1", "hu"},
+		{"*", Default},
+	} {
+		if got := c.Negotiate(tt.header); got != tt.want {
+			t.Errorf("Negotiate(%q) = %q, want %q", tt.header, got, tt.want)
+		}
+	}
+}
+
+func TestT(t *testing.T) {
+	c := &Catalog{messages: map[string]map[string]string{
+		Default: {"greeting": "Salut, %s", "only.default": "implicit"},
+		"en":    {"greeting": "Hello, %s"},
+	}}
+
+	for _, tt := range []struct {
+		locale, key string
+		want        string
+	}{
+		{"en", "greeting", "Hello, Ana"},
+		{Default, "greeting", "Salut, Ana"},
+		{"en", "only.default", "implicit"},
+		{"fr", "greeting", "Salut, Ana"},
+		{"en", "missing.key", "missing.key"},
+	} {
+		var args []any
+		if tt.key == "greeting" {
+			args = []any{"Ana"}
+		}
+		if got := c.T(tt.locale, tt.key, args...); got != tt.want {
+			t.Errorf("T(%q, %q) = %q, want %q", tt.locale, tt.key, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+// Package logging sets up the structured logger every command uses and
+// holds the attributes log lines share.
+package logging
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// New returns a logger that writes records at level and above to w, as
+// JSON or, for reading in a terminal, as text.
+func New(w io.Writer, format, level string) (*slog.Logger, error) {
+	var l slog.Level
+	if err := l.UnmarshalText([]byte(level)); err != nil {
+		return nil, err
+	}
+	opts := &slog.HandlerOptions{Level: l}
+
+	switch format {
+	case "json":
+		return slog.New(slog.NewJSONHandler(w, opts)), nil
+	case "text":
+		return slog.New(slog.NewTextHandler(w, opts)), nil
+	}
+	return nil, fmt.Errorf("unknown log format %q", format)
+}
+
+// NewRunID returns a short random ID that ties together the lines of one
+// command run. The ID only has to tell runs apart, so if the system has no
+// randomness to give, the clock is used instead.
+func NewRunID() string {
+	b := make([]byte, 6)
+	if _, err := rand.Read(b); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano()&0xffffffffffff, 16)
+	}
+	return hex.EncodeToString(b)
+}
+
+// MaskEmail hides all but the first character of the local part, so logs
+// hold no full addresses.
+func MaskEmail(email string) string {
+	local, domain, ok := strings.Cut(email, "@")
+	if !ok || local == "" {
+		return "***"
+	}
+	_, size := utf8.DecodeRuneInString(local)
+	return local[:size] + "***@" + domain
+}
+
+func Email(email string) slog.Attr {
+	return slog.String("email", MaskEmail(email))
+}
+
+func ItemID(id int) slog.Attr {
+	return slog.Int("item_id", id)
+}
+
+func SubscriberID(id string) slog.Attr {
+	return slog.String("subscriber_id", id)
+}
+
+func Err(err error) slog.Attr {
+	return slog.Any("err", err)
+}
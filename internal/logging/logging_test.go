@@ -0,0 +1,56 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestMaskEmail(t *testing.T) {
+	for in, want := range map[string]string{
+		"ana.pop@example.org": "a***@example.org",
+		"a@example.org":       "a***@example.org",
+		"ștefan@example.ro":   "ș***@example.ro",
+		"@example.org":        "***",
+		"":                    "***",
+		"not-an-address":      "***",
+	} {
+		if got := MaskEmail(in); got != want {
+			t.Errorf("MaskEmail(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestNewRunID(t *testing.T) {
+	a, b := NewRunID(), NewRunID()
+	if len(a) != 12 || a == b {
+		t.Errorf("run IDs %q and %q, want two different 12 character IDs", a, b)
+	}
+}
+
+func TestNew(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := New(&buf, "json", "info")
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger.Debug("hidden")
+	logger.Info("subscribed", Email("ana@example.org"), Err(errors.New("boom")))
+
+	var line map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &line); err != nil {
+		t.Fatalf("%v in %q", err, buf.String())
+	}
+	if line["msg"] != "subscribed" || line["email"] != "a***@example.org" || line["err"] != "boom" {
+		t.Errorf("logged %v", line)
+	}
+
+	if _, err := New(&buf, "xml", "info"); err == nil || !strings.Contains(err.Error(), "xml") {
+		t.Errorf("unknown format: got %v", err)
+	}
+	if _, err := New(&buf, "text", "loud"); err == nil {
+		t.Error("unknown level accepted")
+	}
+}
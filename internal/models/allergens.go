@@ -0,0 +1,68 @@
+package models
+
+import (
+	"context"
+	"strings"
+)
+
+// SetSubscriberAllergens replaces the allergens a subscriber follows. With
+// only set, they receive nothing but recalls for those allergens.
+func (db *DB) SetSubscriberAllergens(ctx context.Context, id string, allergens []string, only bool) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM subscriber_allergens WHERE subscriber_id = ?`, id); err != nil {
+		return err
+	}
+	for _, allergen := range allergens {
+		query := `INSERT INTO subscriber_allergens (subscriber_id, allergen) VALUES (?, ?)`
+		if _, err := tx.ExecContext(ctx, query, id, allergen); err != nil {
+			return err
+		}
+	}
+
+	query := `UPDATE subscribers SET allergen_only = ? WHERE id = ?`
+	if _, err := tx.ExecContext(ctx, query, only && len(allergens) > 0, id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// loadAllergens fills in the Allergens of every subscriber with one query.
+func (db *DB) loadAllergens(ctx context.Context, subscribers []Subscriber) error {
+	if len(subscribers) == 0 {
+		return nil
+	}
+
+	index := make(map[string]int, len(subscribers))
+	args := make([]any, len(subscribers))
+	for i, s := range subscribers {
+		index[s.ID] = i
+		args[i] = s.ID
+	}
+
+	query := `
+        SELECT subscriber_id, allergen
+        FROM subscriber_allergens
+        WHERE subscriber_id IN (?` + strings.Repeat(", ?", len(subscribers)-1) + `)
+        ORDER BY allergen
+    `
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id, allergen string
+		if err := rows.Scan(&id, &allergen); err != nil {
+			return err
+		}
+		i := index[id]
+		subscribers[i].Allergens = append(subscribers[i].Allergens, allergen)
+	}
+	return rows.Err()
+}
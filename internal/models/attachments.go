@@ -0,0 +1,68 @@
+package models
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/paluras/product-recall-system/internal/scraper"
+)
+
+// Attachment is a PDF linked from a recall notice.
+type Attachment struct {
+	URL       string
+	SHA256    string
+	Size      int
+	Text      string
+	CreatedAt time.Time
+}
+
+func FromScraperAttachment(a scraper.Attachment) Attachment {
+	return Attachment{
+		URL:    a.URL,
+		SHA256: a.SHA256,
+		Size:   a.Size,
+		Text:   a.Text,
+	}
+}
+
+// AddAttachment stores an attachment of an item, replacing the previous
+// copy of the same URL.
+func (db *DB) AddAttachment(ctx context.Context, itemID int, a Attachment) error {
+	query := `
+        INSERT INTO item_attachments (item_id, url, sha256, size, text)
+        VALUES (?, ?, ?, ?, ?)
+        ON DUPLICATE KEY UPDATE sha256 = VALUES(sha256), size = VALUES(size), text = VALUES(text)
+    `
+	_, err := db.ExecContext(ctx, query, itemID, a.URL, a.SHA256, a.Size, a.Text)
+	return err
+}
+
+func (db *DB) GetAttachments(ctx context.Context, itemID int) ([]Attachment, error) {
+	query := `SELECT url, sha256, size, text, created_at FROM item_attachments WHERE item_id = ? ORDER BY id`
+	rows, err := db.QueryContext(ctx, query, itemID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var attachments []Attachment
+	for rows.Next() {
+		var a Attachment
+		if err := rows.Scan(&a.URL, &a.SHA256, &a.Size, &a.Text, &a.CreatedAt); err != nil {
+			return nil, err
+		}
+		attachments = append(attachments, a)
+	}
+	return attachments, rows.Err()
+}
+
+// AttachmentText joins the text of every attachment, for the classifier and
+// the code extractor.
+func AttachmentText(attachments []Attachment) string {
+	texts := make([]string, len(attachments))
+	for i, a := range attachments {
+		texts[i] = a.Text
+	}
+	return strings.Join(texts, "\n")
+}
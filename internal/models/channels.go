@@ -0,0 +1,170 @@
+package models
+
+import (
+	"context"
+	"strings"
+	"time"
+)
+
+// ChannelTelegram is the channel of subscribers who chat with the Telegram
+// bot; their address is the chat ID.
+const ChannelTelegram = "telegram"
+
+// ChannelSubscriber receives alerts through a channel other than email.
+type ChannelSubscriber struct {
+	ID      int
+	Channel string
+	Address string
+	Locale  string
+	// Tags are the tag slugs the subscriber follows; without any they
+	// receive every recall.
+	Tags      []string
+	CreatedAt time.Time
+}
+
+// AddChannelSubscriber registers address on channel, or keeps the existing
+// registration, and returns its ID.
+func (db *DB) AddChannelSubscriber(ctx context.Context, channel, address, locale string) (int, error) {
+	query := `
+        INSERT INTO subscriber_channels (channel, address, locale)
+        VALUES (?, ?, ?)
+        ON DUPLICATE KEY UPDATE id = LAST_INSERT_ID(id)
+    `
+	result, err := db.ExecContext(ctx, query, channel, address, locale)
+	if err != nil {
+		return 0, err
+	}
+	id, err := result.LastInsertId()
+	return int(id), err
+}
+
+func (db *DB) GetChannelSubscriber(ctx context.Context, channel, address string) (*ChannelSubscriber, error) {
+	subscribers, err := db.getChannelSubscribers(ctx, `WHERE channel = ? AND address = ?`, channel, address)
+	if err != nil {
+		return nil, err
+	}
+	if len(subscribers) == 0 {
+		return nil, ErrNoRecord
+	}
+	return &subscribers[0], nil
+}
+
+// GetChannelSubscribers returns everyone who receives alerts on channel.
+func (db *DB) GetChannelSubscribers(ctx context.Context, channel string) ([]ChannelSubscriber, error) {
+	return db.getChannelSubscribers(ctx, `WHERE channel = ?`, channel)
+}
+
+func (db *DB) getChannelSubscribers(ctx context.Context, where string, args ...any) ([]ChannelSubscriber, error) {
+	query := `SELECT id, channel, address, locale, created_at FROM subscriber_channels ` + where + ` ORDER BY id`
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var subscribers []ChannelSubscriber
+	for rows.Next() {
+		var s ChannelSubscriber
+		if err := rows.Scan(&s.ID, &s.Channel, &s.Address, &s.Locale, &s.CreatedAt); err != nil {
+			return nil, err
+		}
+		subscribers = append(subscribers, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return subscribers, db.loadChannelTags(ctx, subscribers)
+}
+
+// loadChannelTags fills in the Tags of every subscriber with one query.
+func (db *DB) loadChannelTags(ctx context.Context, subscribers []ChannelSubscriber) error {
+	if len(subscribers) == 0 {
+		return nil
+	}
+
+	index := make(map[int]int, len(subscribers))
+	args := make([]any, len(subscribers))
+	for i, s := range subscribers {
+		index[s.ID] = i
+		args[i] = s.ID
+	}
+
+	query := `
+        SELECT channel_id, tag
+        FROM channel_tags
+        WHERE channel_id IN (?` + strings.Repeat(", ?", len(subscribers)-1) + `)
+        ORDER BY tag
+    `
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			id  int
+			tag string
+		)
+		if err := rows.Scan(&id, &tag); err != nil {
+			return err
+		}
+		i := index[id]
+		subscribers[i].Tags = append(subscribers[i].Tags, tag)
+	}
+	return rows.Err()
+}
+
+// RemoveChannelSubscriber deletes the registration with its filters and
+// delivery log.
+func (db *DB) RemoveChannelSubscriber(ctx context.Context, id int) error {
+	return db.execOne(ctx, `DELETE FROM subscriber_channels WHERE id = ?`, id)
+}
+
+// SetChannelTags replaces the tags a subscriber follows.
+func (db *DB) SetChannelTags(ctx context.Context, id int, tags []string) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM channel_tags WHERE channel_id = ?`, id); err != nil {
+		return err
+	}
+	for _, tag := range tags {
+		query := `INSERT IGNORE INTO channel_tags (channel_id, tag) VALUES (?, ?)`
+		if _, err := tx.ExecContext(ctx, query, id, tag); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (db *DB) SetChannelLocale(ctx context.Context, id int, locale string) error {
+	_, err := db.ExecContext(ctx, `UPDATE subscriber_channels SET locale = ? WHERE id = ?`, locale, id)
+	return err
+}
+
+// RecordChannelDelivery logs whether an item reached a channel subscriber.
+func (db *DB) RecordChannelDelivery(ctx context.Context, id, itemID int, sendErr error) error {
+	status, errText := "sent", ""
+	if sendErr != nil {
+		status, errText = "failed", sendErr.Error()
+		if len(errText) > 500 {
+			errText = errText[:500]
+		}
+	}
+	query := `INSERT INTO channel_deliveries (channel_id, item_id, status, error) VALUES (?, ?, ?, ?)`
+	_, err := db.ExecContext(ctx, query, id, itemID, status, errText)
+	return err
+}
+
+// ChannelDelivered reports whether the item already reached the subscriber,
+// so a notify run that is repeated does not send it twice.
+func (db *DB) ChannelDelivered(ctx context.Context, id, itemID int) (bool, error) {
+	query := `SELECT COUNT(*) FROM channel_deliveries WHERE channel_id = ? AND item_id = ? AND status = 'sent'` + sinceRenotified
+	var n int
+	err := db.QueryRowContext(ctx, query, id, itemID, itemID).Scan(&n)
+	return n > 0, err
+}
@@ -0,0 +1,99 @@
+package models
+
+import (
+	"context"
+	"strings"
+)
+
+const (
+	CodeGTIN = "gtin"
+	CodeLot  = "lot"
+)
+
+// SetItemCodes replaces the barcodes and lot numbers recorded for an item.
+// Codes are expected to be normalised by the barcode package.
+func (db *DB) SetItemCodes(ctx context.Context, itemID int, gtins, lots []string) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM item_codes WHERE item_id = ?`, itemID); err != nil {
+		return err
+	}
+
+	insert := func(kind string, codes []string) error {
+		for _, code := range codes {
+			if _, err := tx.ExecContext(ctx, `INSERT IGNORE INTO item_codes (item_id, kind, code) VALUES (?, ?, ?)`, itemID, kind, code); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := insert(CodeGTIN, gtins); err != nil {
+		return err
+	}
+	if err := insert(CodeLot, lots); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// CheckProduct returns the recalls matching a product in hand. With a GTIN,
+// an item matches when it lists the barcode and either the lot or no lot at
+// all, since a notice without lot numbers recalls every lot. With only a lot
+// the lot has to be listed.
+func (db *DB) CheckProduct(ctx context.Context, gtin, lot string) ([]ScrapedItem, error) {
+	var (
+		where []string
+		args  []any
+	)
+	if gtin != "" {
+		where = append(where, `EXISTS (SELECT 1 FROM item_codes c WHERE c.item_id = si.id AND c.kind = 'gtin' AND c.code = ?)`)
+		args = append(args, gtin)
+		if lot != "" {
+			where = append(where, `(EXISTS (SELECT 1 FROM item_codes c WHERE c.item_id = si.id AND c.kind = 'lot' AND c.code = ?)
+                OR NOT EXISTS (SELECT 1 FROM item_codes c WHERE c.item_id = si.id AND c.kind = 'lot'))`)
+			args = append(args, lot)
+		}
+	} else {
+		where = append(where, `EXISTS (SELECT 1 FROM item_codes c WHERE c.item_id = si.id AND c.kind = 'lot' AND c.code = ?)`)
+		args = append(args, lot)
+	}
+
+	query := `
+        SELECT si.id, si.title, si.link, si.date, si.created_at
+        FROM scraped_items si
+        WHERE ` + strings.Join(where, " AND ") + `
+        ORDER BY si.date DESC
+        LIMIT 50
+    `
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []ScrapedItem
+	for rows.Next() {
+		var item ScrapedItem
+		err := rows.Scan(
+			&item.ID,
+			&item.Title,
+			&item.Link,
+			&item.Date,
+			&item.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return items, db.loadTags(ctx, items)
+}
@@ -2,6 +2,7 @@ package models
 
 import (
 	"database/sql"
+	"log/slog"
 	"time"
 
 	_ "github.com/go-sql-driver/mysql"
@@ -9,9 +10,10 @@ import (
 
 type DB struct {
 	*sql.DB
+	logger *slog.Logger
 }
 
-func NewDB(dsn string) (*DB, error) {
+func NewDB(dsn string, logger *slog.Logger) (*DB, error) {
 	db, err := sql.Open("mysql", dsn)
 	if err != nil {
 		return nil, err
@@ -25,5 +27,5 @@ func NewDB(dsn string) (*DB, error) {
 	db.SetMaxIdleConns(25)
 	db.SetConnMaxLifetime(5 * time.Minute)
 
-	return &DB{db}, nil
+	return &DB{DB: db, logger: logger}, nil
 }
@@ -0,0 +1,76 @@
+package models
+
+import (
+	"context"
+	"time"
+)
+
+// ChannelEmail is the channel of confirmed subscribers, whose deliveries are
+// kept in deliveries rather than channel_deliveries.
+const ChannelEmail = "email"
+
+type Delivery struct {
+	ItemID    int       `json:"item_id"`
+	Channel   string    `json:"channel"`
+	Status    string    `json:"status"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// RecordDeliveries logs one delivery per item sent to the subscriber with
+// the given email. A non-nil sendErr is stored as a failed delivery.
+func (db *DB) RecordDeliveries(ctx context.Context, email, channel string, items []ScrapedItem, sendErr error) error {
+	status, errText := "sent", ""
+	if sendErr != nil {
+		status, errText = "failed", sendErr.Error()
+		if len(errText) > 500 {
+			errText = errText[:500]
+		}
+	}
+
+	query := `
+        INSERT INTO deliveries (subscriber_id, item_id, channel, status, error)
+        SELECT id, ?, ?, ?, ? FROM subscribers WHERE email = ?
+    `
+	for _, item := range items {
+		if _, err := db.ExecContext(ctx, query, item.ID, channel, status, errText, email); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Delivered reports whether the item already reached the subscriber over
+// channel, so a notify run that is repeated does not send it twice.
+func (db *DB) Delivered(ctx context.Context, subscriberID, channel string, itemID int) (bool, error) {
+	query := `
+        SELECT COUNT(*) FROM deliveries
+        WHERE subscriber_id = ? AND item_id = ? AND channel = ? AND status = 'sent'
+    ` + sinceRenotified
+	var n int
+	err := db.QueryRowContext(ctx, query, subscriberID, itemID, channel, itemID).Scan(&n)
+	return n > 0, err
+}
+
+func (db *DB) GetDeliveries(ctx context.Context, subscriberID string) ([]Delivery, error) {
+	query := `
+        SELECT item_id, channel, status, created_at
+        FROM deliveries
+        WHERE subscriber_id = ?
+        ORDER BY created_at
+    `
+	rows, err := db.QueryContext(ctx, query, subscriberID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	deliveries := []Delivery{}
+	for rows.Next() {
+		var d Delivery
+		if err := rows.Scan(&d.ItemID, &d.Channel, &d.Status, &d.CreatedAt); err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, rows.Err()
+}
@@ -0,0 +1,8 @@
+package models
+
+import "errors"
+
+var (
+	ErrNoRecord = errors.New("models: no matching record found")
+	ErrCooldown = errors.New("models: token was issued too recently")
+)
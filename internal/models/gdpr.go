@@ -0,0 +1,173 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// Consent records what a subscriber agreed to and when. The IP address is
+// only kept as a keyed hash.
+type Consent struct {
+	Action      string    `json:"action"`
+	IPHash      string    `json:"ip_hash"`
+	FormVersion string    `json:"form_version"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type AuditEntry struct {
+	ID           int
+	Action       string
+	SubscriberID string
+	Actor        string
+	CreatedAt    time.Time
+}
+
+// SubscriberExport is everything we hold about one subscriber, as sent to
+// them on request.
+type SubscriberExport struct {
+	Email        string     `json:"email"`
+	CreatedAt    time.Time  `json:"created_at"`
+	Confirmed    bool       `json:"confirmed"`
+	PausedUntil  *time.Time `json:"paused_until,omitempty"`
+	Locale       string     `json:"locale"`
+	Allergens    []string   `json:"allergens"`
+	AllergenOnly bool       `json:"allergen_only"`
+	Consents     []Consent  `json:"consents"`
+	Deliveries   []Delivery `json:"deliveries"`
+}
+
+func insertConsent(ctx context.Context, tx *sql.Tx, subscriberID any, c Consent) error {
+	query := `INSERT INTO consent_records (subscriber_id, action, ip_hash, form_version) VALUES (?, ?, ?, ?)`
+	_, err := tx.ExecContext(ctx, query, subscriberID, c.Action, c.IPHash, c.FormVersion)
+	return err
+}
+
+func (db *DB) ExportSubscriber(ctx context.Context, id string) (*SubscriberExport, error) {
+	sub, err := db.GetSubscriber(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	export := &SubscriberExport{
+		Email:        sub.Email,
+		CreatedAt:    sub.CreatedAt,
+		Confirmed:    sub.Confirmed,
+		Locale:       sub.Locale,
+		Allergens:    append([]string{}, sub.Allergens...),
+		AllergenOnly: sub.AllergenOnly,
+		Consents:     []Consent{},
+		Deliveries:   []Delivery{},
+	}
+	if !sub.PausedUntil.IsZero() {
+		export.PausedUntil = &sub.PausedUntil
+	}
+
+	query := `SELECT action, ip_hash, form_version, created_at FROM consent_records WHERE subscriber_id = ? ORDER BY created_at`
+	rows, err := db.QueryContext(ctx, query, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var c Consent
+		if err := rows.Scan(&c.Action, &c.IPHash, &c.FormVersion, &c.CreatedAt); err != nil {
+			return nil, err
+		}
+		export.Consents = append(export.Consents, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	export.Deliveries, err = db.GetDeliveries(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return export, nil
+}
+
+// EraseSubscriber removes the subscriber together with their consent records
+// and delivery log, and leaves an audit entry that holds no personal data.
+func (db *DB) EraseSubscriber(ctx context.Context, id, actor string) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// consent_records and deliveries follow through ON DELETE CASCADE
+	result, err := tx.ExecContext(ctx, `DELETE FROM subscribers WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrNoRecord
+	}
+
+	if err := insertAudit(ctx, tx, "erase", id, actor); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (db *DB) RecordAudit(ctx context.Context, action, subscriberID, actor string) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := insertAudit(ctx, tx, action, subscriberID, actor); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func insertAudit(ctx context.Context, tx *sql.Tx, action, subscriberID, actor string) error {
+	query := `INSERT INTO audit_log (action, subscriber_id, actor) VALUES (?, ?, ?)`
+	_, err := tx.ExecContext(ctx, query, action, subscriberID, actor)
+	return err
+}
+
+func (db *DB) GetAuditLog(ctx context.Context, limit int) ([]AuditEntry, error) {
+	query := `
+        SELECT id, action, subscriber_id, actor, created_at
+        FROM audit_log
+        ORDER BY id DESC
+        LIMIT ?
+    `
+	rows, err := db.QueryContext(ctx, query, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []AuditEntry
+	for rows.Next() {
+		var e AuditEntry
+		if err := rows.Scan(&e.ID, &e.Action, &e.SubscriberID, &e.Actor, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+// subscriberIDByConfirmationToken is used to attach the confirmation consent
+// to the right subscriber before the token is cleared.
+func subscriberIDByConfirmationToken(ctx context.Context, tx *sql.Tx, token string) (int64, error) {
+	var id int64
+	query := `SELECT id FROM subscribers WHERE confirmation_token = ? AND confirmed = FALSE FOR UPDATE`
+	err := tx.QueryRowContext(ctx, query, token).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, ErrNoRecord
+	}
+	return id, err
+}
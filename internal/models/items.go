@@ -1,20 +1,34 @@
 package models
 
 import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
 	"time"
 
 	"github.com/paluras/product-recall-system/internal/scraper"
 )
 
 type ScrapedItem struct {
-	ID        int
-	Title     string
-	Link      string
-	Date      time.Time
-	CreatedAt time.Time
+	ID          int
+	Title       string
+	Link        string
+	Date        time.Time
+	CreatedAt   time.Time
+	DetailText  string
+	Tags        []Tag
+	ContentHash string
+	LastSeenAt  time.Time
+	WithdrawnAt time.Time
 }
 
-func (db *DB) GetLatest20Items() ([]ScrapedItem, error) {
+// Withdrawn reports whether the article has disappeared from the source.
+func (i ScrapedItem) Withdrawn() bool {
+	return !i.WithdrawnAt.IsZero()
+}
+
+func (db *DB) GetLatest20Items(ctx context.Context) ([]ScrapedItem, error) {
 	query := `
         SELECT id, title, link, date, created_at
         FROM scraped_items
@@ -22,7 +36,7 @@ func (db *DB) GetLatest20Items() ([]ScrapedItem, error) {
         LIMIT 20
     `
 
-	rows, err := db.Query(query)
+	rows, err := db.QueryContext(ctx, query)
 	if err != nil {
 		return nil, err
 	}
@@ -44,34 +58,19 @@ func (db *DB) GetLatest20Items() ([]ScrapedItem, error) {
 		items = append(items, item)
 	}
 
-	return items, nil
+	return items, db.loadTags(ctx, items)
 }
 
 func FromScraperData(data scraper.ScrapedData) ScrapedItem {
 	return ScrapedItem{
-		Title: data.Title,
-		Link:  data.Link,
-		Date:  data.Date,
+		Title:       data.Title,
+		Link:        data.Link,
+		Date:        data.Date,
+		ContentHash: ContentHash(data.Title, data.Date),
 	}
 }
 
-func (db *DB) InsertItem(item ScrapedItem) error {
-	query := `
-        INSERT INTO scraped_items (title, link, date)
-        VALUES (?, ?, ?)
-    `
-	_, err := db.Exec(query, item.Title, item.Link, item.Date)
-	return err
-}
-
-func (db *DB) ItemExists(link string) (bool, error) {
-	var exists bool
-	query := "SELECT EXISTS(SELECT 1 FROM scraped_items WHERE link = ?)"
-	err := db.QueryRow(query, link).Scan(&exists)
-	return exists, err
-}
-
-func (db *DB) GetLatestItems(limit int) ([]ScrapedItem, error) {
+func (db *DB) GetLatestItems(ctx context.Context, limit int) ([]ScrapedItem, error) {
 	query := `
         SELECT id, title, link, date, created_at
         FROM scraped_items
@@ -79,7 +78,7 @@ func (db *DB) GetLatestItems(limit int) ([]ScrapedItem, error) {
         LIMIT ?
     `
 
-	rows, err := db.Query(query, limit)
+	rows, err := db.QueryContext(ctx, query, limit)
 	if err != nil {
 		return nil, err
 	}
@@ -101,17 +100,17 @@ func (db *DB) GetLatestItems(limit int) ([]ScrapedItem, error) {
 		items = append(items, item)
 	}
 
-	return items, nil
+	return items, db.loadTags(ctx, items)
 }
 
-func (db *DB) GetUnnotifiedItems() ([]ScrapedItem, error) {
+func (db *DB) GetUnnotifiedItems(ctx context.Context) ([]ScrapedItem, error) {
 	query := `
         SELECT id, title, link, date, created_at
         FROM scraped_items
         WHERE notified = FALSE
         ORDER BY date DESC
     `
-	rows, err := db.Query(query)
+	rows, err := db.QueryContext(ctx, query)
 	if err != nil {
 		return nil, err
 	}
@@ -132,11 +131,116 @@ func (db *DB) GetUnnotifiedItems() ([]ScrapedItem, error) {
 		}
 		items = append(items, item)
 	}
-	return items, nil
+	return items, db.loadTags(ctx, items)
 }
 
-func (db *DB) MarkAsNotified(itemID int) error {
+func (db *DB) MarkAsNotified(ctx context.Context, itemID int) error {
 	query := `UPDATE scraped_items SET notified = TRUE WHERE id = ?`
-	_, err := db.Exec(query, itemID)
+	_, err := db.ExecContext(ctx, query, itemID)
+	return err
+}
+
+// MarkAsUnnotified queues an item to be announced again by the next
+// notifier run. Deliveries before now no longer count for it.
+func (db *DB) MarkAsUnnotified(ctx context.Context, itemID int) error {
+	query := `UPDATE scraped_items SET notified = FALSE, renotified_at = CURRENT_TIMESTAMP WHERE id = ?`
+	_, err := db.ExecContext(ctx, query, itemID)
 	return err
 }
+
+// sinceRenotified limits a delivery log query to the deliveries of an item
+// since it was last queued again with MarkAsUnnotified. It takes the
+// item's ID as an argument.
+const sinceRenotified = `
+    AND created_at >= COALESCE((SELECT renotified_at FROM scraped_items WHERE id = ?), created_at)
+`
+
+func (db *DB) GetItemIDByLink(ctx context.Context, link string) (int, error) {
+	var id int
+	err := db.QueryRowContext(ctx, `SELECT id FROM scraped_items WHERE link = ?`, link).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, ErrNoRecord
+	}
+	return id, err
+}
+
+func (db *DB) SetItemDetail(ctx context.Context, itemID int, detailText string) error {
+	_, err := db.ExecContext(ctx, `UPDATE scraped_items SET detail_text = ? WHERE id = ?`, detailText, itemID)
+	return err
+}
+
+// GetItem returns one item with its article text.
+func (db *DB) GetItem(ctx context.Context, id int) (*ScrapedItem, error) {
+	query := `
+        SELECT id, title, link, date, created_at, COALESCE(detail_text, ''), withdrawn_at
+        FROM scraped_items
+        WHERE id = ?
+    `
+	var (
+		item        ScrapedItem
+		withdrawnAt sql.NullTime
+	)
+	err := db.QueryRowContext(ctx, query, id).Scan(
+		&item.ID,
+		&item.Title,
+		&item.Link,
+		&item.Date,
+		&item.CreatedAt,
+		&item.DetailText,
+		&withdrawnAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNoRecord
+	}
+	if err != nil {
+		return nil, err
+	}
+	item.WithdrawnAt = withdrawnAt.Time
+
+	items := []ScrapedItem{item}
+	if err := db.loadTags(ctx, items); err != nil {
+		return nil, err
+	}
+	return &items[0], nil
+}
+
+// SearchItems returns the newest items whose title, article or attachments
+// contain q.
+func (db *DB) SearchItems(ctx context.Context, q string, limit int) ([]ScrapedItem, error) {
+	pattern := "%" + strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(q) + "%"
+	query := `
+        SELECT si.id, si.title, si.link, si.date, si.created_at
+        FROM scraped_items si
+        WHERE si.title LIKE ?
+            OR si.detail_text LIKE ?
+            OR EXISTS (SELECT 1 FROM item_attachments a WHERE a.item_id = si.id AND a.text LIKE ?)
+        ORDER BY si.date DESC
+        LIMIT ?
+    `
+	rows, err := db.QueryContext(ctx, query, pattern, pattern, pattern, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []ScrapedItem
+	for rows.Next() {
+		var item ScrapedItem
+		err := rows.Scan(
+			&item.ID,
+			&item.Title,
+			&item.Link,
+			&item.Date,
+			&item.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return items, db.loadTags(ctx, items)
+}
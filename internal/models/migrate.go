@@ -0,0 +1,173 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strings"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// schemaFile creates the full current schema of a new database.
+const schemaFile = "dump.sql"
+
+// MySQL errors a change gets when it was already applied by hand.
+const (
+	errTableExists     = 1050
+	errDuplicateColumn = 1060
+	errDuplicateKey    = 1061
+)
+
+// Migrate brings the schema up to date with the SQL files in fsys and
+// returns the names of those it applied. A new database gets dump.sql,
+// which already includes every numbered change. Applied changes are
+// recorded in schema_migrations. A database set up before that table
+// existed has every change tried, and changes that are already in place
+// are recorded without failing.
+func (db *DB) Migrate(ctx context.Context, fsys fs.FS) ([]string, error) {
+	names, err := fs.Glob(fsys, "[0-9]*.sql")
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(names)
+
+	tracked, err := db.tableExists(ctx, "schema_migrations")
+	if err != nil {
+		return nil, err
+	}
+	fresh, err := db.tableExists(ctx, "scraped_items")
+	if err != nil {
+		return nil, err
+	}
+	fresh = !fresh
+
+	query := `
+        CREATE TABLE IF NOT EXISTS schema_migrations (
+            name VARCHAR(255) NOT NULL PRIMARY KEY,
+            applied_at DATETIME DEFAULT CURRENT_TIMESTAMP
+        )
+    `
+	if _, err := db.ExecContext(ctx, query); err != nil {
+		return nil, err
+	}
+
+	if fresh {
+		if err := db.execFile(ctx, fsys, schemaFile, false); err != nil {
+			return nil, err
+		}
+		for _, name := range names {
+			if err := db.recordMigration(ctx, name); err != nil {
+				return nil, err
+			}
+		}
+		return []string{schemaFile}, nil
+	}
+
+	applied := make(map[string]bool)
+	rows, err := db.QueryContext(ctx, `SELECT name FROM schema_migrations`)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		applied[name] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var done []string
+	for _, name := range names {
+		if applied[name] {
+			continue
+		}
+		if err := db.execFile(ctx, fsys, name, !tracked); err != nil {
+			return done, err
+		}
+		if err := db.recordMigration(ctx, name); err != nil {
+			return done, err
+		}
+		done = append(done, name)
+	}
+	return done, nil
+}
+
+func (db *DB) tableExists(ctx context.Context, table string) (bool, error) {
+	query := `SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = DATABASE() AND table_name = ?`
+	var n int
+	err := db.QueryRowContext(ctx, query, table).Scan(&n)
+	return n > 0, err
+}
+
+func (db *DB) recordMigration(ctx context.Context, name string) error {
+	_, err := db.ExecContext(ctx, `INSERT IGNORE INTO schema_migrations (name) VALUES (?)`, name)
+	return err
+}
+
+// execFile runs the statements of a file one by one. With tolerant set,
+// statements whose change is already in place are skipped.
+func (db *DB) execFile(ctx context.Context, fsys fs.FS, name string, tolerant bool) error {
+	data, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return err
+	}
+	for _, stmt := range splitStatements(string(data)) {
+		_, err := db.ExecContext(ctx, stmt)
+		var mysqlErr *mysql.MySQLError
+		if tolerant && errors.As(err, &mysqlErr) {
+			switch mysqlErr.Number {
+			case errTableExists, errDuplicateColumn, errDuplicateKey:
+				db.logger.Debug("change already applied", "file", name, "reason", mysqlErr.Message)
+				continue
+			}
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// splitStatements splits a SQL file on the semicolons outside quotes and
+// drops -- comments.
+func splitStatements(sql string) []string {
+	var (
+		statements []string
+		current    strings.Builder
+		quote      rune
+	)
+	flush := func() {
+		if stmt := strings.TrimSpace(current.String()); stmt != "" {
+			statements = append(statements, stmt)
+		}
+		current.Reset()
+	}
+	for _, line := range strings.Split(sql, "\n") {
+		if quote == 0 && strings.HasPrefix(strings.TrimSpace(line), "--") {
+			continue
+		}
+		for _, r := range line {
+			switch {
+			case quote != 0 && r == quote:
+				quote = 0
+			case quote == 0 && (r == '\'' || r == '"' || r == '`'):
+				quote = r
+			case quote == 0 && r == ';':
+				flush()
+				continue
+			}
+			current.WriteRune(r)
+		}
+		current.WriteByte('\n')
+	}
+	flush()
+	return statements
+}
@@ -0,0 +1,95 @@
+package models
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// ChannelWebPush is the channel of browsers that opted in to push
+// notifications. Endpoints can be longer than an address may be, so the
+// address is their hash and the subscription is kept in push_subscriptions.
+const ChannelWebPush = "webpush"
+
+// PushSubscription is what a browser's PushManager returns: the push
+// service URL to post to and the keys to encrypt messages with.
+type PushSubscription struct {
+	ChannelID int
+	Endpoint  string
+	// P256dh is the browser's public key and Auth its secret, both in
+	// unpadded base64url.
+	P256dh string
+	Auth   string
+	Locale string
+}
+
+func endpointAddress(endpoint string) string {
+	sum := sha256.Sum256([]byte(endpoint))
+	return hex.EncodeToString(sum[:])
+}
+
+// AddPushSubscription stores a subscription, updating its keys if the
+// browser subscribed before.
+func (db *DB) AddPushSubscription(ctx context.Context, sub PushSubscription) (int, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	query := `
+        INSERT INTO subscriber_channels (channel, address, locale)
+        VALUES (?, ?, ?)
+        ON DUPLICATE KEY UPDATE id = LAST_INSERT_ID(id), locale = VALUES(locale)
+    `
+	result, err := tx.ExecContext(ctx, query, ChannelWebPush, endpointAddress(sub.Endpoint), sub.Locale)
+	if err != nil {
+		return 0, err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	query = `
+        INSERT INTO push_subscriptions (channel_id, endpoint, p256dh, auth)
+        VALUES (?, ?, ?, ?)
+        ON DUPLICATE KEY UPDATE p256dh = VALUES(p256dh), auth = VALUES(auth)
+    `
+	if _, err := tx.ExecContext(ctx, query, id, sub.Endpoint, sub.P256dh, sub.Auth); err != nil {
+		return 0, err
+	}
+	return int(id), tx.Commit()
+}
+
+// RemovePushSubscription deletes the subscription of endpoint with its
+// delivery log.
+func (db *DB) RemovePushSubscription(ctx context.Context, endpoint string) error {
+	query := `DELETE FROM subscriber_channels WHERE channel = ? AND address = ?`
+	return db.execOne(ctx, query, ChannelWebPush, endpointAddress(endpoint))
+}
+
+func (db *DB) GetPushSubscriptions(ctx context.Context) ([]PushSubscription, error) {
+	query := `
+        SELECT c.id, p.endpoint, p.p256dh, p.auth, c.locale
+        FROM subscriber_channels c
+        JOIN push_subscriptions p ON p.channel_id = c.id
+        WHERE c.channel = ?
+        ORDER BY c.id
+    `
+	rows, err := db.QueryContext(ctx, query, ChannelWebPush)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var subscriptions []PushSubscription
+	for rows.Next() {
+		var s PushSubscription
+		if err := rows.Scan(&s.ChannelID, &s.Endpoint, &s.P256dh, &s.Auth, &s.Locale); err != nil {
+			return nil, err
+		}
+		subscriptions = append(subscriptions, s)
+	}
+	return subscriptions, rows.Err()
+}
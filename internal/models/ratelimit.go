@@ -0,0 +1,51 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/paluras/product-recall-system/internal/ratelimit"
+)
+
+func (db *DB) UpdateRateLimit(ctx context.Context, key string, update func(e *ratelimit.Entry) bool) (bool, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	var (
+		e           ratelimit.Entry
+		bannedUntil sql.NullTime
+	)
+	query := `SELECT count, window_end, banned_until FROM rate_limits WHERE limiter_key = ? FOR UPDATE`
+	err = tx.QueryRowContext(ctx, query, key).Scan(&e.Count, &e.WindowEnd, &bannedUntil)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return false, err
+	}
+	if bannedUntil.Valid {
+		e.BannedUntil = bannedUntil.Time
+	}
+
+	allowed := update(&e)
+
+	bannedUntil = sql.NullTime{Time: e.BannedUntil, Valid: !e.BannedUntil.IsZero()}
+	query = `
+        INSERT INTO rate_limits (limiter_key, count, window_end, banned_until)
+        VALUES (?, ?, ?, ?)
+        ON DUPLICATE KEY UPDATE count = VALUES(count), window_end = VALUES(window_end), banned_until = VALUES(banned_until)
+    `
+	if _, err := tx.ExecContext(ctx, query, key, e.Count, e.WindowEnd, bannedUntil); err != nil {
+		return false, err
+	}
+
+	return allowed, tx.Commit()
+}
+
+func (db *DB) DeleteExpiredRateLimits(ctx context.Context, now time.Time) error {
+	query := `DELETE FROM rate_limits WHERE window_end < ? AND (banned_until IS NULL OR banned_until < ?)`
+	_, err := db.ExecContext(ctx, query, now, now)
+	return err
+}
@@ -0,0 +1,171 @@
+package models
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"time"
+)
+
+const (
+	RevisionEdited    = "edited"
+	RevisionWithdrawn = "withdrawn"
+	RevisionRestored  = "restored"
+)
+
+// Revision is a change ANSVSA made to a recall after we first stored it.
+type Revision struct {
+	ID        int
+	Item      ScrapedItem
+	Change    string
+	OldTitle  string
+	NewTitle  string
+	OldDate   time.Time
+	NewDate   time.Time
+	CreatedAt time.Time
+}
+
+// ContentHash fingerprints what the listing shows of an item, so edits can
+// be noticed without comparing every field.
+func ContentHash(title string, date time.Time) string {
+	sum := sha256.Sum256([]byte(title + "\n" + date.UTC().Format(time.RFC3339)))
+	return hex.EncodeToString(sum[:])
+}
+
+// MarkWithdrawn flags the items dated since or later that were not seen in
+// the scrape that started at seen. since must be a date the listing is
+// complete from, see scraper.Covered: older items have simply scrolled off
+// it, so they are left alone. It returns the number of items flagged.
+func (db *DB) MarkWithdrawn(ctx context.Context, since, seen time.Time) (int, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	query := `
+        INSERT INTO item_revisions (item_id, change_type, old_title, old_date)
+        SELECT id, ?, title, date
+        FROM scraped_items
+        WHERE date >= ? AND withdrawn_at IS NULL AND (last_seen_at IS NULL OR last_seen_at < ?)
+    `
+	if _, err := tx.ExecContext(ctx, query, RevisionWithdrawn, since, seen); err != nil {
+		return 0, err
+	}
+
+	query = `
+        UPDATE scraped_items
+        SET withdrawn_at = ?
+        WHERE date >= ? AND withdrawn_at IS NULL AND (last_seen_at IS NULL OR last_seen_at < ?)
+    `
+	result, err := tx.ExecContext(ctx, query, seen, since, seen)
+	if err != nil {
+		return 0, err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(n), tx.Commit()
+}
+
+// GetRevisions returns the history of an item, oldest first.
+func (db *DB) GetRevisions(ctx context.Context, itemID int) ([]Revision, error) {
+	query := `
+        SELECT id, change_type, COALESCE(old_title, ''), COALESCE(new_title, ''), old_date, new_date, created_at
+        FROM item_revisions
+        WHERE item_id = ?
+        ORDER BY id
+    `
+	rows, err := db.QueryContext(ctx, query, itemID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var revisions []Revision
+	for rows.Next() {
+		r := Revision{Item: ScrapedItem{ID: itemID}}
+		if err := scanRevision(rows, &r); err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, r)
+	}
+	return revisions, rows.Err()
+}
+
+// GetUnnotifiedRevisions returns the material changes subscribers have not
+// been told about, with the current state of each item.
+func (db *DB) GetUnnotifiedRevisions(ctx context.Context) ([]Revision, error) {
+	query := `
+        SELECT r.id, r.change_type, COALESCE(r.old_title, ''), COALESCE(r.new_title, ''), r.old_date, r.new_date, r.created_at,
+            si.id, si.title, si.link, si.date, si.created_at
+        FROM item_revisions r
+        JOIN scraped_items si ON si.id = r.item_id
+        WHERE r.notified = FALSE
+        ORDER BY r.id
+    `
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var revisions []Revision
+	for rows.Next() {
+		var r Revision
+		err := scanRevision(rows, &r, &r.Item.ID, &r.Item.Title, &r.Item.Link, &r.Item.Date, &r.Item.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	items := make([]ScrapedItem, len(revisions))
+	for i, r := range revisions {
+		items[i] = r.Item
+	}
+	if err := db.loadTags(ctx, items); err != nil {
+		return nil, err
+	}
+	for i := range revisions {
+		revisions[i].Item.Tags = items[i].Tags
+	}
+	return revisions, nil
+}
+
+// SkipUnannouncedRevisions marks the changes to items subscribers have not
+// been alerted about yet as notified.
+func (db *DB) SkipUnannouncedRevisions(ctx context.Context) error {
+	query := `
+        UPDATE item_revisions r
+        JOIN scraped_items si ON si.id = r.item_id
+        SET r.notified = TRUE
+        WHERE r.notified = FALSE AND si.notified = FALSE
+    `
+	_, err := db.ExecContext(ctx, query)
+	return err
+}
+
+func (db *DB) MarkRevisionNotified(ctx context.Context, id int) error {
+	_, err := db.ExecContext(ctx, `UPDATE item_revisions SET notified = TRUE WHERE id = ?`, id)
+	return err
+}
+
+// scanRevision reads the revision columns in the order the queries above
+// select them, followed by extra.
+func scanRevision(rows *sql.Rows, r *Revision, extra ...any) error {
+	var oldDate, newDate sql.NullTime
+	dest := append([]any{&r.ID, &r.Change, &r.OldTitle, &r.NewTitle, &oldDate, &newDate, &r.CreatedAt}, extra...)
+	if err := rows.Scan(dest...); err != nil {
+		return err
+	}
+	r.OldDate = oldDate.Time
+	r.NewDate = newDate.Time
+	return nil
+}
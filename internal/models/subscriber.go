@@ -1,10 +1,10 @@
 package models
 
 import (
-	"crypto/rand"
-	"encoding/hex"
+	"context"
+	"database/sql"
+	"errors"
 	"fmt"
-	"log"
 	"time"
 )
 
@@ -13,27 +13,45 @@ type Subscriber struct {
 	Email            string
 	CreatedAt        time.Time
 	UnsubscribeToken string
+	Confirmed        bool
+	PausedUntil      time.Time
+	Locale           string
+	PendingEmail     string
+	Allergens        []string
+	AllergenOnly     bool
 }
 
-// refactor into a helper file
-func generateUnsubscribeToken() string {
-	b := make([]byte, 32)
-	if _, err := rand.Read(b); err != nil {
-		return ""
-	}
-	return hex.EncodeToString(b)
+func (s *Subscriber) Paused() bool {
+	return time.Now().Before(s.PausedUntil)
 }
 
-func (db *DB) CreateUnsubscribeToken(email string) (string, error) {
+func (db *DB) CreateUnsubscribeToken(ctx context.Context, email string) (string, error) {
 	token := generateUnsubscribeToken()
 	query := `UPDATE subscribers SET unsubscribe_token = ? WHERE email = ?`
-	_, err := db.Exec(query, token, email)
+	_, err := db.ExecContext(ctx, query, token, email)
+	return token, err
+}
+
+// UnsubscribeToken returns the unsubscribe token of email, creating one only
+// if it has none yet.
+func (db *DB) UnsubscribeToken(ctx context.Context, email string) (string, error) {
+	query := `UPDATE subscribers SET unsubscribe_token = ? WHERE email = ? AND unsubscribe_token IS NULL`
+	if _, err := db.ExecContext(ctx, query, generateUnsubscribeToken(), email); err != nil {
+		return "", err
+	}
+
+	var token string
+	query = `SELECT unsubscribe_token FROM subscribers WHERE email = ?`
+	err := db.QueryRowContext(ctx, query, email).Scan(&token)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", ErrNoRecord
+	}
 	return token, err
 }
 
-func (db *DB) UnsubscribeWithToken(token string) error {
+func (db *DB) UnsubscribeWithToken(ctx context.Context, token string) error {
 	query := `DELETE FROM subscribers WHERE unsubscribe_token = ?`
-	result, err := db.Exec(query, token)
+	result, err := db.ExecContext(ctx, query, token)
 	if err != nil {
 		return err
 	}
@@ -48,61 +66,246 @@ func (db *DB) UnsubscribeWithToken(token string) error {
 	return nil
 }
 
-func (db *DB) AddSubscriber(email string) (string, error) {
+func (db *DB) AddSubscriber(ctx context.Context, email, locale string, consent Consent) (string, error) {
 	token := generateUnsubscribeToken()
 	if token == "" {
 		return "", fmt.Errorf("failed to generate confirmation token")
 	}
-	query := `INSERT INTO subscribers (email, confirmation_token, confirmed) VALUES (?, ?, FALSE)`
-	_, err := db.Exec(query, email, token)
-	return token, err
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback()
+
+	query := `INSERT INTO subscribers (email, locale, confirmation_token, confirmation_sent_at, confirmed) VALUES (?, ?, ?, UTC_TIMESTAMP(), FALSE)`
+	result, err := tx.ExecContext(ctx, query, email, locale, token)
+	if err != nil {
+		return "", err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return "", err
+	}
+
+	consent.Action = "subscribe"
+	if err := insertConsent(ctx, tx, id, consent); err != nil {
+		return "", err
+	}
+	return token, tx.Commit()
 }
 
-func (db *DB) ConfirmSubscriber(token string) error {
-	query := `UPDATE subscribers SET confirmed = TRUE, confirmation_token = NULL WHERE confirmation_token = ? AND confirmed = FALSE`
-	result, err := db.Exec(query, token)
+func (db *DB) ConfirmSubscriber(ctx context.Context, token string, consent Consent) error {
+	tx, err := db.BeginTx(ctx, nil)
 	if err != nil {
 		return err
 	}
-	rows, err := result.RowsAffected()
+	defer tx.Rollback()
+
+	id, err := subscriberIDByConfirmationToken(ctx, tx, token)
+	if errors.Is(err, ErrNoRecord) {
+		return fmt.Errorf("invalid or already used confirmation token")
+	}
 	if err != nil {
 		return err
 	}
-	if rows == 0 {
-		return fmt.Errorf("invalid or already used confirmation token")
+
+	query := `UPDATE subscribers SET confirmed = TRUE, confirmation_token = NULL WHERE id = ?`
+	if _, err := tx.ExecContext(ctx, query, id); err != nil {
+		return err
 	}
-	return nil
+
+	consent.Action = "confirm"
+	if err := insertConsent(ctx, tx, id, consent); err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
-func (db *DB) GetSubscribersMail() ([]string, error) {
-	query := `SELECT email FROM subscribers WHERE confirmed = TRUE`
-	rows, err := db.Query(query)
+// GetActiveSubscribers returns confirmed subscribers whose alerts are not
+// paused.
+func (db *DB) GetActiveSubscribers(ctx context.Context) ([]Subscriber, error) {
+	query := `
+        SELECT id, email, locale, allergen_only FROM subscribers
+        WHERE confirmed = TRUE AND (paused_until IS NULL OR paused_until < UTC_TIMESTAMP())
+    `
+	rows, err := db.QueryContext(ctx, query)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var subscribers []string
+	var subscribers []Subscriber
+
+	for rows.Next() {
+		var s Subscriber
+		err := rows.Scan(&s.ID, &s.Email, &s.Locale, &s.AllergenOnly)
+		if err != nil {
+			return nil, err
+		}
+		s.Confirmed = true
+		subscribers = append(subscribers, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return subscribers, db.loadAllergens(ctx, subscribers)
+}
+
+func (db *DB) GetSubscriber(ctx context.Context, id string) (*Subscriber, error) {
+	query := `SELECT ` + subscriberColumns + ` FROM subscribers WHERE id = ?`
+	return db.getSubscriber(ctx, query, id)
+}
+
+func (db *DB) GetSubscriberByEmail(ctx context.Context, email string) (*Subscriber, error) {
+	query := `SELECT ` + subscriberColumns + ` FROM subscribers WHERE email = ?`
+	return db.getSubscriber(ctx, query, email)
+}
+
+const subscriberColumns = `id, email, created_at, confirmed, paused_until, locale, pending_email, allergen_only`
+
+func (db *DB) getSubscriber(ctx context.Context, query string, args ...any) (*Subscriber, error) {
+	var (
+		s            Subscriber
+		pausedUntil  sql.NullTime
+		pendingEmail sql.NullString
+	)
+	err := db.QueryRowContext(ctx, query, args...).Scan(&s.ID, &s.Email, &s.CreatedAt, &s.Confirmed, &pausedUntil, &s.Locale, &pendingEmail, &s.AllergenOnly)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNoRecord
+	}
+	if err != nil {
+		return nil, err
+	}
+	s.PausedUntil = pausedUntil.Time
+	s.PendingEmail = pendingEmail.String
+
+	subscribers := []Subscriber{s}
+	if err := db.loadAllergens(ctx, subscribers); err != nil {
+		return nil, err
+	}
+	return &subscribers[0], nil
+}
+
+// ListSubscribers returns every subscriber, oldest first, or with
+// pendingOnly only those who have not confirmed their address.
+func (db *DB) ListSubscribers(ctx context.Context, pendingOnly bool) ([]Subscriber, error) {
+	query := `SELECT ` + subscriberColumns + ` FROM subscribers`
+	if pendingOnly {
+		query += ` WHERE confirmed = FALSE`
+	}
+	query += ` ORDER BY created_at, id`
+
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
 
+	var subscribers []Subscriber
 	for rows.Next() {
-		var s string
-		err := rows.Scan(&s)
+		var (
+			s            Subscriber
+			pausedUntil  sql.NullTime
+			pendingEmail sql.NullString
+		)
+		err := rows.Scan(&s.ID, &s.Email, &s.CreatedAt, &s.Confirmed, &pausedUntil, &s.Locale, &pendingEmail, &s.AllergenOnly)
 		if err != nil {
 			return nil, err
 		}
+		s.PausedUntil = pausedUntil.Time
+		s.PendingEmail = pendingEmail.String
 		subscribers = append(subscribers, s)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return subscribers, db.loadAllergens(ctx, subscribers)
+}
 
-	return subscribers, nil
+// ConfirmationToken returns the outstanding confirmation token of a pending
+// address.
+func (db *DB) ConfirmationToken(ctx context.Context, email string) (string, error) {
+	var token sql.NullString
+	query := `SELECT confirmation_token FROM subscribers WHERE email = ? AND confirmed = FALSE`
+	err := db.QueryRowContext(ctx, query, email).Scan(&token)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && !token.Valid) {
+		return "", ErrNoRecord
+	}
+	return token.String, err
 }
 
-func (db *DB) EmailExists(email string) (bool, error) {
-	var exists bool
-	stmt := `SELECT EXISTS(SELECT 1 FROM subscribers WHERE email = ?)`
-	err := db.QueryRow(stmt, email).Scan(&exists)
+// RenewConfirmationToken issues a fresh confirmation token for a pending
+// subscriber. It returns ErrCooldown if the previous one was sent less than
+// cooldown ago.
+func (db *DB) RenewConfirmationToken(ctx context.Context, email string, cooldown time.Duration) (string, error) {
+	token := generateUnsubscribeToken()
+	if token == "" {
+		return "", fmt.Errorf("failed to generate confirmation token")
+	}
+	query := `
+        UPDATE subscribers SET confirmation_token = ?, confirmation_sent_at = UTC_TIMESTAMP()
+        WHERE email = ? AND confirmed = FALSE
+        AND (confirmation_sent_at IS NULL OR confirmation_sent_at < ?)
+    `
+	result, err := db.ExecContext(ctx, query, token, email, time.Now().UTC().Add(-cooldown))
+	if err != nil {
+		return "", err
+	}
+	rows, err := result.RowsAffected()
 	if err != nil {
-		log.Printf("EmailExists error: %v", err)
-		return false, err
+		return "", err
 	}
-	return exists, nil
+	if rows == 0 {
+		return "", ErrCooldown
+	}
+	return token, nil
+}
+
+// PauseSubscriber stops alerts until the given time. A zero time resumes them.
+func (db *DB) PauseSubscriber(ctx context.Context, id string, until time.Time) error {
+	pausedUntil := sql.NullTime{Time: until.UTC(), Valid: !until.IsZero()}
+	query := `UPDATE subscribers SET paused_until = ? WHERE id = ?`
+	_, err := db.ExecContext(ctx, query, pausedUntil, id)
+	return err
+}
+
+func (db *DB) SetSubscriberLocale(ctx context.Context, id, locale string) error {
+	query := `UPDATE subscribers SET locale = ? WHERE id = ?`
+	_, err := db.ExecContext(ctx, query, locale, id)
+	return err
+}
+
+// RequestEmailChange stores newEmail as pending and returns the token that
+// has to be confirmed from the new address before it replaces the old one.
+func (db *DB) RequestEmailChange(ctx context.Context, id, newEmail string) (string, error) {
+	token := generateUnsubscribeToken()
+	if token == "" {
+		return "", fmt.Errorf("failed to generate email change token")
+	}
+	query := `UPDATE subscribers SET pending_email = ?, email_change_token = ? WHERE id = ?`
+	_, err := db.ExecContext(ctx, query, newEmail, token, id)
+	return token, err
+}
+
+func (db *DB) ConfirmEmailChange(ctx context.Context, token string) error {
+	query := `
+        UPDATE subscribers
+        SET email = pending_email, pending_email = NULL, email_change_token = NULL, unsubscribe_token = NULL
+        WHERE email_change_token = ? AND pending_email IS NOT NULL
+    `
+	result, err := db.ExecContext(ctx, query, token)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return fmt.Errorf("invalid or already used email change token")
+	}
+	return nil
 }
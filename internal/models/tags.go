@@ -0,0 +1,152 @@
+package models
+
+import (
+	"context"
+	"strings"
+
+	"github.com/paluras/product-recall-system/internal/classify"
+)
+
+// Tag is a category or hazard assigned to an item by the classifier.
+type Tag struct {
+	Kind string `json:"kind"`
+	Slug string `json:"slug"`
+}
+
+func FromClassifierTags(tags []classify.Tag) []Tag {
+	result := make([]Tag, len(tags))
+	for i, t := range tags {
+		result[i] = Tag(t)
+	}
+	return result
+}
+
+// SetItemTags replaces the tags of an item.
+func (db *DB) SetItemTags(ctx context.Context, itemID int, tags []Tag) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM item_tags WHERE item_id = ?`, itemID); err != nil {
+		return err
+	}
+
+	for _, tag := range tags {
+		query := `INSERT INTO tags (kind, slug) VALUES (?, ?) ON DUPLICATE KEY UPDATE id = LAST_INSERT_ID(id)`
+		result, err := tx.ExecContext(ctx, query, tag.Kind, tag.Slug)
+		if err != nil {
+			return err
+		}
+		tagID, err := result.LastInsertId()
+		if err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, `INSERT INTO item_tags (item_id, tag_id) VALUES (?, ?)`, itemID, tagID); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// loadTags fills in the Tags of every item with a single query.
+func (db *DB) loadTags(ctx context.Context, items []ScrapedItem) error {
+	if len(items) == 0 {
+		return nil
+	}
+
+	index := make(map[int]int, len(items))
+	args := make([]any, len(items))
+	for i, item := range items {
+		index[item.ID] = i
+		args[i] = item.ID
+	}
+
+	query := `
+        SELECT it.item_id, t.kind, t.slug
+        FROM item_tags it
+        JOIN tags t ON t.id = it.tag_id
+        WHERE it.item_id IN (?` + strings.Repeat(", ?", len(items)-1) + `)
+        ORDER BY t.kind, t.slug
+    `
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			itemID int
+			tag    Tag
+		)
+		if err := rows.Scan(&itemID, &tag.Kind, &tag.Slug); err != nil {
+			return err
+		}
+		i := index[itemID]
+		items[i].Tags = append(items[i].Tags, tag)
+	}
+	return rows.Err()
+}
+
+// GetItemsByTag returns the newest items carrying the tag with slug.
+func (db *DB) GetItemsByTag(ctx context.Context, slug string, limit int) ([]ScrapedItem, error) {
+	query := `
+        SELECT si.id, si.title, si.link, si.date, si.created_at
+        FROM scraped_items si
+        JOIN item_tags it ON it.item_id = si.id
+        JOIN tags t ON t.id = it.tag_id
+        WHERE t.slug = ?
+        ORDER BY si.date DESC
+        LIMIT ?
+    `
+	rows, err := db.QueryContext(ctx, query, slug, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []ScrapedItem
+	for rows.Next() {
+		var item ScrapedItem
+		err := rows.Scan(
+			&item.ID,
+			&item.Title,
+			&item.Link,
+			&item.Date,
+			&item.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return items, db.loadTags(ctx, items)
+}
+
+// GetItemsForClassification returns every item with the text the classifier
+// looks at.
+func (db *DB) GetItemsForClassification(ctx context.Context) ([]ScrapedItem, error) {
+	query := `SELECT id, title, COALESCE(detail_text, '') FROM scraped_items ORDER BY id`
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []ScrapedItem
+	for rows.Next() {
+		var item ScrapedItem
+		if err := rows.Scan(&item.ID, &item.Title, &item.DetailText); err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
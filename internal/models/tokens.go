@@ -0,0 +1,61 @@
+package models
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrInvalidToken = errors.New("models: invalid or expired token")
+
+func generateUnsubscribeToken() string {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(b)
+}
+
+// SignToken returns a stateless token that identifies a subscriber for one
+// purpose until expires. Nothing is stored, so the key must be shared by every
+// process that issues or checks tokens.
+func SignToken(key []byte, purpose, subscriberID string, expires time.Time) string {
+	payload := purpose + ":" + subscriberID + ":" + strconv.FormatInt(expires.Unix(), 10)
+	encoded := base64.RawURLEncoding.EncodeToString([]byte(payload))
+	return encoded + "." + signature(key, encoded)
+}
+
+// VerifyToken checks a token produced by SignToken and returns the subscriber
+// ID it was issued for.
+func VerifyToken(key []byte, purpose, token string) (string, error) {
+	encoded, sig, ok := strings.Cut(token, ".")
+	if !ok || !hmac.Equal([]byte(sig), []byte(signature(key, encoded))) {
+		return "", ErrInvalidToken
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", ErrInvalidToken
+	}
+	parts := strings.Split(string(raw), ":")
+	if len(parts) != 3 || parts[0] != purpose {
+		return "", ErrInvalidToken
+	}
+	expires, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil || time.Now().Unix() > expires {
+		return "", ErrInvalidToken
+	}
+	return parts[1], nil
+}
+
+func signature(key []byte, s string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(s))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,254 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// upsertBatch bounds the rows per statement, well below the limit on
+// placeholders.
+const upsertBatch = 500
+
+// errDeadlock is the MySQL error two concurrent scrapes get when both try
+// to insert links neither had stored.
+const errDeadlock = 1213
+
+// UpsertResult sorts the items of a scrape by what UpsertItems did with them.
+type UpsertResult struct {
+	// New items were inserted and carry their new IDs.
+	New []ScrapedItem
+	// Updated items were stored already and changed title or date, or were
+	// withdrawn and are listed again.
+	Updated []ScrapedItem
+	// Unchanged items were stored already and only marked as seen.
+	Unchanged []ScrapedItem
+}
+
+// StoredLinks returns which of links are already stored, so details are
+// only fetched for new items.
+func (db *DB) StoredLinks(ctx context.Context, links []string) (map[string]bool, error) {
+	stored := make(map[string]bool)
+	for start := 0; start < len(links); start += upsertBatch {
+		batch := links[start:min(start+upsertBatch, len(links))]
+		query := `SELECT link FROM scraped_items WHERE link IN (?` + strings.Repeat(", ?", len(batch)-1) + `)`
+		rows, err := db.QueryContext(ctx, query, anySlice(batch)...)
+		if err != nil {
+			return nil, err
+		}
+		for rows.Next() {
+			var link string
+			if err := rows.Scan(&link); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			stored[link] = true
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+	}
+	return stored, nil
+}
+
+// UpsertItems stores the items of one scrape, seen at seen, in a single
+// transaction. The stored rows are locked before anything is written, so
+// concurrent runs wait for each other instead of racing on the link key,
+// and each item is reported as exactly one of new, updated or unchanged.
+// A changed title or date is kept as an "edited" revision and a withdrawn
+// item that reappears is restored. Only title changes are worth telling
+// subscribers about. DetailText is only written for new items.
+func (db *DB) UpsertItems(ctx context.Context, items []ScrapedItem, seen time.Time) (*UpsertResult, error) {
+	for attempt := 0; ; attempt++ {
+		result, err := db.upsertItems(ctx, items, seen)
+		var mysqlErr *mysql.MySQLError
+		if attempt < 2 && errors.As(err, &mysqlErr) && mysqlErr.Number == errDeadlock {
+			db.logger.Warn("storing items deadlocked, retrying", "attempt", attempt+1)
+			continue
+		}
+		return result, err
+	}
+}
+
+func (db *DB) upsertItems(ctx context.Context, items []ScrapedItem, seen time.Time) (*UpsertResult, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	items = uniqueLinks(items)
+	result := &UpsertResult{}
+	for start := 0; start < len(items); start += upsertBatch {
+		batch := items[start:min(start+upsertBatch, len(items))]
+		if err := upsertBatchTx(ctx, tx, batch, seen, result); err != nil {
+			return nil, err
+		}
+	}
+
+	return result, tx.Commit()
+}
+
+func upsertBatchTx(ctx context.Context, tx *sql.Tx, items []ScrapedItem, seen time.Time, result *UpsertResult) error {
+	links := make([]string, len(items))
+	for i, item := range items {
+		links[i] = item.Link
+	}
+	stored, err := lockItems(ctx, tx, links)
+	if err != nil {
+		return err
+	}
+
+	var (
+		placeholders []string
+		args         []any
+		inserted     []int
+	)
+	for i := range items {
+		item := &items[i]
+		item.LastSeenAt = seen
+
+		if old, ok := stored[item.Link]; ok {
+			item.ID = old.ID
+			item.CreatedAt = old.CreatedAt
+			if item.Date.IsZero() {
+				item.Date = old.Date
+			}
+			item.ContentHash = ContentHash(item.Title, item.Date)
+
+			changed, err := recordChanges(ctx, tx, old, *item)
+			if err != nil {
+				return err
+			}
+			if changed {
+				result.Updated = append(result.Updated, *item)
+			} else {
+				result.Unchanged = append(result.Unchanged, *item)
+			}
+		} else {
+			item.ContentHash = ContentHash(item.Title, item.Date)
+			inserted = append(inserted, i)
+		}
+
+		placeholders = append(placeholders, "(?, ?, ?, ?, ?, ?)")
+		args = append(args, item.Title, item.Link, item.Date, item.DetailText, item.ContentHash, item.LastSeenAt)
+	}
+
+	query := `
+        INSERT INTO scraped_items (title, link, date, detail_text, content_hash, last_seen_at)
+        VALUES ` + strings.Join(placeholders, ", ") + `
+        ON DUPLICATE KEY UPDATE title = VALUES(title), date = VALUES(date), content_hash = VALUES(content_hash),
+            last_seen_at = VALUES(last_seen_at), withdrawn_at = NULL
+    `
+	if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+		return err
+	}
+	if len(inserted) == 0 {
+		return nil
+	}
+
+	newLinks := make([]string, len(inserted))
+	for j, i := range inserted {
+		newLinks[j] = items[i].Link
+	}
+	created, err := lockItems(ctx, tx, newLinks)
+	if err != nil {
+		return err
+	}
+	for _, i := range inserted {
+		item := items[i]
+		item.ID = created[item.Link].ID
+		item.CreatedAt = created[item.Link].CreatedAt
+		result.New = append(result.New, item)
+	}
+	return nil
+}
+
+// lockItems loads the stored items with the given links, locking their rows
+// and, for links not stored yet, the gaps they would go into.
+func lockItems(ctx context.Context, tx *sql.Tx, links []string) (map[string]*ScrapedItem, error) {
+	query := `
+        SELECT id, title, link, date, created_at, COALESCE(content_hash, ''), withdrawn_at
+        FROM scraped_items
+        WHERE link IN (?` + strings.Repeat(", ?", len(links)-1) + `)
+        FOR UPDATE
+    `
+	rows, err := tx.QueryContext(ctx, query, anySlice(links)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := make(map[string]*ScrapedItem)
+	for rows.Next() {
+		var (
+			item        ScrapedItem
+			date        sql.NullTime
+			withdrawnAt sql.NullTime
+		)
+		err := rows.Scan(&item.ID, &item.Title, &item.Link, &date, &item.CreatedAt, &item.ContentHash, &withdrawnAt)
+		if err != nil {
+			return nil, err
+		}
+		item.Date = date.Time
+		item.WithdrawnAt = withdrawnAt.Time
+		items[item.Link] = &item
+	}
+	return items, rows.Err()
+}
+
+// recordChanges keeps a revision for each way current differs from what was
+// stored and reports whether there was any.
+func recordChanges(ctx context.Context, tx *sql.Tx, stored *ScrapedItem, current ScrapedItem) (bool, error) {
+	changed := false
+
+	// items stored before hashes existed have nothing to compare with yet
+	if stored.ContentHash != "" && stored.ContentHash != current.ContentHash {
+		query := `
+            INSERT INTO item_revisions (item_id, change_type, old_title, new_title, old_date, new_date, notified)
+            VALUES (?, ?, ?, ?, ?, ?, ?)
+        `
+		material := stored.Title != current.Title
+		_, err := tx.ExecContext(ctx, query, stored.ID, RevisionEdited, stored.Title, current.Title, stored.Date, current.Date, !material)
+		if err != nil {
+			return false, err
+		}
+		changed = true
+	}
+
+	if stored.Withdrawn() {
+		query := `INSERT INTO item_revisions (item_id, change_type, notified) VALUES (?, ?, TRUE)`
+		if _, err := tx.ExecContext(ctx, query, stored.ID, RevisionRestored); err != nil {
+			return false, err
+		}
+		changed = true
+	}
+
+	return changed, nil
+}
+
+// uniqueLinks drops repeated links, keeping the first item for each.
+func uniqueLinks(items []ScrapedItem) []ScrapedItem {
+	seen := make(map[string]bool, len(items))
+	unique := make([]ScrapedItem, 0, len(items))
+	for _, item := range items {
+		if !seen[item.Link] {
+			seen[item.Link] = true
+			unique = append(unique, item)
+		}
+	}
+	return unique
+}
+
+func anySlice(s []string) []any {
+	args := make([]any, len(s))
+	for i, v := range s {
+		args[i] = v
+	}
+	return args
+}
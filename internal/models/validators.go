@@ -0,0 +1,29 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+)
+
+// GetValidators returns the ETag and Last-Modified last seen for url. It
+// implements scraper.ValidatorStore.
+func (db *DB) GetValidators(ctx context.Context, url string) (string, string, error) {
+	var etag, lastModified string
+	query := `SELECT etag, last_modified FROM http_validators WHERE url = ?`
+	err := db.QueryRowContext(ctx, query, url).Scan(&etag, &lastModified)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", "", nil
+	}
+	return etag, lastModified, err
+}
+
+func (db *DB) SaveValidators(ctx context.Context, url, etag, lastModified string) error {
+	query := `
+        INSERT INTO http_validators (url, etag, last_modified)
+        VALUES (?, ?, ?)
+        ON DUPLICATE KEY UPDATE etag = VALUES(etag), last_modified = VALUES(last_modified)
+    `
+	_, err := db.ExecContext(ctx, query, url, etag, lastModified)
+	return err
+}
@@ -0,0 +1,158 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// ChannelWebhook is the channel of webhooks, whose attempts are kept in
+// webhook_deliveries.
+const ChannelWebhook = "webhook"
+
+// Webhook is an endpoint of a downstream system, such as a shop or a
+// canteen, that is sent every new recall as signed JSON.
+type Webhook struct {
+	ID     int
+	URL    string
+	Secret string
+	// Failures counts the recalls in a row the endpoint did not accept.
+	Failures   int
+	DisabledAt time.Time
+	CreatedAt  time.Time
+}
+
+func (w Webhook) Disabled() bool {
+	return !w.DisabledAt.IsZero()
+}
+
+// AddWebhook registers url and returns its ID and the secret its payloads
+// are signed with.
+func (db *DB) AddWebhook(ctx context.Context, url string) (int, string, error) {
+	secret := generateUnsubscribeToken()
+	if secret == "" {
+		return 0, "", errors.New("failed to generate webhook secret")
+	}
+	result, err := db.ExecContext(ctx, `INSERT INTO webhooks (url, secret) VALUES (?, ?)`, url, secret)
+	if err != nil {
+		return 0, "", err
+	}
+	id, err := result.LastInsertId()
+	return int(id), secret, err
+}
+
+// GetWebhooks returns every webhook, or only those that are not disabled.
+func (db *DB) GetWebhooks(ctx context.Context, activeOnly bool) ([]Webhook, error) {
+	query := `SELECT id, url, secret, failures, disabled_at, created_at FROM webhooks`
+	if activeOnly {
+		query += ` WHERE disabled_at IS NULL`
+	}
+	query += ` ORDER BY id`
+
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var webhooks []Webhook
+	for rows.Next() {
+		var (
+			w          Webhook
+			disabledAt sql.NullTime
+		)
+		if err := rows.Scan(&w.ID, &w.URL, &w.Secret, &w.Failures, &disabledAt, &w.CreatedAt); err != nil {
+			return nil, err
+		}
+		w.DisabledAt = disabledAt.Time
+		webhooks = append(webhooks, w)
+	}
+	return webhooks, rows.Err()
+}
+
+func (db *DB) RemoveWebhook(ctx context.Context, id int) error {
+	return db.execOne(ctx, `DELETE FROM webhooks WHERE id = ?`, id)
+}
+
+// EnableWebhook turns a disabled webhook back on with a clean failure count.
+func (db *DB) EnableWebhook(ctx context.Context, id int) error {
+	return db.execOne(ctx, `UPDATE webhooks SET failures = 0, disabled_at = NULL WHERE id = ?`, id)
+}
+
+// execOne runs a statement that must affect exactly one row, returning
+// ErrNoRecord when it affects none.
+func (db *DB) execOne(ctx context.Context, query string, args ...any) error {
+	result, err := db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrNoRecord
+	}
+	return nil
+}
+
+// WebhookAttempt is one request made to a webhook for an item.
+type WebhookAttempt struct {
+	WebhookID  int
+	ItemID     int
+	Attempt    int
+	StatusCode int
+	Err        error
+}
+
+// RecordWebhookAttempt adds an attempt to the delivery log.
+func (db *DB) RecordWebhookAttempt(ctx context.Context, a WebhookAttempt) error {
+	status, errText := "sent", ""
+	if a.Err != nil {
+		status, errText = "failed", a.Err.Error()
+		if len(errText) > 500 {
+			errText = errText[:500]
+		}
+	}
+	statusCode := sql.NullInt64{Int64: int64(a.StatusCode), Valid: a.StatusCode != 0}
+
+	query := `
+        INSERT INTO webhook_deliveries (webhook_id, item_id, attempt, status, status_code, error)
+        VALUES (?, ?, ?, ?, ?, ?)
+    `
+	_, err := db.ExecContext(ctx, query, a.WebhookID, a.ItemID, a.Attempt, status, statusCode, errText)
+	return err
+}
+
+// WebhookDelivered reports whether the webhook already accepted the item,
+// so a notify run that is repeated does not send it twice.
+func (db *DB) WebhookDelivered(ctx context.Context, webhookID, itemID int) (bool, error) {
+	query := `SELECT COUNT(*) FROM webhook_deliveries WHERE webhook_id = ? AND item_id = ? AND status = 'sent'` + sinceRenotified
+	var n int
+	err := db.QueryRowContext(ctx, query, webhookID, itemID, itemID).Scan(&n)
+	return n > 0, err
+}
+
+// WebhookSucceeded clears the failure count of a webhook.
+func (db *DB) WebhookSucceeded(ctx context.Context, id int) error {
+	_, err := db.ExecContext(ctx, `UPDATE webhooks SET failures = 0 WHERE id = ?`, id)
+	return err
+}
+
+// WebhookFailed counts a recall the webhook did not accept and returns how
+// many it has failed in a row.
+func (db *DB) WebhookFailed(ctx context.Context, id int) (int, error) {
+	if _, err := db.ExecContext(ctx, `UPDATE webhooks SET failures = failures + 1 WHERE id = ?`, id); err != nil {
+		return 0, err
+	}
+	var failures int
+	err := db.QueryRowContext(ctx, `SELECT failures FROM webhooks WHERE id = ?`, id).Scan(&failures)
+	return failures, err
+}
+
+// DisableWebhook stops sending to a webhook until it is enabled again.
+func (db *DB) DisableWebhook(ctx context.Context, id int) error {
+	_, err := db.ExecContext(ctx, `UPDATE webhooks SET disabled_at = UTC_TIMESTAMP() WHERE id = ? AND disabled_at IS NULL`, id)
+	return err
+}
@@ -0,0 +1,265 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+
+	"github.com/paluras/product-recall-system/internal/logging"
+	"github.com/paluras/product-recall-system/internal/models"
+)
+
+// Channel is one way of telling people about new recalls. A Dispatcher does
+// what all channels share: it leaves out the recalls a recipient does not
+// follow or already received, sends what the channel renders and has the
+// outcome recorded.
+type Channel interface {
+	// Name identifies the channel in logs and previews.
+	Name() string
+	// Recipients returns everyone the channel reaches. A preview must not
+	// write to the database.
+	Recipients(ctx context.Context, preview bool) ([]Recipient, error)
+	// Delivered reports whether the item already reached r, so that a run
+	// repeated after a failure does not send it twice.
+	Delivered(ctx context.Context, r Recipient, itemID int) (bool, error)
+	// Render builds the messages that tell r about items. It may return
+	// none when the items do not concern r after all.
+	Render(r Recipient, items []models.ScrapedItem) ([]*Message, error)
+	// Send delivers one rendered message.
+	Send(ctx context.Context, r Recipient, m *Message) error
+	// Record logs the outcome of sending m. It reports whether r is gone
+	// for good, after which r is sent nothing more.
+	Record(ctx context.Context, r Recipient, m *Message, sendErr error) (bool, error)
+}
+
+// Recipient is someone a channel reaches: a subscriber, a Telegram chat, a
+// browser or a webhook.
+type Recipient struct {
+	// ID identifies the recipient within its channel in logs.
+	ID     string
+	Locale string
+	// Tags are the slugs of the tags the recipient follows. Without any
+	// they hear about every recall.
+	Tags []string
+	// Data is what the channel keeps about the recipient.
+	Data any
+}
+
+// Wants reports whether the item has one of the tags the recipient follows,
+// or the recipient follows none.
+func (r Recipient) Wants(item models.ScrapedItem) bool {
+	if len(r.Tags) == 0 {
+		return true
+	}
+	for _, tag := range item.Tags {
+		if slices.Contains(r.Tags, tag.Slug) {
+			return true
+		}
+	}
+	return false
+}
+
+// Dispatcher sends new recalls out on every channel.
+type Dispatcher struct {
+	channels []Channel
+	logger   *slog.Logger
+}
+
+func NewDispatcher(logger *slog.Logger, channels ...Channel) *Dispatcher {
+	return &Dispatcher{channels: channels, logger: logger}
+}
+
+// Preview is what a channel would send.
+type Preview struct {
+	Channel  string
+	Messages []*Message
+}
+
+// Dispatch tells every recipient of every channel about the items they
+// follow and have not received yet. Messages that fail are logged and
+// recorded rather than returned, so one recipient cannot hold up the
+// others. An error means a channel could not finish or every message it
+// sent failed; the items should then stay pending, and a repeated run only
+// sends what is missing.
+func (d *Dispatcher) Dispatch(ctx context.Context, items []models.ScrapedItem) error {
+	var errs []error
+	for _, ch := range d.channels {
+		if err := d.dispatch(ctx, ch, items); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			errs = append(errs, fmt.Errorf("%s: %w", ch.Name(), err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Outgoing is a rendered message and who it is for.
+type Outgoing struct {
+	Recipient Recipient
+	Message   *Message
+}
+
+// BatchSender is implemented by channels that send many messages faster
+// together than one at a time, such as email through a provider with a
+// batch endpoint and a request quota. The Dispatcher then hands them all
+// messages of a run at once instead of calling Send for each.
+type BatchSender interface {
+	// SendAll sends every message and calls done with the outcome of each
+	// as soon as it is known. done is never called concurrently.
+	SendAll(ctx context.Context, out []Outgoing, done func(Outgoing, error))
+}
+
+func (d *Dispatcher) dispatch(ctx context.Context, ch Channel, items []models.ScrapedItem) error {
+	recipients, err := ch.Recipients(ctx, false)
+	if err != nil {
+		return err
+	}
+
+	var out []Outgoing
+	for _, r := range recipients {
+		messages, err := d.render(ctx, ch, r, items)
+		if err != nil {
+			return err
+		}
+		for _, m := range messages {
+			out = append(out, Outgoing{Recipient: r, Message: m})
+		}
+	}
+
+	t := &tally{ch: ch, logger: d.logger.With("channel", ch.Name()), total: len(out)}
+	if bs, ok := ch.(BatchSender); ok {
+		sendCtx, cancel := context.WithCancelCause(ctx)
+		defer cancel(nil)
+		bs.SendAll(sendCtx, out, func(o Outgoing, sendErr error) {
+			if _, err := t.record(ctx, o, sendErr); err != nil {
+				cancel(err)
+			}
+		})
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err := context.Cause(sendCtx); err != nil {
+			return err
+		}
+	} else {
+		gone := make(map[string]bool)
+		for _, o := range out {
+			if gone[o.Recipient.ID] {
+				continue
+			}
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			sendErr := ch.Send(ctx, o.Recipient, o.Message)
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if gone[o.Recipient.ID], err = t.record(ctx, o, sendErr); err != nil {
+				return err
+			}
+		}
+	}
+
+	d.logger.Info("alerts sent", "channel", ch.Name(), "sent", t.sent, "failed", t.failed)
+	if t.failed > 0 && t.sent == 0 {
+		return fmt.Errorf("all %d messages failed", t.failed)
+	}
+	return nil
+}
+
+// tally records the outcome of each message of a channel and logs it with
+// the progress of the run.
+type tally struct {
+	ch           Channel
+	logger       *slog.Logger
+	total        int
+	sent, failed int
+}
+
+func (t *tally) record(ctx context.Context, o Outgoing, sendErr error) (bool, error) {
+	gone, err := t.ch.Record(ctx, o.Recipient, o.Message, sendErr)
+	if err != nil {
+		return false, err
+	}
+
+	logger := t.logger.With("recipient_id", o.Recipient.ID, "items", len(o.Message.Items))
+	if sendErr != nil {
+		t.failed++
+		logger.Warn("sending alert failed", "done", t.sent+t.failed, "total", t.total, logging.Err(sendErr))
+	} else {
+		t.sent++
+		logger.Info("alert sent", "done", t.sent+t.failed, "total", t.total)
+	}
+	if gone {
+		logger.Info("recipient is gone, no longer sending to it")
+	}
+	return gone, nil
+}
+
+// Preview renders what Dispatch would send, without sending or recording
+// anything.
+func (d *Dispatcher) Preview(ctx context.Context, items []models.ScrapedItem) ([]Preview, error) {
+	previews := make([]Preview, 0, len(d.channels))
+	for _, ch := range d.channels {
+		recipients, err := ch.Recipients(ctx, true)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", ch.Name(), err)
+		}
+		p := Preview{Channel: ch.Name()}
+		for _, r := range recipients {
+			messages, err := d.render(ctx, ch, r, items)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", ch.Name(), err)
+			}
+			p.Messages = append(p.Messages, messages...)
+		}
+		previews = append(previews, p)
+	}
+	return previews, nil
+}
+
+// render builds r's messages about the items r wants and has not received.
+func (d *Dispatcher) render(ctx context.Context, ch Channel, r Recipient, items []models.ScrapedItem) ([]*Message, error) {
+	var pending []models.ScrapedItem
+	for _, item := range items {
+		if !r.Wants(item) {
+			continue
+		}
+		delivered, err := ch.Delivered(ctx, r, item.ID)
+		if err != nil {
+			return nil, err
+		}
+		if !delivered {
+			pending = append(pending, item)
+		}
+	}
+	if len(pending) == 0 {
+		return nil, nil
+	}
+	return ch.Render(r, pending)
+}
+
+// recordChannelDelivery logs a message to a subscriber_channels recipient,
+// unsubscribing it when it is gone.
+func recordChannelDelivery(ctx context.Context, db *models.DB, id int, m *Message, sendErr error, gone bool) (bool, error) {
+	for _, item := range m.Items {
+		if err := db.RecordChannelDelivery(ctx, id, item.ID, sendErr); err != nil {
+			return false, err
+		}
+	}
+	if !gone {
+		return false, nil
+	}
+	return true, db.RemoveChannelSubscriber(ctx, id)
+}
+
+var (
+	_ Channel     = (*EmailService)(nil)
+	_ BatchSender = (*EmailService)(nil)
+	_ Channel     = (*WebhookService)(nil)
+	_ Channel     = (*TelegramService)(nil)
+	_ Channel     = (*WebPushService)(nil)
+)
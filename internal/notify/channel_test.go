@@ -0,0 +1,163 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"strconv"
+	"testing"
+
+	"github.com/paluras/product-recall-system/internal/classify"
+	"github.com/paluras/product-recall-system/internal/models"
+)
+
+func TestRecipientWants(t *testing.T) {
+	item := models.ScrapedItem{Tags: []models.Tag{{Kind: classify.KindCategory, Slug: "dairy"}}}
+
+	for _, tt := range []struct {
+		tags []string
+		want bool
+	}{
+		{nil, true},
+		{[]string{"dairy"}, true},
+		{[]string{"meat", "dairy"}, true},
+		{[]string{"meat"}, false},
+	} {
+		r := Recipient{Tags: tt.tags}
+		if got := r.Wants(item); got != tt.want {
+			t.Errorf("Wants with tags %v = %v, want %v", tt.tags, got, tt.want)
+		}
+	}
+}
+
+// fakeChannel sends one message per item and keeps what it sent in memory.
+type fakeChannel struct {
+	recipients []Recipient
+	delivered  map[string]bool
+	failing    map[string]error
+	gone       map[string]bool
+	sent       []string
+	recorded   int
+}
+
+func (c *fakeChannel) Name() string { return "fake" }
+
+func (c *fakeChannel) Recipients(context.Context, bool) ([]Recipient, error) {
+	return c.recipients, nil
+}
+
+func (c *fakeChannel) Delivered(_ context.Context, r Recipient, itemID int) (bool, error) {
+	return c.delivered[r.ID+"/"+strconv.Itoa(itemID)], nil
+}
+
+func (c *fakeChannel) Render(r Recipient, items []models.ScrapedItem) ([]*Message, error) {
+	var messages []*Message
+	for _, item := range items {
+		messages = append(messages, &Message{To: r.ID, Text: item.Title, Items: []models.ScrapedItem{item}})
+	}
+	return messages, nil
+}
+
+func (c *fakeChannel) Send(_ context.Context, r Recipient, m *Message) error {
+	c.sent = append(c.sent, r.ID+"/"+m.Text)
+	return c.failing[r.ID]
+}
+
+func (c *fakeChannel) Record(_ context.Context, r Recipient, _ *Message, sendErr error) (bool, error) {
+	c.recorded++
+	return sendErr != nil && c.gone[r.ID], nil
+}
+
+func TestDispatch(t *testing.T) {
+	items := []models.ScrapedItem{
+		{ID: 1, Title: "milk", Tags: []models.Tag{{Kind: classify.KindCategory, Slug: "dairy"}}},
+		{ID: 2, Title: "salami", Tags: []models.Tag{{Kind: classify.KindCategory, Slug: "meat"}}},
+	}
+	ch := &fakeChannel{
+		recipients: []Recipient{
+			{ID: "all"},
+			{ID: "dairy", Tags: []string{"dairy"}},
+			{ID: "repeat"},
+			{ID: "blocked"},
+		},
+		delivered: map[string]bool{"repeat/1": true},
+		failing:   map[string]error{"blocked": errors.New("blocked")},
+		gone:      map[string]bool{"blocked": true},
+	}
+
+	d := NewDispatcher(slog.New(slog.NewTextHandler(io.Discard, nil)), ch)
+	if err := d.Dispatch(context.Background(), items); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"all/milk", "all/salami", "dairy/milk", "repeat/salami", "blocked/milk"}
+	if len(ch.sent) != len(want) {
+		t.Fatalf("sent %v, want %v", ch.sent, want)
+	}
+	for i := range want {
+		if ch.sent[i] != want[i] {
+			t.Errorf("sent %v, want %v", ch.sent, want)
+			break
+		}
+	}
+	if ch.recorded != len(want) {
+		t.Errorf("recorded %d messages, want %d", ch.recorded, len(want))
+	}
+}
+
+func TestDispatchAllFailed(t *testing.T) {
+	ch := &fakeChannel{
+		recipients: []Recipient{{ID: "a"}, {ID: "b"}},
+		failing:    map[string]error{"a": errors.New("down"), "b": errors.New("down")},
+	}
+
+	d := NewDispatcher(slog.New(slog.NewTextHandler(io.Discard, nil)), ch)
+	if err := d.Dispatch(context.Background(), []models.ScrapedItem{{ID: 1}}); err == nil {
+		t.Error("want an error when every message failed")
+	}
+}
+
+func TestPreviewSendsNothing(t *testing.T) {
+	ch := &fakeChannel{recipients: []Recipient{{ID: "a"}, {ID: "b"}}}
+
+	d := NewDispatcher(slog.New(slog.NewTextHandler(io.Discard, nil)), ch)
+	previews, err := d.Preview(context.Background(), []models.ScrapedItem{{ID: 1}, {ID: 2}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(previews) != 1 || previews[0].Channel != "fake" || len(previews[0].Messages) != 4 {
+		t.Errorf("got previews %+v, want 4 fake messages", previews)
+	}
+	if len(ch.sent) != 0 || ch.recorded != 0 {
+		t.Errorf("preview sent %d and recorded %d messages", len(ch.sent), ch.recorded)
+	}
+}
+
+// batchChannel hands every message to fakeChannel in one call.
+type batchChannel struct {
+	*fakeChannel
+	calls int
+}
+
+func (c *batchChannel) SendAll(ctx context.Context, out []Outgoing, done func(Outgoing, error)) {
+	c.calls++
+	for _, o := range out {
+		done(o, c.Send(ctx, o.Recipient, o.Message))
+	}
+}
+
+func TestDispatchBatch(t *testing.T) {
+	ch := &batchChannel{fakeChannel: &fakeChannel{
+		recipients: []Recipient{{ID: "a"}, {ID: "b"}},
+		failing:    map[string]error{"b": errors.New("invalid address")},
+	}}
+
+	d := NewDispatcher(slog.New(slog.NewTextHandler(io.Discard, nil)), ch)
+	if err := d.Dispatch(context.Background(), []models.ScrapedItem{{ID: 1}, {ID: 2}}); err != nil {
+		t.Fatal(err)
+	}
+	if ch.calls != 1 || len(ch.sent) != 4 || ch.recorded != 4 {
+		t.Errorf("made %d calls, sent %d and recorded %d messages, want 1, 4 and 4", ch.calls, len(ch.sent), ch.recorded)
+	}
+}
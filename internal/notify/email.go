@@ -2,205 +2,438 @@ package notify
 
 import (
 	"bytes"
-	"html/template"
+	"context"
+	htmltemplate "html/template"
+	"log/slog"
+	"net/url"
+	"slices"
+	"strings"
+	texttemplate "text/template"
+	"time"
 
+	"github.com/paluras/product-recall-system/internal/classify"
+	"github.com/paluras/product-recall-system/internal/i18n"
 	"github.com/paluras/product-recall-system/internal/models"
+	"github.com/paluras/product-recall-system/internal/ratelimit"
+	"github.com/paluras/product-recall-system/ui"
 	"github.com/resend/resend-go/v2"
 )
 
 type EmailConfig struct {
 	APIKey    string
 	FromEmail string
+	// BaseURL is the address of the website, without a trailing slash.
+	BaseURL string
+	// Rate is the number of requests a second Resend allows the account.
+	Rate int
+	// Workers is the number of requests to Resend in flight at once.
+	Workers int
+	// Retries is the number of attempts after the first one when Resend
+	// rate limits a request.
+	Retries int
+	// Backoff is the wait before the first retry unless Resend names one;
+	// it doubles every retry.
+	Backoff time.Duration
 }
 
 type EmailService struct {
-	client *resend.Client
-	config EmailConfig
-	db     *models.DB
+	client  *resend.Client
+	config  EmailConfig
+	db      *models.DB
+	logger  *slog.Logger
+	catalog *i18n.Catalog
+	html    *htmltemplate.Template
+	text    *texttemplate.Template
+	limiter *ratelimit.Bucket
 }
 
-func NewEmailService(cfg EmailConfig, db *models.DB) (*EmailService, error) {
+func NewEmailService(cfg EmailConfig, db *models.DB, logger *slog.Logger) (*EmailService, error) {
 	client := resend.NewClient(cfg.APIKey)
+	if cfg.Rate <= 0 {
+		cfg.Rate = 2
+	}
+	if cfg.Workers <= 0 {
+		cfg.Workers = 1
+	}
+	if cfg.Backoff <= 0 {
+		cfg.Backoff = time.Second
+	}
+
+	catalog, err := i18n.Load()
+	if err != nil {
+		return nil, err
+	}
+
+	funcs := map[string]any{
+		"t":     catalog.T,
+		"upper": strings.ToUpper,
+	}
+	html, err := htmltemplate.New("email").Funcs(funcs).ParseFS(ui.Files, "email/*.html")
+	if err != nil {
+		return nil, err
+	}
+	text, err := texttemplate.New("email").Funcs(funcs).ParseFS(ui.Files, "email/*.txt")
+	if err != nil {
+		return nil, err
+	}
 
 	return &EmailService{
-		client: client,
-		config: cfg,
-		db:     db,
+		client:  client,
+		config:  cfg,
+		db:      db,
+		logger:  logger,
+		catalog: catalog,
+		html:    html,
+		text:    text,
+		limiter: ratelimit.NewBucket(float64(cfg.Rate), cfg.Rate),
 	}, nil
 }
 
-func (s *EmailService) SendBatchNotification(recipients []string, items []models.ScrapedItem) error {
+// render executes the HTML and plain text variants of an email template.
+func (s *EmailService) render(name string, data any) (string, string, error) {
+	var htmlBuffer, textBuffer bytes.Buffer
+	if err := s.html.ExecuteTemplate(&htmlBuffer, name+".html", data); err != nil {
+		return "", "", err
+	}
+	if err := s.text.ExecuteTemplate(&textBuffer, name+".txt", data); err != nil {
+		return "", "", err
+	}
+	return htmlBuffer.String(), textBuffer.String(), nil
+}
+
+// Message is an alert ready to be sent. Emails use every field; other
+// channels send Text alone.
+type Message struct {
+	// SubscriberID identifies the recipient in logs.
+	SubscriberID string
+	From         string
+	To           string
+	Subject      string
+	HTML         string
+	Text         string
+	// Items are the recalls the message tells about.
+	Items []models.ScrapedItem
+}
+
+// previewToken stands in for unsubscribe tokens in previews, which must not
+// write to the database.
+const previewToken = "preview"
 
-	tokenMap := make(map[string]string)
-	for _, recipient := range recipients {
-		token, err := s.db.CreateUnsubscribeToken(recipient)
+// SendBatchNotification sends the items to the subscribers, whether or not
+// they received them before.
+func (s *EmailService) SendBatchNotification(ctx context.Context, subscribers []models.Subscriber, items []models.ScrapedItem) error {
+	var messages []*Message
+	for _, sub := range subscribers {
+		token, err := s.db.CreateUnsubscribeToken(ctx, sub.Email)
 		if err != nil {
 			return err
 		}
-		tokenMap[recipient] = token
-	}
-
-	emailTemplate := `
-	<!DOCTYPE html>
-	<html>
-	<head>
-		<meta charset="UTF-8">
-		<meta name="viewport" content="width=device-width, initial-scale=1.0">
-		<title>Alerte Retragere Produse</title>
-	</head>
-	<body style="margin: 0; padding: 20px; background-color: #f5f5f5; font-family: monospace;">
-		<div style="max-width: 600px; margin: 0 auto; background-color: #fff; border: 3px solid #000; padding: 20px; box-sizing: border-box;">
-			<!-- Logo and Header -->
-			<div style="margin-bottom: 30px; text-align: center;">
-				<div style="width: 60px; height: 60px; background: #000; position: relative; margin: 0 auto 20px;">
-					<div style="position: absolute; color: #fff; font-size: 40px; font-weight: bold; top: 50%; left: 50%; transform: translate(-50%, -50%);">!</div>
-				</div>
-				<h1 style="margin: 0; font-size: clamp(20px, 5vw, 28px); text-transform: uppercase; border-bottom: 3px solid #000; padding-bottom: 20px;">Retrageri Noi de Produse</h1>
-			</div>
-
-			<!-- Product Recalls -->
-			{{range .Items}}
-			<div style="margin-bottom: 30px; padding: 15px; border: 3px solid #000; background-color: #fff;">
-				<h2 style="margin: 0 0 15px 0; font-family: monospace; font-size: clamp(16px, 4vw, 20px); line-height: 1.4; word-break: break-word;">
-					<a href="{{.Link}}" style="color: #000; text-decoration: none; border-bottom: 2px solid #ff0000; display: inline-block;">
-						{{.Title}}
-					</a>
-				</h2>
-				<div style="font-family: monospace; color: #666; font-size: 14px; text-transform: uppercase;">
-					Data Publicării: {{.Date.Format "02/01/2006"}}
-				</div>
-			</div>
-			{{end}}
-
-			<!-- Footer -->
-			 <div style="margin-top: 30px; padding-top: 20px; border-top: 3px solid #000; font-size: 14px; color: #666; text-align: center;">
-        		<p style="margin: 0 0 10px 0;">Primiți acest email deoarece v-ați abonat la alertele noastre despre retragerile de produse.</p>
-        		<p style="margin: 0;">
-           			 <a href="https://produseretrase.eu/unsubscribe?token={{.UnsubscribeToken}}"
-               style="color: #ff0000; text-decoration: none; display: inline-block; border: 2px solid #ff0000; padding: 10px 20px; margin-top: 10px;">
-               Dezabonare
-           			 </a>
-        </p>
-    </div>
-		</div>
-	</body>
-	</html>`
-
-	textTemplate := `ALERTE RETRAGERI PRODUSE
-------------------------
-{{range .Items}}
-{{.Title}}
-Link: {{.Link}}
-Data: {{.Date.Format "02/01/2006"}}
-
-{{end}}
-
-Pentru dezabonare, accesați: https://produseretrase.eu/unsubscribe?token={{.UnsubscribeToken}}`
-
-	for recipient, token := range tokenMap {
-		data := struct {
-			Items            []models.ScrapedItem
-			UnsubscribeToken string
-		}{
-			Items:            items,
-			UnsubscribeToken: token,
-		}
-
-		htmlTmpl, err := template.New("email").Parse(emailTemplate)
+		m, err := s.alertMessage(sub, items, token)
 		if err != nil {
 			return err
 		}
-		var htmlBuffer bytes.Buffer
-		err = htmlTmpl.Execute(&htmlBuffer, data)
+		if m != nil {
+			messages = append(messages, m)
+		}
+	}
+	return s.sendMessages(ctx, messages)
+}
+
+// PreviewBatchNotification renders the alerts SendBatchNotification would
+// send, without sending them or touching the database.
+func (s *EmailService) PreviewBatchNotification(subscribers []models.Subscriber, items []models.ScrapedItem) ([]*Message, error) {
+	var messages []*Message
+	for _, sub := range subscribers {
+		m, err := s.alertMessage(sub, items, previewToken)
 		if err != nil {
-			return err
+			return nil, err
+		}
+		if m != nil {
+			messages = append(messages, m)
+		}
+	}
+	return messages, nil
+}
+
+func (s *EmailService) Name() string {
+	return models.ChannelEmail
+}
+
+// Recipients returns the active subscribers with fresh unsubscribe tokens,
+// or placeholders in a preview. Subscribers who only follow their allergens
+// but picked none are left out.
+func (s *EmailService) Recipients(ctx context.Context, preview bool) ([]Recipient, error) {
+	subscribers, err := s.db.GetActiveSubscribers(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	recipients := make([]Recipient, 0, len(subscribers))
+	for _, sub := range subscribers {
+		if sub.AllergenOnly && len(sub.Allergens) == 0 {
+			continue
+		}
+		sub.UnsubscribeToken = previewToken
+		if !preview {
+			if sub.UnsubscribeToken, err = s.db.CreateUnsubscribeToken(ctx, sub.Email); err != nil {
+				return nil, err
+			}
+		}
+
+		r := Recipient{ID: sub.ID, Locale: sub.Locale, Data: sub}
+		if sub.AllergenOnly {
+			r.Tags = sub.Allergens
+		}
+		recipients = append(recipients, r)
+	}
+	return recipients, nil
+}
+
+func (s *EmailService) Delivered(ctx context.Context, r Recipient, itemID int) (bool, error) {
+	return s.db.Delivered(ctx, r.ID, models.ChannelEmail, itemID)
+}
+
+// Render batches all items into one alert.
+func (s *EmailService) Render(r Recipient, items []models.ScrapedItem) ([]*Message, error) {
+	sub := r.Data.(models.Subscriber)
+	m, err := s.alertMessage(sub, items, sub.UnsubscribeToken)
+	if err != nil || m == nil {
+		return nil, err
+	}
+	return []*Message{m}, nil
+}
+
+func (s *EmailService) Send(ctx context.Context, _ Recipient, m *Message) error {
+	return s.send(ctx, m)
+}
+
+func (s *EmailService) Record(ctx context.Context, _ Recipient, m *Message, sendErr error) (bool, error) {
+	return false, s.db.RecordDeliveries(ctx, m.To, models.ChannelEmail, m.Items, sendErr)
+}
+
+// alertMessage renders the alert for one subscriber, or returns nil when
+// none of the items concern them.
+func (s *EmailService) alertMessage(sub models.Subscriber, items []models.ScrapedItem, unsubscribeToken string) (*Message, error) {
+	highlights, rest := splitByAllergens(sub, items)
+	if sub.AllergenOnly {
+		if len(highlights) == 0 {
+			return nil, nil
+		}
+		rest = nil
+	}
+
+	data := struct {
+		Locale         string
+		Highlights     []allergenHighlight
+		Items          []models.ScrapedItem
+		UnsubscribeURL string
+	}{
+		Locale:         sub.Locale,
+		Highlights:     highlights,
+		Items:          rest,
+		UnsubscribeURL: s.link("/unsubscribe", unsubscribeToken),
+	}
+
+	htmlBody, textBody, err := s.render("alert", data)
+	if err != nil {
+		return nil, err
+	}
+
+	sent := rest
+	for _, h := range highlights {
+		sent = append(sent, h.Item)
+	}
+
+	return &Message{
+		SubscriberID: sub.ID,
+		From:         s.config.FromEmail,
+		To:           sub.Email,
+		Subject:      s.catalog.T(sub.Locale, "email.alert.subject"),
+		HTML:         htmlBody,
+		Text:         textBody,
+		Items:        sent,
+	}, nil
+}
+
+// SendUpdateNotification tells subscribers about recalls that were edited or
+// withdrawn after they were announced. Subscribers who only follow their
+// allergens hear only about those recalls.
+func (s *EmailService) SendUpdateNotification(ctx context.Context, subscribers []models.Subscriber, revisions []models.Revision) error {
+	var messages []*Message
+	for _, sub := range subscribers {
+		if len(relevantRevisions(sub, revisions)) == 0 {
+			continue
 		}
-		htmlBody := htmlBuffer.String()
 
-		textTmpl, err := template.New("email").Parse(textTemplate)
+		token, err := s.db.CreateUnsubscribeToken(ctx, sub.Email)
 		if err != nil {
 			return err
 		}
-		var textBuffer bytes.Buffer
-		err = textTmpl.Execute(&textBuffer, data)
+
+		m, err := s.updateMessage(sub, revisions, token)
 		if err != nil {
 			return err
 		}
-		textBody := textBuffer.String()
+		messages = append(messages, m)
+	}
+	return s.sendMessages(ctx, messages)
+}
 
-		params := &resend.SendEmailRequest{
-			From:    s.config.FromEmail,
-			To:      []string{recipient},
-			Subject: "Alerte Noi Retrageri de Produse",
-			Html:    htmlBody,
-			Text:    textBody,
+// PreviewUpdateNotification renders the emails SendUpdateNotification would
+// send, without sending them or touching the database.
+func (s *EmailService) PreviewUpdateNotification(subscribers []models.Subscriber, revisions []models.Revision) ([]*Message, error) {
+	var messages []*Message
+	for _, sub := range subscribers {
+		if len(relevantRevisions(sub, revisions)) == 0 {
+			continue
 		}
-
-		_, err = s.client.Emails.Send(params)
+		m, err := s.updateMessage(sub, revisions, previewToken)
 		if err != nil {
-			return err
+			return nil, err
+		}
+		messages = append(messages, m)
+	}
+	return messages, nil
+}
+
+func (s *EmailService) updateMessage(sub models.Subscriber, revisions []models.Revision, unsubscribeToken string) (*Message, error) {
+	relevant := relevantRevisions(sub, revisions)
+
+	data := struct {
+		Locale         string
+		Revisions      []models.Revision
+		UnsubscribeURL string
+	}{
+		Locale:         sub.Locale,
+		Revisions:      relevant,
+		UnsubscribeURL: s.link("/unsubscribe", unsubscribeToken),
+	}
+
+	htmlBody, textBody, err := s.render("update", data)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]models.ScrapedItem, len(relevant))
+	for i, r := range relevant {
+		items[i] = r.Item
+	}
+
+	return &Message{
+		SubscriberID: sub.ID,
+		From:         s.config.FromEmail,
+		To:           sub.Email,
+		Subject:      s.catalog.T(sub.Locale, "email.update.subject"),
+		HTML:         htmlBody,
+		Text:         textBody,
+		Items:        items,
+	}, nil
+}
+
+// relevantRevisions drops the revisions a subscriber who only follows their
+// allergens does not need to hear about.
+func relevantRevisions(sub models.Subscriber, revisions []models.Revision) []models.Revision {
+	if !sub.AllergenOnly {
+		return revisions
+	}
+	var relevant []models.Revision
+	for _, r := range revisions {
+		if highlights, _ := splitByAllergens(sub, []models.ScrapedItem{r.Item}); len(highlights) > 0 {
+			relevant = append(relevant, r)
+		}
+	}
+	return relevant
+}
+
+// allergenHighlight is a recall for undeclared allergens the subscriber
+// follows.
+type allergenHighlight struct {
+	Item      models.ScrapedItem
+	Allergens []string
+}
+
+// splitByAllergens separates the items that carry one of the subscriber's
+// allergens from the rest.
+func splitByAllergens(sub models.Subscriber, items []models.ScrapedItem) ([]allergenHighlight, []models.ScrapedItem) {
+	var (
+		highlights []allergenHighlight
+		rest       []models.ScrapedItem
+	)
+	for _, item := range items {
+		var matched []string
+		for _, tag := range item.Tags {
+			if tag.Kind == classify.KindAllergen && slices.Contains(sub.Allergens, tag.Slug) {
+				matched = append(matched, tag.Slug)
+			}
+		}
+		if len(matched) > 0 {
+			highlights = append(highlights, allergenHighlight{Item: item, Allergens: matched})
+		} else {
+			rest = append(rest, item)
 		}
 	}
+	return highlights, rest
+}
 
-	return nil
-}
-
-func (s *EmailService) SendConfirmationEmail(recipient, confirmToken string) error {
-	htmlBody := `<!DOCTYPE html>
-<html>
-<head>
-	<meta charset="UTF-8">
-	<meta name="viewport" content="width=device-width, initial-scale=1.0">
-	<title>Confirmați abonarea</title>
-</head>
-<body style="margin: 0; padding: 20px; background-color: #f5f5f5; font-family: monospace;">
-	<div style="max-width: 600px; margin: 0 auto; background-color: #fff; border: 3px solid #000; padding: 20px; box-sizing: border-box;">
-		<div style="margin-bottom: 30px; text-align: center;">
-			<div style="width: 60px; height: 60px; background: #000; position: relative; margin: 0 auto 20px;">
-				<div style="position: absolute; color: #fff; font-size: 40px; font-weight: bold; top: 50%; left: 50%; transform: translate(-50%, -50%);">!</div>
-			</div>
-			<h1 style="margin: 0; font-size: clamp(20px, 5vw, 28px); text-transform: uppercase; border-bottom: 3px solid #000; padding-bottom: 20px;">Confirmați Abonarea</h1>
-		</div>
-
-		<div style="padding: 20px; border: 3px solid #000; background-color: #fff; margin-bottom: 20px;">
-			<p style="font-family: monospace; font-size: 16px; line-height: 1.6; margin: 0;">
-				Ați solicitat abonarea la alertele despre retragerile de produse din România. Apăsați butonul de mai jos pentru a confirma adresa de email.
-			</p>
-			<p style="font-family: monospace; font-size: 14px; color: #666; margin-top: 10px;">
-				Dacă nu ați solicitat această abonare, ignorați acest email.
-			</p>
-		</div>
-
-		<div style="text-align: center; margin-bottom: 30px;">
-			<a href="https://produseretrase.eu/confirm?token=` + confirmToken + `"
-				style="color: #fff; background: #000; text-decoration: none; display: inline-block; border: 3px solid #000; padding: 14px 28px; font-family: monospace; font-size: 16px; font-weight: bold; text-transform: uppercase;">
-				Confirmă Abonarea
-			</a>
-		</div>
-
-		<div style="margin-top: 30px; padding-top: 20px; border-top: 3px solid #000; font-size: 12px; color: #999; text-align: center;">
-			<p style="margin: 0;">Dacă butonul nu funcționează, copiați acest link în browser:</p>
-			<p style="margin: 5px 0 0 0; word-break: break-all;">https://produseretrase.eu/confirm?token=` + confirmToken + `</p>
-		</div>
-	</div>
-</body>
-</html>`
-
-	textBody := "CONFIRMAȚI ABONAREA\n" +
-		"-------------------\n\n" +
-		"Ați solicitat abonarea la alertele despre retragerile de produse din România.\n\n" +
-		"Confirmați adresa de email accesând:\n" +
-		"https://produseretrase.eu/confirm?token=" + confirmToken + "\n\n" +
-		"Dacă nu ați solicitat această abonare, ignorați acest email."
+// sendAction sends a single call-to-action message such as a magic link.
+// key selects the messages, e.g. "email.confirm".
+func (s *EmailService) sendAction(ctx context.Context, recipient, locale, key, link string) error {
+	data := struct {
+		Locale string
+		Key    string
+		Link   string
+	}{
+		Locale: locale,
+		Key:    key,
+		Link:   link,
+	}
+
+	htmlBody, textBody, err := s.render("action", data)
+	if err != nil {
+		return err
+	}
 
 	params := &resend.SendEmailRequest{
 		From:    s.config.FromEmail,
 		To:      []string{recipient},
-		Subject: "Confirmați abonarea – Alerte Retrageri Produse",
+		Subject: s.catalog.T(locale, key+".subject"),
 		Html:    htmlBody,
 		Text:    textBody,
 	}
 
-	_, err := s.client.Emails.Send(params)
+	_, err = s.client.Emails.SendWithContext(ctx, params)
+	return err
+}
+
+// link returns the absolute URL of a page on the website that takes token.
+func (s *EmailService) link(path, token string) string {
+	return s.config.BaseURL + path + "?token=" + url.QueryEscape(token)
+}
+
+func (s *EmailService) SendConfirmationEmail(ctx context.Context, recipient, confirmToken, locale string) error {
+	return s.sendAction(ctx, recipient, locale, "email.confirm", s.link("/confirm", confirmToken))
+}
+
+func (s *EmailService) SendManageLink(ctx context.Context, recipient, token, locale string) error {
+	return s.sendAction(ctx, recipient, locale, "email.manage", s.link("/preferences", token))
+}
+
+func (s *EmailService) SendEmailChangeConfirmation(ctx context.Context, recipient, token, locale string) error {
+	return s.sendAction(ctx, recipient, locale, "email.email_change", s.link("/preferences/email", token))
+}
+
+func (s *EmailService) SendDataExport(ctx context.Context, recipient, locale string, data []byte) error {
+	params := &resend.SendEmailRequest{
+		From:    s.config.FromEmail,
+		To:      []string{recipient},
+		Subject: s.catalog.T(locale, "email.export.subject"),
+		Text:    s.catalog.T(locale, "email.export.body"),
+		Attachments: []*resend.Attachment{{
+			Content:  data,
+			Filename: "produseretrase-date.json",
+		}},
+	}
+
+	_, err := s.client.Emails.SendWithContext(ctx, params)
 	return err
 }
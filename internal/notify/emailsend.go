@@ -0,0 +1,166 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/paluras/product-recall-system/internal/logging"
+	"github.com/paluras/product-recall-system/internal/models"
+	"github.com/resend/resend-go/v2"
+)
+
+// emailBatchSize is the most emails Resend accepts in one batch request.
+const emailBatchSize = 100
+
+// SendAll sends the messages in batches of up to emailBatchSize, with
+// Workers requests in flight and no more than Rate of them a second.
+func (s *EmailService) SendAll(ctx context.Context, out []Outgoing, done func(Outgoing, error)) {
+	batches := make(chan []Outgoing)
+	var (
+		mu sync.Mutex
+		wg sync.WaitGroup
+	)
+	for range s.config.Workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for batch := range batches {
+				messages := make([]*Message, len(batch))
+				for i, o := range batch {
+					messages[i] = o.Message
+				}
+				errs := s.sendBatch(ctx, messages)
+
+				mu.Lock()
+				for i, o := range batch {
+					done(o, errs[i])
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+	for start := 0; start < len(out); start += emailBatchSize {
+		batches <- out[start:min(start+emailBatchSize, len(out))]
+	}
+	close(batches)
+	wg.Wait()
+}
+
+// sendBatch sends the messages in one request and returns the error of
+// each. A single message goes through the plain send endpoint.
+func (s *EmailService) sendBatch(ctx context.Context, messages []*Message) []error {
+	errs := make([]error, len(messages))
+	if len(messages) == 1 {
+		errs[0] = s.send(ctx, messages[0])
+		return errs
+	}
+
+	params := make([]*resend.SendEmailRequest, len(messages))
+	for i, m := range messages {
+		params[i] = m.request()
+	}
+	// permissive validation sends the valid emails of a batch with an
+	// invalid one, and tells which failed
+	options := &resend.BatchSendEmailOptions{BatchValidation: resend.BatchValidationPermissive}
+
+	var resp *resend.BatchEmailResponse
+	err := s.retry(ctx, func() error {
+		var err error
+		resp, err = s.client.Batch.SendWithOptions(ctx, params, options)
+		return err
+	})
+	if err != nil {
+		for i := range errs {
+			errs[i] = err
+		}
+		return errs
+	}
+	for _, e := range resp.Errors {
+		if e.Index >= 0 && e.Index < len(errs) {
+			errs[e.Index] = errors.New(e.Message)
+		}
+	}
+	return errs
+}
+
+func (s *EmailService) send(ctx context.Context, m *Message) error {
+	return s.retry(ctx, func() error {
+		_, err := s.client.Emails.SendWithContext(ctx, m.request())
+		return err
+	})
+}
+
+// retry makes a request once the limiter allows it, and again when Resend
+// rate limits it anyway, e.g. because another process shares the account.
+// Other errors are not retried: Resend may have accepted the request.
+func (s *EmailService) retry(ctx context.Context, request func() error) error {
+	backoff := s.config.Backoff
+	for attempt := 0; ; attempt++ {
+		if err := s.limiter.Wait(ctx); err != nil {
+			return err
+		}
+		err := request()
+		var rateErr *resend.RateLimitError
+		if err == nil || !errors.As(err, &rateErr) || attempt >= s.config.Retries {
+			return err
+		}
+
+		wait := backoff
+		if seconds, _ := strconv.Atoi(rateErr.RetryAfter); seconds > 0 {
+			wait = time.Duration(seconds) * time.Second
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+		backoff *= 2
+	}
+}
+
+func (m *Message) request() *resend.SendEmailRequest {
+	return &resend.SendEmailRequest{
+		From:    m.From,
+		To:      []string{m.To},
+		Subject: m.Subject,
+		Html:    m.HTML,
+		Text:    m.Text,
+	}
+}
+
+// sendMessages sends messages outside a Dispatcher, logging and recording
+// the delivery of each. It fails when any message failed.
+func (s *EmailService) sendMessages(ctx context.Context, messages []*Message) error {
+	out := make([]Outgoing, len(messages))
+	for i, m := range messages {
+		out[i] = Outgoing{Message: m}
+	}
+
+	var (
+		failed int
+		errs   []error
+	)
+	s.SendAll(ctx, out, func(o Outgoing, err error) {
+		m := o.Message
+		attrs := []any{logging.SubscriberID(m.SubscriberID), logging.Email(m.To), "items", len(m.Items)}
+		if err != nil {
+			failed++
+			s.logger.Error("sending email failed", append(attrs, logging.Err(err))...)
+		} else {
+			s.logger.Info("email sent", attrs...)
+		}
+		if logErr := s.db.RecordDeliveries(ctx, m.To, models.ChannelEmail, m.Items, err); logErr != nil {
+			errs = append(errs, logErr)
+		}
+	})
+
+	if failed > 0 {
+		errs = append(errs, fmt.Errorf("%d of %d emails failed", failed, len(messages)))
+	}
+	return errors.Join(errs...)
+}
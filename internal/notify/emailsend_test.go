@@ -0,0 +1,153 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeResend records the emails posted to it. It answers the first
+// requests with the queued statuses and rejects the batch entries whose
+// recipients are in invalid.
+type fakeResend struct {
+	mu       sync.Mutex
+	status   []int
+	invalid  map[string]bool
+	requests map[string]int
+	sent     []string
+}
+
+func (f *fakeResend) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.requests[r.URL.Path]++
+
+	if len(f.status) > 0 {
+		status := f.status[0]
+		f.status = f.status[1:]
+		w.Header().Set("Retry-After", "0")
+		http.Error(w, http.StatusText(status), status)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	switch r.URL.Path {
+	case "/emails":
+		var email struct{ To []string }
+		json.NewDecoder(r.Body).Decode(&email)
+		f.sent = append(f.sent, email.To...)
+		fmt.Fprint(w, `{"id": "1"}`)
+	case "/emails/batch":
+		var emails []struct{ To []string }
+		json.NewDecoder(r.Body).Decode(&emails)
+		var errs []map[string]any
+		for i, e := range emails {
+			if f.invalid[e.To[0]] {
+				errs = append(errs, map[string]any{"index": i, "message": "invalid recipient"})
+				continue
+			}
+			f.sent = append(f.sent, e.To...)
+		}
+		json.NewEncoder(w).Encode(map[string]any{"data": []any{}, "errors": errs})
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func newTestEmail(t *testing.T, f *fakeResend) *EmailService {
+	t.Helper()
+	f.requests = make(map[string]int)
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+
+	s, err := NewEmailService(EmailConfig{
+		APIKey:    "re_test",
+		FromEmail: "alerts@example.org",
+		Rate:      1000,
+		Workers:   3,
+		Retries:   2,
+		Backoff:   time.Millisecond,
+	}, nil, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.client.BaseURL, _ = url.Parse(srv.URL + "/")
+	return s
+}
+
+func outgoing(n int) []Outgoing {
+	out := make([]Outgoing, n)
+	for i := range out {
+		out[i] = Outgoing{Message: &Message{To: fmt.Sprintf("s%d@example.org", i), Subject: "Recall"}}
+	}
+	return out
+}
+
+func TestSendAllBatches(t *testing.T) {
+	f := &fakeResend{invalid: map[string]bool{"s7@example.org": true}}
+	s := newTestEmail(t, f)
+
+	failed := map[string]error{}
+	outcomes := 0
+	s.SendAll(context.Background(), outgoing(250), func(o Outgoing, err error) {
+		outcomes++
+		if err != nil {
+			failed[o.Message.To] = err
+		}
+	})
+
+	if outcomes != 250 {
+		t.Errorf("got %d outcomes, want 250", outcomes)
+	}
+	if f.requests["/emails/batch"] != 3 {
+		t.Errorf("made %d batch requests, want 3", f.requests["/emails/batch"])
+	}
+	if len(f.sent) != 249 {
+		t.Errorf("sent %d emails, want 249", len(f.sent))
+	}
+	if len(failed) != 1 || failed["s7@example.org"] == nil {
+		t.Errorf("failed %v, want only s7@example.org", failed)
+	}
+}
+
+func TestSendAllRetriesRateLimit(t *testing.T) {
+	f := &fakeResend{status: []int{http.StatusTooManyRequests, http.StatusTooManyRequests}}
+	s := newTestEmail(t, f)
+
+	var sendErr error
+	s.SendAll(context.Background(), outgoing(1), func(_ Outgoing, err error) {
+		sendErr = err
+	})
+
+	if sendErr != nil {
+		t.Fatal(sendErr)
+	}
+	if f.requests["/emails"] != 3 || len(f.sent) != 1 {
+		t.Errorf("made %d requests and sent %d emails, want 3 and 1", f.requests["/emails"], len(f.sent))
+	}
+}
+
+func TestSendAllGivesUp(t *testing.T) {
+	f := &fakeResend{status: []int{http.StatusTooManyRequests, http.StatusTooManyRequests, http.StatusTooManyRequests}}
+	s := newTestEmail(t, f)
+
+	var errs []error
+	s.SendAll(context.Background(), outgoing(2), func(_ Outgoing, err error) {
+		errs = append(errs, err)
+	})
+
+	if len(errs) != 2 || errs[0] == nil || errs[1] == nil {
+		t.Errorf("got errors %v, want both emails to fail", errs)
+	}
+	if f.requests["/emails/batch"] != 3 {
+		t.Errorf("made %d batch requests, want 3", f.requests["/emails/batch"])
+	}
+}
@@ -0,0 +1,266 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/paluras/product-recall-system/internal/classify"
+	"github.com/paluras/product-recall-system/internal/i18n"
+	"github.com/paluras/product-recall-system/internal/models"
+	"github.com/paluras/product-recall-system/internal/utils"
+)
+
+// DefaultTelegramAPIURL is the public Bot API server.
+const DefaultTelegramAPIURL = "https://api.telegram.org"
+
+// telegramPoll is how long getUpdates waits for new messages.
+const telegramPoll = 30 * time.Second
+
+// telegramRate keeps bulk sends under the Bot API's limit of about 30
+// messages a second.
+const telegramRate = time.Second / 25
+
+type TelegramConfig struct {
+	Token string
+	// APIURL is the Bot API server, without a trailing slash.
+	APIURL string
+	// BaseURL is the address of the website, without a trailing slash.
+	BaseURL string
+	// Timeout bounds each request; getUpdates may take telegramPoll longer.
+	Timeout time.Duration
+	// Retries is the number of attempts after the first one when Telegram
+	// is unavailable or asks us to slow down.
+	Retries int
+	// Backoff is the wait before the first retry unless Telegram names
+	// one; it doubles every retry.
+	Backoff time.Duration
+	// Tags are the tags chats can filter recalls by.
+	Tags []classify.Tag
+}
+
+// TelegramService sends recalls to the chats that started the bot, and
+// answers the commands they send it.
+type TelegramService struct {
+	config  TelegramConfig
+	db      *models.DB
+	logger  *slog.Logger
+	catalog *i18n.Catalog
+	http    *http.Client
+	// next is when the next alert may be sent.
+	next time.Time
+}
+
+func NewTelegramService(cfg TelegramConfig, db *models.DB, logger *slog.Logger) (*TelegramService, error) {
+	if cfg.APIURL == "" {
+		cfg.APIURL = DefaultTelegramAPIURL
+	}
+	if cfg.Backoff <= 0 {
+		cfg.Backoff = time.Second
+	}
+	catalog, err := i18n.Load()
+	if err != nil {
+		return nil, err
+	}
+	return &TelegramService{
+		config:  cfg,
+		db:      db,
+		logger:  logger,
+		catalog: catalog,
+		http:    utils.CreateHTTPClient(cfg.Timeout + telegramPoll),
+	}, nil
+}
+
+// TelegramError is an unsuccessful response of the Bot API.
+type TelegramError struct {
+	Method      string
+	Code        int
+	Description string
+	// RetryAfter is the number of seconds to wait before trying again.
+	RetryAfter int
+}
+
+func (e *TelegramError) Error() string {
+	return fmt.Sprintf("telegram %s: %d %s", e.Method, e.Code, e.Description)
+}
+
+func (s *TelegramService) Name() string {
+	return models.ChannelTelegram
+}
+
+// Recipients returns the chats that started the bot, with their filters.
+func (s *TelegramService) Recipients(ctx context.Context, _ bool) ([]Recipient, error) {
+	chats, err := s.db.GetChannelSubscribers(ctx, models.ChannelTelegram)
+	if err != nil {
+		return nil, err
+	}
+	recipients := make([]Recipient, len(chats))
+	for i, chat := range chats {
+		recipients[i] = Recipient{ID: strconv.Itoa(chat.ID), Locale: chat.Locale, Tags: chat.Tags, Data: chat}
+	}
+	return recipients, nil
+}
+
+func (s *TelegramService) Delivered(ctx context.Context, r Recipient, itemID int) (bool, error) {
+	return s.db.ChannelDelivered(ctx, r.Data.(models.ChannelSubscriber).ID, itemID)
+}
+
+// Render writes one message per item.
+func (s *TelegramService) Render(r Recipient, items []models.ScrapedItem) ([]*Message, error) {
+	messages := make([]*Message, len(items))
+	for i, item := range items {
+		messages[i] = &Message{
+			To:    r.Data.(models.ChannelSubscriber).Address,
+			Text:  s.alertText(r.Locale, item),
+			Items: []models.ScrapedItem{item},
+		}
+	}
+	return messages, nil
+}
+
+// Send waits its turn under telegramRate and sends m to the chat.
+func (s *TelegramService) Send(ctx context.Context, _ Recipient, m *Message) error {
+	if wait := time.Until(s.next); wait > 0 {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+	s.next = time.Now().Add(telegramRate)
+	return s.send(ctx, m.To, m.Text)
+}
+
+// Record unsubscribes chats that blocked the bot.
+func (s *TelegramService) Record(ctx context.Context, r Recipient, m *Message, sendErr error) (bool, error) {
+	return recordChannelDelivery(ctx, s.db, r.Data.(models.ChannelSubscriber).ID, m, sendErr, chatGone(sendErr))
+}
+
+// chatGone reports whether the chat can no longer be written to: the user
+// blocked the bot, deleted their account or the group is gone.
+func chatGone(err error) bool {
+	var tgErr *TelegramError
+	if !errors.As(err, &tgErr) {
+		return false
+	}
+	return tgErr.Code == http.StatusForbidden ||
+		(tgErr.Code == http.StatusBadRequest && strings.Contains(tgErr.Description, "chat not found"))
+}
+
+// alertText formats an item as an HTML message linking to its page.
+func (s *TelegramService) alertText(locale string, item models.ScrapedItem) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "<b>%s</b>\n\n", html.EscapeString(s.catalog.T(locale, "telegram.alert")))
+	fmt.Fprintf(&b, "%s\n", html.EscapeString(item.Title))
+	fmt.Fprintf(&b, "%s %s\n", html.EscapeString(s.catalog.T(locale, "email.alert.date")), item.Date.Format("02/01/2006"))
+	if len(item.Tags) > 0 {
+		names := make([]string, len(item.Tags))
+		for i, tag := range item.Tags {
+			names[i] = s.catalog.T(locale, "tag."+tag.Slug)
+		}
+		fmt.Fprintf(&b, "%s %s\n", html.EscapeString(s.catalog.T(locale, "telegram.tags")), html.EscapeString(strings.Join(names, ", ")))
+	}
+	link := s.config.BaseURL + "/recalls/" + strconv.Itoa(item.ID)
+	fmt.Fprintf(&b, "\n<a href=\"%s\">%s</a>", html.EscapeString(link), html.EscapeString(s.catalog.T(locale, "telegram.view")))
+	return b.String()
+}
+
+// send posts an HTML message to a chat, retrying when Telegram is
+// unavailable or rate limits us.
+func (s *TelegramService) send(ctx context.Context, chatID, text string) error {
+	params := map[string]any{
+		"chat_id":              chatID,
+		"text":                 text,
+		"parse_mode":           "HTML",
+		"link_preview_options": map[string]bool{"is_disabled": true},
+	}
+
+	backoff := s.config.Backoff
+	for attempt := 0; ; attempt++ {
+		err := s.call(ctx, "sendMessage", params, nil)
+		if err == nil {
+			return nil
+		}
+
+		wait := backoff
+		var tgErr *TelegramError
+		if errors.As(err, &tgErr) {
+			if tgErr.Code != http.StatusTooManyRequests && tgErr.Code < 500 {
+				return err
+			}
+			if tgErr.RetryAfter > 0 {
+				wait = time.Duration(tgErr.RetryAfter) * time.Second
+			}
+		}
+		if attempt >= s.config.Retries || ctx.Err() != nil {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+		backoff *= 2
+	}
+}
+
+// call invokes a Bot API method with params as JSON and decodes its result
+// into result, if not nil.
+func (s *TelegramService) call(ctx context.Context, method string, params, result any) error {
+	body, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	endpoint := s.config.APIURL + "/bot" + s.config.Token + "/" + method
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("telegram %s: invalid API URL", method)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.http.Do(req)
+	if err != nil {
+		// the URL holds the bot token, which must not end up in logs
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return fmt.Errorf("telegram %s: %w", method, err)
+	}
+	defer resp.Body.Close()
+
+	var r struct {
+		OK          bool            `json:"ok"`
+		Result      json.RawMessage `json:"result"`
+		ErrorCode   int             `json:"error_code"`
+		Description string          `json:"description"`
+		Parameters  struct {
+			RetryAfter int `json:"retry_after"`
+		} `json:"parameters"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&r); err != nil {
+		return fmt.Errorf("telegram %s: %s: %w", method, resp.Status, err)
+	}
+	if !r.OK {
+		code := r.ErrorCode
+		if code == 0 {
+			code = resp.StatusCode
+		}
+		return &TelegramError{Method: method, Code: code, Description: r.Description, RetryAfter: r.Parameters.RetryAfter}
+	}
+	if result == nil {
+		return nil
+	}
+	return json.Unmarshal(r.Result, result)
+}
@@ -0,0 +1,167 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/paluras/product-recall-system/internal/classify"
+	"github.com/paluras/product-recall-system/internal/models"
+)
+
+const testToken = "123:secret"
+
+// fakeTelegram stands in for the Bot API, answering sendMessage with the
+// queued responses, then with success.
+type fakeTelegram struct {
+	responses []string
+	messages  []map[string]any
+}
+
+func (f *fakeTelegram) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/bot"+testToken+"/sendMessage" {
+		http.NotFound(w, r)
+		return
+	}
+	var params map[string]any
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	f.messages = append(f.messages, params)
+
+	response := `{"ok":true,"result":{}}`
+	if len(f.responses) > 0 {
+		response, f.responses = f.responses[0], f.responses[1:]
+	}
+	w.Header().Set("Content-Type", "application/json")
+	io.WriteString(w, response)
+}
+
+func newTestTelegram(t *testing.T, fake *fakeTelegram) *TelegramService {
+	t.Helper()
+	apiURL := "http://127.0.0.1:1"
+	if fake != nil {
+		srv := httptest.NewServer(fake)
+		t.Cleanup(srv.Close)
+		apiURL = srv.URL
+	}
+	s, err := NewTelegramService(TelegramConfig{
+		Token:   testToken,
+		APIURL:  apiURL,
+		BaseURL: "https://example.org",
+		Timeout: time.Second,
+		Retries: 2,
+		Backoff: time.Millisecond,
+		Tags:    []classify.Tag{{Kind: classify.KindAllergen, Slug: "milk"}},
+	}, nil, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestTelegramSend(t *testing.T) {
+	fake := &fakeTelegram{}
+	s := newTestTelegram(t, fake)
+
+	if err := s.send(context.Background(), "42", "<b>hi</b>"); err != nil {
+		t.Fatal(err)
+	}
+	if len(fake.messages) != 1 {
+		t.Fatalf("sent %d messages, want 1", len(fake.messages))
+	}
+	m := fake.messages[0]
+	if m["chat_id"] != "42" || m["text"] != "<b>hi</b>" || m["parse_mode"] != "HTML" {
+		t.Errorf("unexpected message %v", m)
+	}
+}
+
+func TestTelegramSendRetries(t *testing.T) {
+	fake := &fakeTelegram{responses: []string{
+		`{"ok":false,"error_code":502,"description":"Bad Gateway"}`,
+		`{"ok":false,"error_code":429,"description":"Too Many Requests: retry after 0","parameters":{"retry_after":0}}`,
+	}}
+	s := newTestTelegram(t, fake)
+
+	if err := s.send(context.Background(), "42", "hi"); err != nil {
+		t.Fatal(err)
+	}
+	if len(fake.messages) != 3 {
+		t.Errorf("made %d attempts, want 3", len(fake.messages))
+	}
+}
+
+func TestTelegramSendBlocked(t *testing.T) {
+	fake := &fakeTelegram{responses: []string{
+		`{"ok":false,"error_code":403,"description":"Forbidden: bot was blocked by the user"}`,
+	}}
+	s := newTestTelegram(t, fake)
+
+	err := s.send(context.Background(), "42", "hi")
+	if !chatGone(err) {
+		t.Errorf("got %v, want an error that unsubscribes the chat", err)
+	}
+	if len(fake.messages) != 1 {
+		t.Errorf("made %d attempts, want 1", len(fake.messages))
+	}
+}
+
+func TestTelegramErrorHidesToken(t *testing.T) {
+	s := newTestTelegram(t, nil)
+
+	err := s.send(context.Background(), "42", "hi")
+	if err == nil {
+		t.Fatal("want an error from an unreachable server")
+	}
+	if strings.Contains(err.Error(), "secret") {
+		t.Errorf("error %q contains the bot token", err)
+	}
+}
+
+func TestTelegramAlertText(t *testing.T) {
+	s := newTestTelegram(t, nil)
+	item := models.ScrapedItem{
+		ID:    7,
+		Title: "Lapte <1,5%> & smântână",
+		Date:  time.Date(2024, 3, 5, 0, 0, 0, 0, time.UTC),
+		Tags:  []models.Tag{{Kind: classify.KindAllergen, Slug: "milk"}},
+	}
+
+	text := s.alertText("en", item)
+	for _, want := range []string{
+		"Lapte &lt;1,5%&gt; &amp; smântână",
+		"05/03/2024",
+		"Milk",
+		`<a href="https://example.org/recalls/7">`,
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("alert text does not contain %q:\n%s", want, text)
+		}
+	}
+}
+
+func TestParseCommand(t *testing.T) {
+	for _, tt := range []struct {
+		text    string
+		command string
+		args    []string
+	}{
+		{"/start", "/start", []string{}},
+		{"/Filter@RecallBot milk  gluten", "/filter", []string{"milk", "gluten"}},
+		{"hello", "", nil},
+		{"", "", nil},
+	} {
+		command, args := parseCommand(tt.text)
+		if command != tt.command || !slices.Equal(args, tt.args) {
+			t.Errorf("parseCommand(%q) = %q, %q; want %q, %q", tt.text, command, args, tt.command, tt.args)
+		}
+	}
+}
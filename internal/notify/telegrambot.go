@@ -0,0 +1,183 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/paluras/product-recall-system/internal/classify"
+	"github.com/paluras/product-recall-system/internal/i18n"
+	"github.com/paluras/product-recall-system/internal/logging"
+	"github.com/paluras/product-recall-system/internal/models"
+)
+
+type telegramUpdate struct {
+	UpdateID int `json:"update_id"`
+	Message  *struct {
+		Text string `json:"text"`
+		Chat struct {
+			ID int64 `json:"id"`
+		} `json:"chat"`
+		From *struct {
+			LanguageCode string `json:"language_code"`
+		} `json:"from"`
+	} `json:"message"`
+}
+
+// RunBot long-polls the Bot API for messages and answers the commands in
+// them until ctx is done.
+func (s *TelegramService) RunBot(ctx context.Context) error {
+	offset := 0
+	for {
+		var updates []telegramUpdate
+		params := map[string]any{
+			"offset":          offset,
+			"timeout":         int(telegramPoll / time.Second),
+			"allowed_updates": []string{"message"},
+		}
+		err := s.call(ctx, "getUpdates", params, &updates)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err != nil {
+			s.logger.Warn("fetching telegram updates failed", logging.Err(err))
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-time.After(5 * time.Second):
+			}
+			continue
+		}
+
+		for _, u := range updates {
+			// the next getUpdates confirms everything before offset
+			offset = u.UpdateID + 1
+			if u.Message == nil {
+				continue
+			}
+			language := ""
+			if u.Message.From != nil {
+				language = u.Message.From.LanguageCode
+			}
+			chatID := strconv.FormatInt(u.Message.Chat.ID, 10)
+			if err := s.handle(ctx, chatID, u.Message.Text, language); err != nil {
+				s.logger.Error("answering telegram command failed", logging.Err(err))
+			}
+		}
+	}
+}
+
+// parseCommand splits a message into its command, without the bot name
+// groups add, and the arguments.
+func parseCommand(text string) (string, []string) {
+	fields := strings.Fields(text)
+	if len(fields) == 0 || !strings.HasPrefix(fields[0], "/") {
+		return "", nil
+	}
+	command, _, _ := strings.Cut(fields[0], "@")
+	return strings.ToLower(command), fields[1:]
+}
+
+// handle answers one message of a chat.
+func (s *TelegramService) handle(ctx context.Context, chatID, text, language string) error {
+	command, args := parseCommand(text)
+
+	chat, err := s.db.GetChannelSubscriber(ctx, models.ChannelTelegram, chatID)
+	if err != nil && !errors.Is(err, models.ErrNoRecord) {
+		return err
+	}
+
+	locale := i18n.Default
+	if chat != nil {
+		locale = chat.Locale
+	} else if base, _, _ := strings.Cut(strings.ToLower(language), "-"); s.catalog.Supported(base) {
+		locale = base
+	}
+	reply := func(key string, args ...any) error {
+		return s.send(ctx, chatID, html.EscapeString(s.catalog.T(locale, key, args...)))
+	}
+
+	switch command {
+	case "/start":
+		if chat == nil {
+			id, err := s.db.AddChannelSubscriber(ctx, models.ChannelTelegram, chatID, locale)
+			if err != nil {
+				return err
+			}
+			s.logger.Info("telegram chat subscribed", "channel_id", id)
+		}
+		return s.send(ctx, chatID, html.EscapeString(s.catalog.T(locale, "telegram.welcome")+"\n\n"+s.catalog.T(locale, "telegram.help")))
+	case "/help", "":
+		return reply("telegram.help")
+	}
+
+	if chat == nil {
+		return reply("telegram.not_subscribed")
+	}
+
+	switch command {
+	case "/stop":
+		if err := s.db.RemoveChannelSubscriber(ctx, chat.ID); err != nil {
+			return err
+		}
+		s.logger.Info("telegram chat unsubscribed", "channel_id", chat.ID)
+		return reply("telegram.stopped")
+	case "/filter":
+		if len(args) == 0 {
+			return s.send(ctx, chatID, s.filtersText(locale, chat.Tags)+"\n\n"+s.tagsText(locale))
+		}
+		tags := make([]string, len(args))
+		for i, arg := range args {
+			tags[i] = strings.ToLower(strings.Trim(arg, ","))
+			if !s.knownTag(tags[i]) {
+				return s.send(ctx, chatID, html.EscapeString(s.catalog.T(locale, "telegram.unknown_tag", args[i]))+"\n\n"+s.tagsText(locale))
+			}
+		}
+		if err := s.db.SetChannelTags(ctx, chat.ID, tags); err != nil {
+			return err
+		}
+		return s.send(ctx, chatID, s.filtersText(locale, tags))
+	case "/all":
+		if err := s.db.SetChannelTags(ctx, chat.ID, nil); err != nil {
+			return err
+		}
+		return s.send(ctx, chatID, s.filtersText(locale, nil))
+	case "/lang":
+		if len(args) != 1 || !s.catalog.Supported(strings.ToLower(args[0])) {
+			return reply("telegram.unknown_locale", strings.Join(s.catalog.Locales(), ", "))
+		}
+		locale = strings.ToLower(args[0])
+		if err := s.db.SetChannelLocale(ctx, chat.ID, locale); err != nil {
+			return err
+		}
+		return reply("telegram.locale_set")
+	}
+	return reply("telegram.help")
+}
+
+func (s *TelegramService) knownTag(slug string) bool {
+	return slices.ContainsFunc(s.config.Tags, func(t classify.Tag) bool { return t.Slug == slug })
+}
+
+// filtersText describes which recalls the chat receives.
+func (s *TelegramService) filtersText(locale string, tags []string) string {
+	if len(tags) == 0 {
+		return html.EscapeString(s.catalog.T(locale, "telegram.filters_all"))
+	}
+	return html.EscapeString(s.catalog.T(locale, "telegram.filters", strings.Join(tags, ", ")))
+}
+
+// tagsText lists the tags a chat can filter by with their names.
+func (s *TelegramService) tagsText(locale string) string {
+	var b strings.Builder
+	b.WriteString(html.EscapeString(s.catalog.T(locale, "telegram.available")))
+	for _, tag := range s.config.Tags {
+		fmt.Fprintf(&b, "\n<code>%s</code> – %s", html.EscapeString(tag.Slug), html.EscapeString(s.catalog.T(locale, "tag."+tag.Slug)))
+	}
+	return b.String()
+}
@@ -0,0 +1,227 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/paluras/product-recall-system/internal/models"
+	"github.com/paluras/product-recall-system/internal/utils"
+)
+
+// Headers of webhook requests. The signature is the hex HMAC-SHA256 of the
+// timestamp, a dot and the body, keyed with the webhook's secret.
+const (
+	SignatureHeader = "X-Recall-Signature"
+	TimestampHeader = "X-Recall-Timestamp"
+	EventHeader     = "X-Recall-Event"
+)
+
+const eventItemCreated = "recall.created"
+
+type WebhookConfig struct {
+	// BaseURL is the address of the website, without a trailing slash.
+	BaseURL string
+	// Timeout bounds each attempt.
+	Timeout time.Duration
+	// Retries is the number of attempts after the first one.
+	Retries int
+	// Backoff is the wait before the first retry; it doubles every retry.
+	Backoff time.Duration
+	// MaxFailures is the number of recalls in a row a webhook may fail to
+	// accept before it is disabled.
+	MaxFailures int
+}
+
+// webhookStore is the part of models.DB webhooks are kept in.
+type webhookStore interface {
+	GetWebhooks(ctx context.Context, activeOnly bool) ([]models.Webhook, error)
+	WebhookDelivered(ctx context.Context, webhookID, itemID int) (bool, error)
+	RecordWebhookAttempt(ctx context.Context, a models.WebhookAttempt) error
+	WebhookSucceeded(ctx context.Context, id int) error
+	WebhookFailed(ctx context.Context, id int) (int, error)
+	DisableWebhook(ctx context.Context, id int) error
+}
+
+// WebhookService posts every new recall to the registered webhooks.
+type WebhookService struct {
+	config WebhookConfig
+	db     webhookStore
+	logger *slog.Logger
+	http   *http.Client
+}
+
+func NewWebhookService(cfg WebhookConfig, db *models.DB, logger *slog.Logger) *WebhookService {
+	if cfg.Backoff <= 0 {
+		cfg.Backoff = time.Second
+	}
+	return &WebhookService{
+		config: cfg,
+		db:     db,
+		logger: logger,
+		http:   utils.CreateHTTPClient(cfg.Timeout),
+	}
+}
+
+type webhookPayload struct {
+	Event  string      `json:"event"`
+	SentAt time.Time   `json:"sent_at"`
+	Item   webhookItem `json:"item"`
+}
+
+type webhookItem struct {
+	ID    int          `json:"id"`
+	Title string       `json:"title"`
+	Link  string       `json:"link"`
+	URL   string       `json:"url"`
+	Date  time.Time    `json:"date"`
+	Tags  []models.Tag `json:"tags"`
+}
+
+func (s *WebhookService) Name() string {
+	return models.ChannelWebhook
+}
+
+// Recipients returns the webhooks that are not disabled.
+func (s *WebhookService) Recipients(ctx context.Context, _ bool) ([]Recipient, error) {
+	webhooks, err := s.db.GetWebhooks(ctx, true)
+	if err != nil {
+		return nil, err
+	}
+	recipients := make([]Recipient, len(webhooks))
+	for i, w := range webhooks {
+		recipients[i] = Recipient{ID: strconv.Itoa(w.ID), Data: w}
+	}
+	return recipients, nil
+}
+
+func (s *WebhookService) Delivered(ctx context.Context, r Recipient, itemID int) (bool, error) {
+	return s.db.WebhookDelivered(ctx, r.Data.(models.Webhook).ID, itemID)
+}
+
+// Render builds one JSON payload per item.
+func (s *WebhookService) Render(r Recipient, items []models.ScrapedItem) ([]*Message, error) {
+	messages := make([]*Message, len(items))
+	for i, item := range items {
+		tags := item.Tags
+		if tags == nil {
+			tags = []models.Tag{}
+		}
+		body, err := json.Marshal(webhookPayload{
+			Event:  eventItemCreated,
+			SentAt: time.Now().UTC(),
+			Item: webhookItem{
+				ID:    item.ID,
+				Title: item.Title,
+				Link:  item.Link,
+				URL:   s.config.BaseURL + "/recalls/" + strconv.Itoa(item.ID),
+				Date:  item.Date,
+				Tags:  tags,
+			},
+		})
+		if err != nil {
+			return nil, err
+		}
+		messages[i] = &Message{
+			To:    r.Data.(models.Webhook).URL,
+			Text:  string(body),
+			Items: []models.ScrapedItem{item},
+		}
+	}
+	return messages, nil
+}
+
+// Send posts m to the webhook, retrying network errors, 5xx, 408 and 429
+// responses with exponential backoff. Every attempt is recorded.
+func (s *WebhookService) Send(ctx context.Context, r Recipient, m *Message) error {
+	w := r.Data.(models.Webhook)
+	body := []byte(m.Text)
+
+	backoff := s.config.Backoff
+	for attempt := 1; ; attempt++ {
+		statusCode, err := s.post(ctx, w, body)
+		record := models.WebhookAttempt{WebhookID: w.ID, ItemID: m.Items[0].ID, Attempt: attempt, StatusCode: statusCode, Err: err}
+		if recordErr := s.db.RecordWebhookAttempt(ctx, record); recordErr != nil {
+			return recordErr
+		}
+		if err == nil {
+			return nil
+		}
+
+		retryable := statusCode == 0 || statusCode >= 500 ||
+			statusCode == http.StatusRequestTimeout || statusCode == http.StatusTooManyRequests
+		if !retryable || attempt > s.config.Retries {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// Record counts the failures of the webhook in a row, disabling it once
+// they reach MaxFailures. The attempts themselves were recorded by Send.
+func (s *WebhookService) Record(ctx context.Context, r Recipient, _ *Message, sendErr error) (bool, error) {
+	w := r.Data.(models.Webhook)
+	if sendErr == nil {
+		return false, s.db.WebhookSucceeded(ctx, w.ID)
+	}
+	failures, err := s.db.WebhookFailed(ctx, w.ID)
+	if err != nil || failures < s.config.MaxFailures {
+		return false, err
+	}
+	if err := s.db.DisableWebhook(ctx, w.ID); err != nil {
+		return false, err
+	}
+	s.logger.Error("webhook disabled after repeated failures", "webhook_id", w.ID, "failures", failures)
+	return true, nil
+}
+
+// post makes one signed request and returns the response status.
+func (s *WebhookService) post(ctx context.Context, w models.Webhook, body []byte) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "ProduseRetrase-Webhooks/1.0")
+	req.Header.Set(EventHeader, eventItemCreated)
+	req.Header.Set(TimestampHeader, timestamp)
+	req.Header.Set(SignatureHeader, "sha256="+Sign(w.Secret, timestamp, body))
+
+	resp, err := s.http.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("webhook responded %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
+
+// Sign returns the hex HMAC-SHA256 that receivers recompute to check a
+// payload came from us and was not replayed with another timestamp.
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
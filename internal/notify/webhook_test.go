@@ -0,0 +1,250 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/paluras/product-recall-system/internal/models"
+)
+
+const testSecret = "webhook-secret"
+
+// fakeWebhookStore keeps the webhooks and their delivery log in memory.
+type fakeWebhookStore struct {
+	webhooks []models.Webhook
+	attempts []models.WebhookAttempt
+	failures map[int]int
+	disabled map[int]bool
+}
+
+func (f *fakeWebhookStore) GetWebhooks(context.Context, bool) ([]models.Webhook, error) {
+	var active []models.Webhook
+	for _, w := range f.webhooks {
+		if !f.disabled[w.ID] {
+			active = append(active, w)
+		}
+	}
+	return active, nil
+}
+
+func (f *fakeWebhookStore) WebhookDelivered(_ context.Context, webhookID, itemID int) (bool, error) {
+	for _, a := range f.attempts {
+		if a.WebhookID == webhookID && a.ItemID == itemID && a.Err == nil {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (f *fakeWebhookStore) RecordWebhookAttempt(_ context.Context, a models.WebhookAttempt) error {
+	f.attempts = append(f.attempts, a)
+	return nil
+}
+
+func (f *fakeWebhookStore) WebhookSucceeded(_ context.Context, id int) error {
+	f.failures[id] = 0
+	return nil
+}
+
+func (f *fakeWebhookStore) WebhookFailed(_ context.Context, id int) (int, error) {
+	f.failures[id]++
+	return f.failures[id], nil
+}
+
+func (f *fakeWebhookStore) DisableWebhook(_ context.Context, id int) error {
+	f.disabled[id] = true
+	return nil
+}
+
+// receiver is a webhook endpoint that checks the signature of every request
+// and answers with the queued statuses, then with 204.
+type receiver struct {
+	t      *testing.T
+	mu     sync.Mutex
+	status []int
+	bodies []string
+}
+
+func (rc *receiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	body, _ := io.ReadAll(r.Body)
+	rc.bodies = append(rc.bodies, string(body))
+
+	timestamp := r.Header.Get(TimestampHeader)
+	if want := "sha256=" + Sign(testSecret, timestamp, body); r.Header.Get(SignatureHeader) != want {
+		rc.t.Errorf("signature %q, want %q", r.Header.Get(SignatureHeader), want)
+	}
+	if r.Header.Get(EventHeader) != eventItemCreated {
+		rc.t.Errorf("event %q, want %q", r.Header.Get(EventHeader), eventItemCreated)
+	}
+
+	status := http.StatusNoContent
+	if len(rc.status) > 0 {
+		status, rc.status = rc.status[0], rc.status[1:]
+	}
+	w.WriteHeader(status)
+}
+
+func newTestWebhook(t *testing.T, status ...int) (*WebhookService, *fakeWebhookStore, *receiver) {
+	t.Helper()
+	rc := &receiver{t: t, status: status}
+	srv := httptest.NewServer(rc)
+	t.Cleanup(srv.Close)
+
+	store := &fakeWebhookStore{
+		webhooks: []models.Webhook{{ID: 7, URL: srv.URL + "/hook", Secret: testSecret}},
+		failures: map[int]int{},
+		disabled: map[int]bool{},
+	}
+	s := NewWebhookService(WebhookConfig{
+		BaseURL:     "https://example.org",
+		Timeout:     time.Second,
+		Retries:     2,
+		Backoff:     time.Millisecond,
+		MaxFailures: 3,
+	}, nil, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	s.db = store
+	return s, store, rc
+}
+
+// sendItem renders item for the first webhook and sends it.
+func sendItem(t *testing.T, s *WebhookService, item models.ScrapedItem) error {
+	t.Helper()
+	recipients, err := s.Recipients(context.Background(), false)
+	if err != nil || len(recipients) != 1 {
+		t.Fatalf("recipients %v, %v", recipients, err)
+	}
+	messages, err := s.Render(recipients[0], []models.ScrapedItem{item})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s.Send(context.Background(), recipients[0], messages[0])
+}
+
+func checkAttempts(t *testing.T, got []models.WebhookAttempt, statuses ...int) {
+	t.Helper()
+	if len(got) != len(statuses) {
+		t.Fatalf("recorded %d attempts, want %d", len(got), len(statuses))
+	}
+	for i, a := range got {
+		if a.WebhookID != 7 || a.ItemID != 42 || a.Attempt != i+1 || a.StatusCode != statuses[i] {
+			t.Errorf("attempt %d recorded as %+v, want status %d", i+1, a, statuses[i])
+		}
+		if failed := a.StatusCode >= 300; failed != (a.Err != nil) {
+			t.Errorf("attempt %d: status %d with error %v", i+1, a.StatusCode, a.Err)
+		}
+	}
+}
+
+func TestWebhookSend(t *testing.T) {
+	s, store, rc := newTestWebhook(t)
+
+	if err := sendItem(t, s, models.ScrapedItem{ID: 42, Title: "Salam", Link: "https://ansvsa.ro/salam"}); err != nil {
+		t.Fatal(err)
+	}
+	if len(rc.bodies) != 1 {
+		t.Fatalf("received %d requests, want 1", len(rc.bodies))
+	}
+	var payload webhookPayload
+	if err := json.Unmarshal([]byte(rc.bodies[0]), &payload); err != nil {
+		t.Fatal(err)
+	}
+	if payload.Event != eventItemCreated || payload.Item.ID != 42 || payload.Item.URL != "https://example.org/recalls/42" {
+		t.Errorf("payload %+v", payload)
+	}
+	checkAttempts(t, store.attempts, http.StatusNoContent)
+}
+
+func TestWebhookSendRetries(t *testing.T) {
+	s, store, rc := newTestWebhook(t, http.StatusServiceUnavailable, http.StatusTooManyRequests)
+
+	if err := sendItem(t, s, models.ScrapedItem{ID: 42}); err != nil {
+		t.Fatal(err)
+	}
+	if len(rc.bodies) != 3 {
+		t.Errorf("received %d requests, want 3", len(rc.bodies))
+	}
+	checkAttempts(t, store.attempts, http.StatusServiceUnavailable, http.StatusTooManyRequests, http.StatusNoContent)
+}
+
+func TestWebhookSendGivesUp(t *testing.T) {
+	s, store, _ := newTestWebhook(t, http.StatusBadGateway, http.StatusBadGateway, http.StatusBadGateway)
+
+	if err := sendItem(t, s, models.ScrapedItem{ID: 42}); err == nil {
+		t.Fatal("want an error after the retries ran out")
+	}
+	checkAttempts(t, store.attempts, http.StatusBadGateway, http.StatusBadGateway, http.StatusBadGateway)
+}
+
+func TestWebhookSendNoRetryOnClientError(t *testing.T) {
+	for _, status := range []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusNotFound} {
+		s, store, rc := newTestWebhook(t, status)
+
+		if err := sendItem(t, s, models.ScrapedItem{ID: 42}); err == nil {
+			t.Errorf("%d: want an error", status)
+		}
+		if len(rc.bodies) != 1 {
+			t.Errorf("%d: received %d requests, want 1", status, len(rc.bodies))
+		}
+		checkAttempts(t, store.attempts, status)
+	}
+}
+
+func TestWebhookDisabled(t *testing.T) {
+	s, store, _ := newTestWebhook(t)
+	ctx := context.Background()
+	r := Recipient{ID: "7", Data: store.webhooks[0]}
+	failed := errors.New("webhook responded 500")
+
+	// a success in between starts the count again
+	for i, sendErr := range []error{failed, failed, nil, failed, failed} {
+		if gone, err := s.Record(ctx, r, nil, sendErr); err != nil || gone {
+			t.Fatalf("record %d: gone %v, err %v", i+1, gone, err)
+		}
+	}
+	gone, err := s.Record(ctx, r, nil, failed)
+	if err != nil || !gone {
+		t.Fatalf("third failure in a row: gone %v, err %v", gone, err)
+	}
+	if !store.disabled[7] {
+		t.Error("webhook not disabled")
+	}
+}
+
+func TestWebhookDispatchDisables(t *testing.T) {
+	s, store, rc := newTestWebhook(t,
+		http.StatusInternalServerError, http.StatusInternalServerError, http.StatusInternalServerError,
+		http.StatusInternalServerError, http.StatusInternalServerError, http.StatusInternalServerError,
+		http.StatusInternalServerError, http.StatusInternalServerError, http.StatusInternalServerError,
+	)
+	s.config.MaxFailures = 2
+
+	items := make([]models.ScrapedItem, 5)
+	for i := range items {
+		items[i] = models.ScrapedItem{ID: 100 + i, Title: "recall " + strconv.Itoa(i)}
+	}
+	d := NewDispatcher(slog.New(slog.NewTextHandler(io.Discard, nil)), s)
+	if err := d.Dispatch(context.Background(), items); err == nil {
+		t.Error("want an error when every delivery failed")
+	}
+
+	if !store.disabled[7] {
+		t.Error("webhook not disabled")
+	}
+	// each failed recall is tried three times, and nothing is sent to the
+	// webhook once it is disabled
+	if len(rc.bodies) != 6 || len(store.attempts) != 6 {
+		t.Errorf("received %d requests and recorded %d attempts, want 6", len(rc.bodies), len(store.attempts))
+	}
+}
@@ -0,0 +1,476 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/big"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/paluras/product-recall-system/internal/i18n"
+	"github.com/paluras/product-recall-system/internal/models"
+	"github.com/paluras/product-recall-system/internal/utils"
+)
+
+// pushTTL is how long a push service keeps a message for a browser that is
+// offline.
+const pushTTL = 24 * time.Hour
+
+type WebPushConfig struct {
+	// PrivateKey is the VAPID key: a P-256 scalar in unpadded base64url.
+	PrivateKey string
+	// Subject is the mailto: or https: contact push services may use to
+	// reach us.
+	Subject string
+	// BaseURL is the address of the website, without a trailing slash.
+	BaseURL string
+	// Timeout bounds each request.
+	Timeout time.Duration
+	// Retries is the number of attempts after the first one when the push
+	// service is unavailable or rate limits us.
+	Retries int
+	// Backoff is the wait before the first retry unless the push service
+	// names one; it doubles every retry.
+	Backoff time.Duration
+}
+
+// WebPushService sends recalls to the browsers that opted in on the home
+// page, encrypted as RFC 8291 requires and signed with VAPID (RFC 8292).
+type WebPushService struct {
+	config    WebPushConfig
+	db        *models.DB
+	logger    *slog.Logger
+	catalog   *i18n.Catalog
+	http      *http.Client
+	key       *ecdsa.PrivateKey
+	publicKey string
+}
+
+func NewWebPushService(cfg WebPushConfig, db *models.DB, logger *slog.Logger) (*WebPushService, error) {
+	if cfg.Backoff <= 0 {
+		cfg.Backoff = time.Second
+	}
+	key, err := parseVAPIDKey(cfg.PrivateKey)
+	if err != nil {
+		return nil, err
+	}
+	catalog, err := i18n.Load()
+	if err != nil {
+		return nil, err
+	}
+	return &WebPushService{
+		config:    cfg,
+		db:        db,
+		logger:    logger,
+		catalog:   catalog,
+		http:      newPushClient(cfg.Timeout),
+		key:       key,
+		publicKey: b64.EncodeToString(marshalPublicKey(&key.PublicKey)),
+	}, nil
+}
+
+var b64 = base64.RawURLEncoding
+
+// GenerateVAPIDKeys returns a new private key and the public key browsers
+// subscribe with, both in unpadded base64url.
+func GenerateVAPIDKeys() (string, string, error) {
+	key, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		return "", "", err
+	}
+	return b64.EncodeToString(key.Bytes()), b64.EncodeToString(key.PublicKey().Bytes()), nil
+}
+
+// VAPIDPublicKey returns the public key of a private VAPID key.
+func VAPIDPublicKey(privateKey string) (string, error) {
+	key, err := parseVAPIDKey(privateKey)
+	if err != nil {
+		return "", err
+	}
+	return b64.EncodeToString(marshalPublicKey(&key.PublicKey)), nil
+}
+
+func parseVAPIDKey(s string) (*ecdsa.PrivateKey, error) {
+	raw, err := b64.DecodeString(s)
+	if err != nil {
+		return nil, errors.New("VAPID private key is not base64url")
+	}
+	key, err := ecdh.P256().NewPrivateKey(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid VAPID private key: %w", err)
+	}
+	// uncompressed point: 0x04, X, Y
+	pub := key.PublicKey().Bytes()
+	return &ecdsa.PrivateKey{
+		PublicKey: ecdsa.PublicKey{
+			Curve: elliptic.P256(),
+			X:     new(big.Int).SetBytes(pub[1:33]),
+			Y:     new(big.Int).SetBytes(pub[33:]),
+		},
+		D: new(big.Int).SetBytes(raw),
+	}, nil
+}
+
+func marshalPublicKey(pub *ecdsa.PublicKey) []byte {
+	b := make([]byte, 65)
+	b[0] = 4
+	pub.X.FillBytes(b[1:33])
+	pub.Y.FillBytes(b[33:])
+	return b
+}
+
+// PushError is a push service's refusal of a message.
+type PushError struct {
+	StatusCode int
+	Status     string
+	// RetryAfter is how long the push service asked us to wait.
+	RetryAfter time.Duration
+}
+
+func (e *PushError) Error() string {
+	return "push service responded " + e.Status
+}
+
+type pushPayload struct {
+	Title string `json:"title"`
+	Body  string `json:"body"`
+	URL   string `json:"url"`
+	Tag   string `json:"tag"`
+}
+
+func (s *WebPushService) Name() string {
+	return models.ChannelWebPush
+}
+
+// Recipients returns the browsers that opted in.
+func (s *WebPushService) Recipients(ctx context.Context, _ bool) ([]Recipient, error) {
+	subscriptions, err := s.db.GetPushSubscriptions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	recipients := make([]Recipient, len(subscriptions))
+	for i, sub := range subscriptions {
+		recipients[i] = Recipient{ID: strconv.Itoa(sub.ChannelID), Locale: sub.Locale, Data: sub}
+	}
+	return recipients, nil
+}
+
+func (s *WebPushService) Delivered(ctx context.Context, r Recipient, itemID int) (bool, error) {
+	return s.db.ChannelDelivered(ctx, r.Data.(models.PushSubscription).ChannelID, itemID)
+}
+
+// Render builds one notification per item, as the JSON the service worker
+// reads.
+func (s *WebPushService) Render(r Recipient, items []models.ScrapedItem) ([]*Message, error) {
+	messages := make([]*Message, len(items))
+	for i, item := range items {
+		payload, err := json.Marshal(pushPayload{
+			Title: s.catalog.T(r.Locale, "push.title"),
+			Body:  item.Title,
+			URL:   s.config.BaseURL + "/recalls/" + strconv.Itoa(item.ID),
+			Tag:   "recall-" + strconv.Itoa(item.ID),
+		})
+		if err != nil {
+			return nil, err
+		}
+		messages[i] = &Message{
+			To:    r.Data.(models.PushSubscription).Endpoint,
+			Text:  string(payload),
+			Items: []models.ScrapedItem{item},
+		}
+	}
+	return messages, nil
+}
+
+// Record removes subscriptions the push service no longer knows.
+func (s *WebPushService) Record(ctx context.Context, r Recipient, m *Message, sendErr error) (bool, error) {
+	return recordChannelDelivery(ctx, s.db, r.Data.(models.PushSubscription).ChannelID, m, sendErr, subscriptionGone(sendErr))
+}
+
+// subscriptionGone reports whether the push service says the subscription
+// expired or was cancelled, or its endpoint is not one we post to.
+func subscriptionGone(err error) bool {
+	var pushErr *PushError
+	return errors.Is(err, ErrEndpointNotAllowed) || errors.As(err, &pushErr) &&
+		(pushErr.StatusCode == http.StatusNotFound || pushErr.StatusCode == http.StatusGone)
+}
+
+// ErrEndpointNotAllowed is returned for push endpoints that are not public
+// https URLs. Anyone can register an endpoint, so without the check the
+// server could be made to post to hosts on its own network.
+var ErrEndpointNotAllowed = errors.New("push endpoint must be a public https URL")
+
+// CheckPushEndpoint checks that endpoint is an https URL on the default port
+// whose host name resolves only to public addresses. IP literals are
+// refused: push services are always reached by name.
+func CheckPushEndpoint(ctx context.Context, endpoint string) error {
+	u, err := url.Parse(endpoint)
+	if err != nil || u.Scheme != "https" || u.Hostname() == "" || (u.Port() != "" && u.Port() != "443") {
+		return ErrEndpointNotAllowed
+	}
+	host := u.Hostname()
+	if net.ParseIP(host) != nil {
+		return ErrEndpointNotAllowed
+	}
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrEndpointNotAllowed, err)
+	}
+	for _, addr := range addrs {
+		if !publicIP(addr.IP) {
+			return ErrEndpointNotAllowed
+		}
+	}
+	return nil
+}
+
+// sharedAddressSpace is the carrier-grade NAT range of RFC 6598, which
+// net.IP does not count as private.
+var sharedAddressSpace = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+func publicIP(ip net.IP) bool {
+	return ip.IsGlobalUnicast() && !ip.IsPrivate() && !sharedAddressSpace.Contains(ip)
+}
+
+// publicOnly refuses connections to addresses that are not public, so a
+// push endpoint whose name resolves differently at send time than when it
+// was checked still cannot reach the local network.
+func publicOnly(_, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if ip := net.ParseIP(host); ip == nil || !publicIP(ip) {
+		return fmt.Errorf("%w: %s is not a public address", ErrEndpointNotAllowed, host)
+	}
+	return nil
+}
+
+// newPushClient returns an HTTP client that only connects to public
+// addresses. It does not use a proxy: the connection has to go to the push
+// service itself for its address to be checked.
+func newPushClient(timeout time.Duration) *http.Client {
+	client := utils.CreateHTTPClient(timeout)
+	transport := client.Transport.(*http.Transport)
+	transport.Proxy = nil
+	transport.DialContext = (&net.Dialer{Timeout: 30 * time.Second, Control: publicOnly}).DialContext
+	return client
+}
+
+func (s *WebPushService) Send(ctx context.Context, r Recipient, m *Message) error {
+	return s.push(ctx, r.Data.(models.PushSubscription), []byte(m.Text))
+}
+
+// push encrypts payload for the subscription and posts it to its push
+// service, retrying network errors, 5xx and 429 responses.
+func (s *WebPushService) push(ctx context.Context, sub models.PushSubscription, payload []byte) error {
+	body, err := encryptPush(sub, payload)
+	if err != nil {
+		return err
+	}
+
+	backoff := s.config.Backoff
+	for attempt := 0; ; attempt++ {
+		err := s.post(ctx, sub.Endpoint, body)
+		if err == nil || errors.Is(err, ErrEndpointNotAllowed) {
+			return err
+		}
+
+		wait := backoff
+		var pushErr *PushError
+		if errors.As(err, &pushErr) {
+			if pushErr.StatusCode != http.StatusTooManyRequests && pushErr.StatusCode < 500 {
+				return err
+			}
+			if pushErr.RetryAfter > 0 {
+				wait = pushErr.RetryAfter
+			}
+		}
+		if attempt >= s.config.Retries || ctx.Err() != nil {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+		backoff *= 2
+	}
+}
+
+func (s *WebPushService) post(ctx context.Context, endpoint string, body []byte) error {
+	token, err := s.vapidToken(endpoint)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("Content-Encoding", "aes128gcm")
+	req.Header.Set("TTL", strconv.Itoa(int(pushTTL/time.Second)))
+	req.Header.Set("Urgency", "high")
+	req.Header.Set("Authorization", "vapid t="+token+", k="+s.publicKey)
+
+	resp, err := s.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		pushErr := &PushError{StatusCode: resp.StatusCode, Status: resp.Status}
+		if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+			pushErr.RetryAfter = time.Duration(seconds) * time.Second
+		}
+		return pushErr
+	}
+	return nil
+}
+
+// vapidToken signs the ES256 JWT that identifies us to the push service of
+// endpoint.
+func (s *WebPushService) vapidToken(endpoint string) (string, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return "", err
+	}
+	subject := s.config.Subject
+	if subject == "" {
+		subject = s.config.BaseURL
+	}
+	claims, err := json.Marshal(map[string]any{
+		"aud": u.Scheme + "://" + u.Host,
+		"exp": time.Now().Add(12 * time.Hour).Unix(),
+		"sub": subject,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	unsigned := b64.EncodeToString([]byte(`{"typ":"JWT","alg":"ES256"}`)) + "." + b64.EncodeToString(claims)
+	digest := sha256.Sum256([]byte(unsigned))
+	r, sig, err := ecdsa.Sign(rand.Reader, s.key, digest[:])
+	if err != nil {
+		return "", err
+	}
+	signature := make([]byte, 64)
+	r.FillBytes(signature[:32])
+	sig.FillBytes(signature[32:])
+	return unsigned + "." + b64.EncodeToString(signature), nil
+}
+
+// pushRecordSize is the record size announced in the header; the payload
+// always fits in one record.
+const pushRecordSize = 4096
+
+// encryptPush encrypts payload for the browser's keys with the aes128gcm
+// content coding of RFC 8188, keyed as RFC 8291 describes.
+func encryptPush(sub models.PushSubscription, payload []byte) ([]byte, error) {
+	asPrivate, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	return encryptPushWith(sub, payload, asPrivate, salt)
+}
+
+// encryptPushWith encrypts with the given ephemeral key and salt, which
+// must never be reused.
+func encryptPushWith(sub models.PushSubscription, payload []byte, asPrivate *ecdh.PrivateKey, salt []byte) ([]byte, error) {
+	uaPublicBytes, err := b64.DecodeString(sub.P256dh)
+	if err != nil {
+		return nil, errors.New("subscription key is not base64url")
+	}
+	uaPublic, err := ecdh.P256().NewPublicKey(uaPublicBytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid subscription key: %w", err)
+	}
+	authSecret, err := b64.DecodeString(sub.Auth)
+	if err != nil || len(authSecret) != 16 {
+		return nil, errors.New("invalid subscription auth secret")
+	}
+	// a message must fit in one record with its delimiter and the tag
+	if len(payload) > pushRecordSize-17 {
+		return nil, errors.New("push payload is too large")
+	}
+
+	asPublic := asPrivate.PublicKey().Bytes()
+	shared, err := asPrivate.ECDH(uaPublic)
+	if err != nil {
+		return nil, err
+	}
+
+	cek, nonce := pushKeys(shared, authSecret, uaPublicBytes, asPublic, salt)
+	block, err := aes.NewCipher(cek)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	header := make([]byte, 0, 16+4+1+len(asPublic))
+	header = append(header, salt...)
+	header = binary.BigEndian.AppendUint32(header, pushRecordSize)
+	header = append(header, byte(len(asPublic)))
+	header = append(header, asPublic...)
+
+	// 0x02 marks the last record, with no padding after it
+	plaintext := append(append([]byte{}, payload...), 2)
+	return gcm.Seal(header, nonce, plaintext, nil), nil
+}
+
+// pushKeys derives the content encryption key and nonce of a message from
+// the ECDH secret shared with the browser, its auth secret and the salt.
+func pushKeys(shared, authSecret, uaPublic, asPublic, salt []byte) ([]byte, []byte) {
+	keyInfo := append([]byte("WebPush: info\x00"), uaPublic...)
+	keyInfo = append(keyInfo, asPublic...)
+	ikm := hkdfExpand(hkdfExtract(authSecret, shared), keyInfo, 32)
+
+	prk := hkdfExtract(salt, ikm)
+	cek := hkdfExpand(prk, []byte("Content-Encoding: aes128gcm\x00"), 16)
+	nonce := hkdfExpand(prk, []byte("Content-Encoding: nonce\x00"), 12)
+	return cek, nonce
+}
+
+func hkdfExtract(salt, ikm []byte) []byte {
+	mac := hmac.New(sha256.New, salt)
+	mac.Write(ikm)
+	return mac.Sum(nil)
+}
+
+// hkdfExpand is HKDF-Expand for outputs of at most one SHA-256 block, which
+// is all Web Push needs.
+func hkdfExpand(prk, info []byte, length int) []byte {
+	mac := hmac.New(sha256.New, prk)
+	mac.Write(info)
+	mac.Write([]byte{1})
+	return mac.Sum(nil)[:length]
+}
@@ -0,0 +1,323 @@
+package notify
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/paluras/product-recall-system/internal/models"
+	"github.com/paluras/product-recall-system/internal/utils"
+)
+
+// The example of RFC 8291, section 5.
+const (
+	rfcPlaintext = "When I grow up, I want to be a watermelon"
+	rfcASPrivate = "yfWPiYE-n46HLnH0KqZOF1fJJU3MYrct3AELtAQ-oRw"
+	rfcUAPublic  = "BCVxsr7N_eNgVRqvHtD0zTZsEc6-VV-JvLexhqUzORcxaOzi6-AYWXvTBHm4bjyPjs7Vd8pZGH6SRpkNtoIAiw4"
+	rfcSalt      = "DGv6ra1nlYgDCS1FRnbzlw"
+	rfcAuth      = "BTBZMqHH6r4Tts7J_aSIgg"
+	rfcBody      = "DGv6ra1nlYgDCS1FRnbzlwAAEABBBP4z9KsN6nGRTbVYI_c7VJSPQTBtkgcy27mlmlMoZIIgDll6e3vCYLocInmYWAmS6TlzAC8wEqKK6PBru3jl7A_yl95bQpu6cVPTpK4Mqgkf1CXztLVBSt2Ks3oZwbuwXPXLWyouBWLVWGNWQexSgSxsj_Qulcy4a-fN"
+)
+
+func decode(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := b64.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestEncryptPushRFCExample(t *testing.T) {
+	asPrivate, err := ecdh.P256().NewPrivateKey(decode(t, rfcASPrivate))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sub := models.PushSubscription{P256dh: rfcUAPublic, Auth: rfcAuth}
+
+	body, err := encryptPushWith(sub, []byte(rfcPlaintext), asPrivate, decode(t, rfcSalt))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := b64.EncodeToString(body); got != rfcBody {
+		t.Errorf("encrypted body\n got %s\nwant %s", got, rfcBody)
+	}
+}
+
+// pushService stands in for a browser vendor's push service: it checks the
+// VAPID token of each request and decrypts the message with the keys of
+// the one browser it knows.
+type pushService struct {
+	vapidKey  string
+	uaPrivate *ecdh.PrivateKey
+	auth      []byte
+	status    []int
+
+	mu       sync.Mutex
+	messages []string
+}
+
+func (p *pushService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if r.Header.Get("Content-Encoding") != "aes128gcm" || r.Header.Get("TTL") == "" {
+		http.Error(w, "missing headers", http.StatusBadRequest)
+		return
+	}
+	if err := p.checkVAPID(r); err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	body, _ := io.ReadAll(r.Body)
+	plaintext, err := p.decrypt(body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	p.messages = append(p.messages, plaintext)
+
+	status := http.StatusCreated
+	if len(p.status) > 0 {
+		status, p.status = p.status[0], p.status[1:]
+	}
+	w.WriteHeader(status)
+}
+
+func (p *pushService) checkVAPID(r *http.Request) error {
+	token, key, ok := strings.Cut(strings.TrimPrefix(r.Header.Get("Authorization"), "vapid t="), ", k=")
+	if !ok || key != p.vapidKey {
+		return errors.New("wrong VAPID key")
+	}
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return errors.New("malformed JWT")
+	}
+
+	pub, err := b64.DecodeString(key)
+	if err != nil || len(pub) != 65 {
+		return errors.New("malformed VAPID key")
+	}
+	ecdsaKey := &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(pub[1:33]), Y: new(big.Int).SetBytes(pub[33:])}
+	sig, err := b64.DecodeString(parts[2])
+	if err != nil || len(sig) != 64 {
+		return errors.New("malformed signature")
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if !ecdsa.Verify(ecdsaKey, digest[:], new(big.Int).SetBytes(sig[:32]), new(big.Int).SetBytes(sig[32:])) {
+		return errors.New("bad signature")
+	}
+
+	var claims struct {
+		Aud string `json:"aud"`
+		Exp int64  `json:"exp"`
+		Sub string `json:"sub"`
+	}
+	claimsJSON, _ := b64.DecodeString(parts[1])
+	if err := json.Unmarshal(claimsJSON, &claims); err != nil {
+		return err
+	}
+	if claims.Aud != "http://"+r.Host || claims.Exp < time.Now().Unix() || claims.Sub == "" {
+		return errors.New("bad claims")
+	}
+	return nil
+}
+
+func (p *pushService) decrypt(body []byte) (string, error) {
+	if len(body) < 21 || len(body) < 21+int(body[20]) {
+		return "", errors.New("short body")
+	}
+	salt, idlen := body[:16], int(body[20])
+	if binary.BigEndian.Uint32(body[16:20]) < 18 {
+		return "", errors.New("bad record size")
+	}
+	asPublicBytes, ciphertext := body[21:21+idlen], body[21+idlen:]
+
+	asPublic, err := ecdh.P256().NewPublicKey(asPublicBytes)
+	if err != nil {
+		return "", err
+	}
+	shared, err := p.uaPrivate.ECDH(asPublic)
+	if err != nil {
+		return "", err
+	}
+	cek, nonce := pushKeys(shared, p.auth, p.uaPrivate.PublicKey().Bytes(), asPublicBytes, salt)
+	block, err := aes.NewCipher(cek)
+	if err != nil {
+		return "", err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return "", err
+	}
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return "", err
+	}
+	if len(plaintext) == 0 || plaintext[len(plaintext)-1] != 2 {
+		return "", errors.New("missing last record delimiter")
+	}
+	return string(plaintext[:len(plaintext)-1]), nil
+}
+
+func newTestWebPush(t *testing.T, status ...int) (*WebPushService, *pushService, models.PushSubscription) {
+	t.Helper()
+	privateKey, publicKey, err := GenerateVAPIDKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := NewWebPushService(WebPushConfig{
+		PrivateKey: privateKey,
+		Subject:    "mailto:alerts@example.org",
+		BaseURL:    "https://example.org",
+		Timeout:    time.Second,
+		Retries:    2,
+		Backoff:    time.Millisecond,
+	}, nil, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the stand-in push service listens on loopback, which the real client
+	// refuses to connect to
+	s.http = utils.CreateHTTPClient(time.Second)
+
+	uaPrivate, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	auth := make([]byte, 16)
+	rand.Read(auth)
+	ps := &pushService{vapidKey: publicKey, uaPrivate: uaPrivate, auth: auth, status: status}
+	srv := httptest.NewServer(ps)
+	t.Cleanup(srv.Close)
+
+	sub := models.PushSubscription{
+		Endpoint: srv.URL + "/push/abc",
+		P256dh:   b64.EncodeToString(uaPrivate.PublicKey().Bytes()),
+		Auth:     b64.EncodeToString(auth),
+	}
+	return s, ps, sub
+}
+
+func TestPush(t *testing.T) {
+	s, ps, sub := newTestWebPush(t)
+
+	if err := s.push(context.Background(), sub, []byte(`{"title":"Recall"}`)); err != nil {
+		t.Fatal(err)
+	}
+	if len(ps.messages) != 1 || ps.messages[0] != `{"title":"Recall"}` {
+		t.Errorf("push service received %q", ps.messages)
+	}
+}
+
+func TestPushRetries(t *testing.T) {
+	s, ps, sub := newTestWebPush(t, http.StatusServiceUnavailable, http.StatusTooManyRequests)
+
+	if err := s.push(context.Background(), sub, []byte("hi")); err != nil {
+		t.Fatal(err)
+	}
+	if len(ps.messages) != 3 {
+		t.Errorf("made %d attempts, want 3", len(ps.messages))
+	}
+}
+
+func TestPushGone(t *testing.T) {
+	s, ps, sub := newTestWebPush(t, http.StatusGone)
+
+	err := s.push(context.Background(), sub, []byte("hi"))
+	if !subscriptionGone(err) {
+		t.Errorf("got %v, want an error that removes the subscription", err)
+	}
+	if len(ps.messages) != 1 {
+		t.Errorf("made %d attempts, want 1", len(ps.messages))
+	}
+}
+
+func TestVAPIDPublicKey(t *testing.T) {
+	privateKey, publicKey, err := GenerateVAPIDKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := VAPIDPublicKey(privateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != publicKey {
+		t.Errorf("VAPIDPublicKey = %s, want %s", got, publicKey)
+	}
+	if _, err := VAPIDPublicKey("not a key"); err == nil {
+		t.Error("want an error for an invalid key")
+	}
+}
+
+func TestPushLocalNetwork(t *testing.T) {
+	s, ps, sub := newTestWebPush(t, http.StatusServiceUnavailable)
+	s.http = newPushClient(time.Second)
+
+	err := s.push(context.Background(), sub, []byte("hi"))
+	if !errors.Is(err, ErrEndpointNotAllowed) || !subscriptionGone(err) {
+		t.Errorf("got %v, want ErrEndpointNotAllowed", err)
+	}
+	if len(ps.messages) != 0 {
+		t.Errorf("push service on loopback received %d messages", len(ps.messages))
+	}
+}
+
+func TestCheckPushEndpoint(t *testing.T) {
+	for _, endpoint := range []string{
+		"http://fcm.googleapis.com/fcm/send/abc",
+		"https://fcm.googleapis.com:8443/fcm/send/abc",
+		"https://127.0.0.1/push",
+		"https://10.1.2.3/push",
+		"https://[::1]/push",
+		"https://8.8.8.8/push",
+		"https://localhost/push",
+		"https:///push",
+		"not a url",
+	} {
+		if err := CheckPushEndpoint(context.Background(), endpoint); !errors.Is(err, ErrEndpointNotAllowed) {
+			t.Errorf("CheckPushEndpoint(%q) = %v, want ErrEndpointNotAllowed", endpoint, err)
+		}
+	}
+}
+
+func TestPublicIP(t *testing.T) {
+	for addr, want := range map[string]bool{
+		"8.8.8.8":         true,
+		"2001:4860::8888": true,
+		"127.0.0.1":       false,
+		"10.0.0.1":        false,
+		"172.16.5.4":      false,
+		"192.168.1.1":     false,
+		"169.254.169.254": false,
+		"100.64.0.1":      false,
+		"0.0.0.0":         false,
+		"::1":             false,
+		"fc00::1":         false,
+		"fe80::1":         false,
+		"::ffff:10.0.0.1": false,
+		"224.0.0.1":       false,
+	} {
+		if got := publicIP(net.ParseIP(addr)); got != want {
+			t.Errorf("publicIP(%s) = %v, want %v", addr, got, want)
+		}
+	}
+}
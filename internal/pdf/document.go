@@ -0,0 +1,296 @@
+package pdf
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/zlib"
+	"encoding/ascii85"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+)
+
+// maxStreamSize caps every decoded stream, so a small compressed file cannot
+// expand into gigabytes.
+const maxStreamSize = 32 << 20
+
+var (
+	ErrNotPDF = errors.New("pdf: not a PDF file")
+
+	objRx = regexp.MustCompile(`(\d+)\s+(\d+)\s+obj\b`)
+)
+
+// document holds every object of a file, indexed by object number. Objects
+// are found by scanning for "n g obj" rather than through the xref table,
+// which is often broken in files produced by scanners and office tools.
+type document struct {
+	objects map[int]any
+}
+
+func parse(data []byte) (*document, error) {
+	if !bytes.Contains(data[:min(len(data), 1024)], []byte("%PDF-")) {
+		return nil, ErrNotPDF
+	}
+
+	doc := &document{objects: map[int]any{}}
+	var objStms []stream
+
+	end := 0
+	for _, m := range objRx.FindAllSubmatchIndex(data, -1) {
+		if m[0] < end {
+			// inside the previous object, most likely stream data
+			continue
+		}
+		var num int
+		fmt.Sscan(string(data[m[2]:m[3]]), &num)
+
+		p := &parser{b: data, pos: m[1]}
+		obj, err := p.object()
+		if err != nil {
+			continue
+		}
+		if d, ok := obj.(dict); ok {
+			if raw, ok := p.streamData(d); ok {
+				s := stream{dict: d, data: raw}
+				obj = s
+				if d["Type"] == name("ObjStm") {
+					objStms = append(objStms, s)
+				}
+			}
+		}
+		// later definitions come from incremental updates and win
+		doc.objects[num] = obj
+		end = p.pos
+	}
+
+	for _, s := range objStms {
+		doc.loadObjStm(s)
+	}
+
+	if len(doc.objects) == 0 {
+		return nil, ErrNotPDF
+	}
+	return doc, nil
+}
+
+// loadObjStm adds the objects packed in an object stream.
+func (doc *document) loadObjStm(s stream) {
+	data, err := doc.decode(s)
+	if err != nil {
+		return
+	}
+	n, _ := s.dict["N"].(int)
+	first, _ := s.dict["First"].(int)
+	if n < 0 || first < 0 || first > len(data) {
+		return
+	}
+
+	header := &parser{b: data[:first]}
+	for i := 0; i < n; i++ {
+		num, err1 := header.object()
+		off, err2 := header.object()
+		if err1 != nil || err2 != nil {
+			return
+		}
+		objNum, ok1 := num.(int)
+		offset, ok2 := off.(int)
+		if !ok1 || !ok2 || offset < 0 || first+offset > len(data) {
+			return
+		}
+		if _, ok := doc.objects[objNum]; ok {
+			continue
+		}
+		p := &parser{b: data, pos: first + offset}
+		if obj, err := p.object(); err == nil {
+			doc.objects[objNum] = obj
+		}
+	}
+}
+
+// resolve follows indirect references.
+func (doc *document) resolve(v any) any {
+	for i := 0; i < 32; i++ {
+		r, ok := v.(ref)
+		if !ok {
+			return v
+		}
+		v = doc.objects[r.num]
+	}
+	return nil
+}
+
+func (doc *document) dict(v any) dict {
+	switch v := doc.resolve(v).(type) {
+	case dict:
+		return v
+	case stream:
+		return v.dict
+	}
+	return nil
+}
+
+// decode applies the stream's filters. Image filters are reported as
+// errors since they never hold text.
+func (doc *document) decode(s stream) ([]byte, error) {
+	var filters []any
+	switch f := doc.resolve(s.dict["Filter"]).(type) {
+	case name:
+		filters = []any{f}
+	case array:
+		filters = f
+	}
+
+	data := s.data
+	for _, f := range filters {
+		var err error
+		switch doc.resolve(f) {
+		case name("FlateDecode"), name("Fl"):
+			data, err = inflate(data)
+		case name("ASCIIHexDecode"), name("AHx"):
+			data, err = hexDecode(data)
+		case name("ASCII85Decode"), name("A85"):
+			data, err = a85Decode(data)
+		default:
+			err = fmt.Errorf("pdf: unsupported filter %v", f)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return data, nil
+}
+
+func inflate(data []byte) ([]byte, error) {
+	var r io.Reader
+	zr, err := zlib.NewReader(bytes.NewReader(data))
+	if err != nil {
+		// some producers write raw deflate without the zlib header
+		r = flate.NewReader(bytes.NewReader(data))
+	} else {
+		r = zr
+	}
+	out, err := io.ReadAll(io.LimitReader(r, maxStreamSize))
+	if len(out) > 0 && (errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, zlib.ErrChecksum)) {
+		// truncated streams are common; keep what was decoded
+		return out, nil
+	}
+	return out, err
+}
+
+func hexDecode(data []byte) ([]byte, error) {
+	var digits []byte
+	for _, c := range data {
+		if c == '>' {
+			break
+		}
+		if !isSpace(c) {
+			digits = append(digits, c)
+		}
+	}
+	if len(digits)%2 == 1 {
+		digits = append(digits, '0')
+	}
+	out := make([]byte, len(digits)/2)
+	_, err := hex.Decode(out, digits)
+	return out, err
+}
+
+func a85Decode(data []byte) ([]byte, error) {
+	data = bytes.TrimPrefix(bytes.TrimSpace(data), []byte("<~"))
+	if i := bytes.Index(data, []byte("~>")); i >= 0 {
+		data = data[:i]
+	}
+	out := make([]byte, 4*len(data)/5+4)
+	n, _, err := ascii85.Decode(out, data, true)
+	return out[:n], err
+}
+
+// pages returns the page dictionaries in reading order, each with the
+// resources it inherits from the page tree.
+func (doc *document) pages() []page {
+	var pages []page
+	seen := map[int]bool{}
+
+	var walk func(v any, resources dict)
+	walk = func(v any, resources dict) {
+		if r, ok := v.(ref); ok {
+			if seen[r.num] {
+				return
+			}
+			seen[r.num] = true
+		}
+		d := doc.dict(v)
+		if d == nil {
+			return
+		}
+		if res := doc.dict(d["Resources"]); res != nil {
+			resources = res
+		}
+		switch d["Type"] {
+		case name("Pages"):
+			kids, _ := doc.resolve(d["Kids"]).(array)
+			for _, kid := range kids {
+				walk(kid, resources)
+			}
+		case name("Page"):
+			pages = append(pages, page{dict: d, resources: resources})
+		}
+	}
+
+	for _, obj := range doc.objects {
+		if d := doc.dict(obj); d != nil && d["Type"] == name("Catalog") {
+			walk(d["Pages"], nil)
+			break
+		}
+	}
+	if len(pages) > 0 {
+		return pages
+	}
+
+	// no usable page tree: take the page objects in object order
+	nums := make([]int, 0, len(doc.objects))
+	for num := range doc.objects {
+		nums = append(nums, num)
+	}
+	sort.Ints(nums)
+	for _, num := range nums {
+		if d := doc.dict(doc.objects[num]); d != nil && d["Type"] == name("Page") {
+			pages = append(pages, page{dict: d, resources: doc.dict(d["Resources"])})
+		}
+	}
+	return pages
+}
+
+type page struct {
+	dict      dict
+	resources dict
+}
+
+// contents returns the decoded content streams of the page, concatenated.
+func (doc *document) contents(p page) []byte {
+	var parts []any
+	switch c := doc.resolve(p.dict["Contents"]).(type) {
+	case array:
+		parts = c
+	case stream:
+		parts = []any{c}
+	}
+
+	var buf bytes.Buffer
+	for _, part := range parts {
+		s, ok := doc.resolve(part).(stream)
+		if !ok {
+			continue
+		}
+		data, err := doc.decode(s)
+		if err != nil {
+			continue
+		}
+		buf.Write(data)
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes()
+}
@@ -0,0 +1,85 @@
+package pdf
+
+import "strconv"
+
+// winAnsi is WinAnsiEncoding, i.e. Windows-1252.
+var winAnsi = func() [256]rune {
+	var t [256]rune
+	for i := 0x20; i < 0x7f; i++ {
+		t[i] = rune(i)
+	}
+	for i := 0xa0; i <= 0xff; i++ {
+		t[i] = rune(i)
+	}
+	t['\t'], t['\n'], t['\r'] = ' ', ' ', ' '
+	for i, r := range []rune{
+		'€', 0, '‚', 'ƒ', '„', '…', '†', '‡', 'ˆ', '‰', 'Š', '‹', 'Œ', 0, 'Ž', 0,
+		0, '‘', '’', '“', '”', '•', '–', '—', '˜', '™', 'š', '›', 'œ', 0, 'ž', 'Ÿ',
+	} {
+		t[0x80+i] = r
+	}
+	return t
+}()
+
+// glyphs maps the glyph names found in /Differences arrays that are not
+// single letters. Romanian letters are listed in both the comma-below and
+// the older cedilla forms.
+var glyphs = map[string]rune{
+	"space": ' ', "exclam": '!', "quotedbl": '"', "numbersign": '#',
+	"dollar": '$', "percent": '%', "ampersand": '&', "quotesingle": '\'',
+	"parenleft": '(', "parenright": ')', "asterisk": '*', "plus": '+',
+	"comma": ',', "hyphen": '-', "period": '.', "slash": '/',
+	"zero": '0', "one": '1', "two": '2', "three": '3', "four": '4',
+	"five": '5', "six": '6', "seven": '7', "eight": '8', "nine": '9',
+	"colon": ':', "semicolon": ';', "less": '<', "equal": '=',
+	"greater": '>', "question": '?', "at": '@', "underscore": '_',
+	"bracketleft": '[', "bracketright": ']', "quoteleft": '‘',
+	"quoteright": '’', "quotedblleft": '“', "quotedblright": '”',
+	"quotedblbase": '„', "endash": '–', "emdash": '—', "bullet": '•',
+	"degree": '°', "Euro": '€',
+	"abreve": 'ă', "Abreve": 'Ă', "acircumflex": 'â', "Acircumflex": 'Â',
+	"icircumflex": 'î', "Icircumflex": 'Î',
+	"scommaaccent": 'ș', "Scommaaccent": 'Ș', "tcommaaccent": 'ț', "Tcommaaccent": 'Ț',
+	"scedilla": 'ş', "Scedilla": 'Ş', "tcedilla": 'ţ', "Tcedilla": 'Ţ',
+	"aacute": 'á', "Aacute": 'Á', "eacute": 'é', "Eacute": 'É',
+	"iacute": 'í', "Iacute": 'Í', "oacute": 'ó', "Oacute": 'Ó',
+	"odieresis": 'ö', "Odieresis": 'Ö', "ohungarumlaut": 'ő', "Ohungarumlaut": 'Ő',
+	"uacute": 'ú', "Uacute": 'Ú', "udieresis": 'ü', "Udieresis": 'Ü',
+	"uhungarumlaut": 'ű', "Uhungarumlaut": 'Ű',
+}
+
+// applyDifferences returns base with the codes listed in a /Differences
+// array remapped.
+func applyDifferences(base *[256]rune, diffs array) *[256]rune {
+	t := *base
+	code := 0
+	for _, d := range diffs {
+		switch d := d.(type) {
+		case int:
+			code = d
+		case name:
+			if code >= 0 && code < 256 {
+				if r := glyphRune(string(d)); r != 0 {
+					t[code] = r
+				}
+			}
+			code++
+		}
+	}
+	return &t
+}
+
+func glyphRune(g string) rune {
+	if len(g) == 1 {
+		return rune(g[0])
+	}
+	if r, ok := glyphs[g]; ok {
+		return r
+	}
+	if len(g) == 7 && g[:3] == "uni" {
+		if v, err := strconv.ParseUint(g[3:], 16, 32); err == nil {
+			return rune(v)
+		}
+	}
+	return 0
+}
@@ -0,0 +1,341 @@
+package pdf
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strconv"
+)
+
+// The PDF object model, as far as text extraction needs it.
+type (
+	name    string
+	keyword string
+	dict    map[name]any
+	array   []any
+	ref     struct{ num, gen int }
+	stream  struct {
+		dict dict
+		data []byte
+	}
+)
+
+// maxNesting bounds arrays and dictionaries inside each other, so a crafted
+// file cannot recurse the parser off the stack.
+const maxNesting = 64
+
+var errSyntax = errors.New("pdf: syntax error")
+
+// parser reads PDF objects from b. The same tokenizer serves the file body,
+// content streams and CMaps.
+type parser struct {
+	b     []byte
+	pos   int
+	depth int
+}
+
+func isSpace(c byte) bool {
+	switch c {
+	case 0, '\t', '\n', '\f', '\r', ' ':
+		return true
+	}
+	return false
+}
+
+func isDelim(c byte) bool {
+	switch c {
+	case '(', ')', '<', '>', '[', ']', '{', '}', '/', '%':
+		return true
+	}
+	return false
+}
+
+func (p *parser) skipSpace() {
+	for p.pos < len(p.b) {
+		c := p.b[p.pos]
+		switch {
+		case isSpace(c):
+			p.pos++
+		case c == '%':
+			for p.pos < len(p.b) && p.b[p.pos] != '\n' && p.b[p.pos] != '\r' {
+				p.pos++
+			}
+		default:
+			return
+		}
+	}
+}
+
+// object reads the next object. Operators and other bare words come back
+// as keywords; io.EOF is returned at the end of the input.
+func (p *parser) object() (any, error) {
+	p.skipSpace()
+	if p.pos >= len(p.b) {
+		return nil, io.EOF
+	}
+
+	switch c := p.b[p.pos]; {
+	case c == '/':
+		return p.name(), nil
+	case c == '(':
+		return p.literal(), nil
+	case c == '<':
+		if p.pos+1 < len(p.b) && p.b[p.pos+1] == '<' {
+			if p.depth >= maxNesting {
+				return nil, errSyntax
+			}
+			p.pos += 2
+			p.depth++
+			defer func() { p.depth-- }()
+			return p.dict()
+		}
+		return p.hex(), nil
+	case c == '[':
+		if p.depth >= maxNesting {
+			return nil, errSyntax
+		}
+		p.pos++
+		p.depth++
+		defer func() { p.depth-- }()
+		return p.array()
+	case c == ']' || c == '>' || c == ')' || c == '{' || c == '}':
+		p.pos++
+		if c == '>' && p.pos < len(p.b) && p.b[p.pos] == '>' {
+			p.pos++
+			return keyword(">>"), nil
+		}
+		return keyword([]byte{c}), nil
+	case c == '+' || c == '-' || c == '.' || (c >= '0' && c <= '9'):
+		return p.number(), nil
+	}
+
+	start := p.pos
+	for p.pos < len(p.b) && !isSpace(p.b[p.pos]) && !isDelim(p.b[p.pos]) {
+		p.pos++
+	}
+	switch kw := string(p.b[start:p.pos]); kw {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	case "null":
+		return nil, nil
+	default:
+		return keyword(kw), nil
+	}
+}
+
+func (p *parser) name() name {
+	p.pos++
+	var b []byte
+	for p.pos < len(p.b) && !isSpace(p.b[p.pos]) && !isDelim(p.b[p.pos]) {
+		c := p.b[p.pos]
+		if c == '#' && p.pos+2 < len(p.b) {
+			if v, err := strconv.ParseUint(string(p.b[p.pos+1:p.pos+3]), 16, 8); err == nil {
+				b = append(b, byte(v))
+				p.pos += 3
+				continue
+			}
+		}
+		b = append(b, c)
+		p.pos++
+	}
+	return name(b)
+}
+
+func (p *parser) literal() string {
+	p.pos++
+	var b []byte
+	depth := 1
+	for p.pos < len(p.b) {
+		c := p.b[p.pos]
+		p.pos++
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return string(b)
+			}
+		case '\\':
+			if p.pos >= len(p.b) {
+				return string(b)
+			}
+			c = p.b[p.pos]
+			p.pos++
+			switch c {
+			case 'n':
+				c = '\n'
+			case 'r':
+				c = '\r'
+			case 't':
+				c = '\t'
+			case 'b':
+				c = '\b'
+			case 'f':
+				c = '\f'
+			case '\r':
+				if p.pos < len(p.b) && p.b[p.pos] == '\n' {
+					p.pos++
+				}
+				continue
+			case '\n':
+				continue
+			default:
+				if c >= '0' && c <= '7' {
+					v := int(c - '0')
+					for i := 0; i < 2 && p.pos < len(p.b) && p.b[p.pos] >= '0' && p.b[p.pos] <= '7'; i++ {
+						v = v*8 + int(p.b[p.pos]-'0')
+						p.pos++
+					}
+					c = byte(v)
+				}
+			}
+		}
+		b = append(b, c)
+	}
+	return string(b)
+}
+
+func (p *parser) hex() string {
+	p.pos++
+	var digits []byte
+	for p.pos < len(p.b) && p.b[p.pos] != '>' {
+		if c := p.b[p.pos]; !isSpace(c) {
+			digits = append(digits, c)
+		}
+		p.pos++
+	}
+	p.pos++
+	if len(digits)%2 == 1 {
+		digits = append(digits, '0')
+	}
+	b := make([]byte, len(digits)/2)
+	for i := range b {
+		v, _ := strconv.ParseUint(string(digits[2*i:2*i+2]), 16, 8)
+		b[i] = byte(v)
+	}
+	return string(b)
+}
+
+func (p *parser) dict() (dict, error) {
+	d := dict{}
+	for {
+		p.skipSpace()
+		if p.pos+1 < len(p.b) && p.b[p.pos] == '>' && p.b[p.pos+1] == '>' {
+			p.pos += 2
+			return d, nil
+		}
+		k, err := p.object()
+		if err != nil {
+			return nil, err
+		}
+		key, ok := k.(name)
+		if !ok {
+			return nil, errSyntax
+		}
+		v, err := p.object()
+		if err != nil {
+			return nil, err
+		}
+		d[key] = v
+	}
+}
+
+func (p *parser) array() (array, error) {
+	var a array
+	for {
+		p.skipSpace()
+		if p.pos < len(p.b) && p.b[p.pos] == ']' {
+			p.pos++
+			return a, nil
+		}
+		v, err := p.object()
+		if err != nil {
+			return nil, err
+		}
+		a = append(a, v)
+	}
+}
+
+// number reads an integer or real. An integer followed by another integer
+// and R is an indirect reference.
+func (p *parser) number() any {
+	start := p.pos
+	p.pos++
+	for p.pos < len(p.b) && (p.b[p.pos] == '.' || (p.b[p.pos] >= '0' && p.b[p.pos] <= '9')) {
+		p.pos++
+	}
+	s := string(p.b[start:p.pos])
+
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		f, _ := strconv.ParseFloat(s, 64)
+		return f
+	}
+
+	save := p.pos
+	p.skipSpace()
+	genStart := p.pos
+	for p.pos < len(p.b) && p.b[p.pos] >= '0' && p.b[p.pos] <= '9' {
+		p.pos++
+	}
+	if p.pos > genStart {
+		gen, _ := strconv.Atoi(string(p.b[genStart:p.pos]))
+		p.skipSpace()
+		if p.pos < len(p.b) && p.b[p.pos] == 'R' && (p.pos+1 == len(p.b) || isSpace(p.b[p.pos+1]) || isDelim(p.b[p.pos+1])) {
+			p.pos++
+			return ref{n, gen}
+		}
+	}
+	p.pos = save
+	return n
+}
+
+// streamData returns the raw bytes of the stream whose dictionary ends at
+// the current position, or false if no stream follows.
+func (p *parser) streamData(d dict) ([]byte, bool) {
+	p.skipSpace()
+	if !bytes.HasPrefix(p.b[p.pos:], []byte("stream")) {
+		return nil, false
+	}
+	p.pos += len("stream")
+	if p.pos < len(p.b) && p.b[p.pos] == '\r' {
+		p.pos++
+	}
+	if p.pos < len(p.b) && p.b[p.pos] == '\n' {
+		p.pos++
+	}
+	start := p.pos
+
+	// trust /Length only when endstream is where it says; it is often an
+	// indirect object or simply wrong
+	if n, ok := d["Length"].(int); ok && n >= 0 && start+n <= len(p.b) {
+		rest := bytes.TrimLeft(p.b[start+n:], "\r\n \t")
+		if bytes.HasPrefix(rest, []byte("endstream")) {
+			p.pos = start + n
+			return p.b[start:p.pos], true
+		}
+	}
+
+	end := bytes.Index(p.b[start:], []byte("endstream"))
+	if end < 0 {
+		p.pos = len(p.b)
+		return p.b[start:], true
+	}
+	p.pos = start + end
+	return bytes.TrimRight(p.b[start:p.pos], "\r\n"), true
+}
+
+// skipInlineImage moves past the data of an inline image, which starts
+// after the ID operator and is not made of PDF tokens.
+func (p *parser) skipInlineImage() {
+	for i := p.pos + 1; i+2 <= len(p.b); i++ {
+		if p.b[i] == 'E' && p.b[i+1] == 'I' && isSpace(p.b[i-1]) && (i+2 == len(p.b) || isSpace(p.b[i+2])) {
+			p.pos = i + 2
+			return
+		}
+	}
+	p.pos = len(p.b)
+}
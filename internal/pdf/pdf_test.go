@@ -0,0 +1,132 @@
+package pdf
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// build returns a PDF holding objs as objects 1, 2, ... in order.
+func build(objs ...string) []byte {
+	var b strings.Builder
+	b.WriteString("%PDF-1.7\n")
+	for i, obj := range objs {
+		fmt.Fprintf(&b, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+	b.WriteString("%%EOF\n")
+	return []byte(b.String())
+}
+
+func streamObj(dict, data string) string {
+	return fmt.Sprintf("<< %s /Length %d >>\nstream\n%s\nendstream", dict, len(data), data)
+}
+
+// objStm packs objs into an object stream, numbered from num, with a header
+// as given, or a correct one if header is empty.
+func objStm(num int, header string, first int, objs ...string) string {
+	var h, body strings.Builder
+	for i, obj := range objs {
+		fmt.Fprintf(&h, "%d %d ", num+i, body.Len())
+		body.WriteString(obj + " ")
+	}
+	if header == "" {
+		header = h.String()
+		first = len(header)
+	}
+	return streamObj(fmt.Sprintf("/Type /ObjStm /N %d /First %d", len(objs), first), header+body.String())
+}
+
+const content = "BT /F1 12 Tf 72 720 Td (Rechemare produs) Tj 0 -14 Td [(lot) -300 (1234)] TJ ET"
+
+func TestExtractText(t *testing.T) {
+	data := build(
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 2 0 R /Resources << /Font << /F1 5 0 R >> >> /Contents 4 0 R >>",
+		streamObj("", content),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>",
+	)
+	text, err := ExtractText(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "Rechemare produs\nlot 1234"; text != want {
+		t.Errorf("got %q, want %q", text, want)
+	}
+}
+
+func TestExtractTextObjStm(t *testing.T) {
+	data := build(
+		"<< /Type /Catalog /Pages 4 0 R >>",
+		streamObj("", "BT (ObjStm) Tj ET"),
+		objStm(4, "", 0,
+			"<< /Type /Pages /Kids [5 0 R] /Count 1 >>",
+			"<< /Type /Page /Parent 4 0 R /Contents 2 0 R >>",
+		),
+	)
+	text, err := ExtractText(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text != "ObjStm" {
+		t.Errorf("got %q, want %q", text, "ObjStm")
+	}
+}
+
+func TestNotPDF(t *testing.T) {
+	for _, data := range []string{"", "<html></html>", "%PDF-1.4\nno objects"} {
+		if _, err := ExtractText([]byte(data)); !errors.Is(err, ErrNotPDF) {
+			t.Errorf("ExtractText(%q): got %v, want ErrNotPDF", data, err)
+		}
+	}
+}
+
+// The malformed files must be rejected by the parser itself, without
+// relying on ExtractText recovering, so these call parse.
+
+func TestMalformedObjStm(t *testing.T) {
+	for _, tt := range []struct {
+		name   string
+		header string
+		first  int
+	}{
+		{"negative First", "7 0 ", -5},
+		{"negative offset", "7 -40 ", 6},
+		{"First past the end", "7 0 ", 1 << 20},
+		{"offset past the end", "7 1000 ", 7},
+	} {
+		data := build(objStm(7, tt.header, tt.first, "<< /Type /Page >>"))
+		if _, err := parse(data); err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+		}
+	}
+
+	data := build(streamObj("/Type /ObjStm /N -1 /First 0", "7 0 (x)"))
+	if _, err := parse(data); err != nil {
+		t.Errorf("negative N: %v", err)
+	}
+}
+
+func TestDeepNesting(t *testing.T) {
+	deep := strings.Repeat("[", 1<<20) + strings.Repeat("<<", 1<<20)
+
+	p := &parser{b: []byte(deep)}
+	if _, err := p.object(); err != errSyntax {
+		t.Errorf("got %v, want errSyntax", err)
+	}
+
+	shallow := strings.Repeat("[", maxNesting) + strings.Repeat("]", maxNesting)
+	p = &parser{b: []byte(shallow)}
+	if _, err := p.object(); err != nil {
+		t.Errorf("%d nested arrays: %v", maxNesting, err)
+	}
+
+	data := build(
+		"<< /Type /Page /Contents 2 0 R >>",
+		streamObj("", "BT "+deep+" (after) Tj ET"),
+	)
+	if _, err := ExtractText(data); err != nil {
+		t.Errorf("deep content stream: %v", err)
+	}
+}
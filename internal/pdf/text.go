@@ -0,0 +1,395 @@
+// Package pdf extracts the text layer of PDF files. It understands enough
+// of the format for the notices authorities publish: compressed and
+// object streams, ToUnicode maps and the standard Latin encodings. Scanned
+// documents have no text layer and yield an empty string.
+package pdf
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxFormDepth bounds form XObjects drawing other forms.
+const maxFormDepth = 8
+
+// ExtractText returns the text of every page of a PDF, one line per text
+// line, pages separated by blank lines.
+func ExtractText(data []byte) (text string, err error) {
+	// the files come from the internet; a bug tripped by one of them must
+	// not take the scraper down with it
+	defer func() {
+		if r := recover(); r != nil {
+			text, err = "", fmt.Errorf("pdf: malformed file: %v", r)
+		}
+	}()
+
+	doc, err := parse(data)
+	if err != nil {
+		return "", err
+	}
+
+	var pages []string
+	for _, p := range doc.pages() {
+		w := &textWriter{}
+		doc.run(w, doc.contents(p), p.resources, 0)
+		if text := w.String(); text != "" {
+			pages = append(pages, text)
+		}
+	}
+	return strings.Join(pages, "\n\n"), nil
+}
+
+// textWriter collects decoded text, tidying whitespace as it goes.
+type textWriter struct {
+	lines []string
+	line  strings.Builder
+}
+
+func (w *textWriter) text(s string) {
+	w.line.WriteString(s)
+}
+
+func (w *textWriter) space() {
+	if s := w.line.String(); s != "" && !strings.HasSuffix(s, " ") {
+		w.line.WriteByte(' ')
+	}
+}
+
+func (w *textWriter) newline() {
+	if s := strings.Join(strings.Fields(w.line.String()), " "); s != "" {
+		w.lines = append(w.lines, s)
+	}
+	w.line.Reset()
+}
+
+func (w *textWriter) String() string {
+	w.newline()
+	return strings.Join(w.lines, "\n")
+}
+
+// run interprets a content stream, writing out the strings it shows.
+func (doc *document) run(w *textWriter, content []byte, resources dict, depth int) {
+	fonts := map[name]*font{}
+	fontDict := doc.dict(resources["Font"])
+	xobjects := doc.dict(resources["XObject"])
+
+	var (
+		current  *font
+		lastY    float64
+		operands []any
+	)
+	p := &parser{b: content}
+	for {
+		obj, err := p.object()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			// skip the damaged token and carry on; partial text beats none
+			p.pos++
+			operands = operands[:0]
+			continue
+		}
+		op, ok := obj.(keyword)
+		if !ok {
+			operands = append(operands, obj)
+			continue
+		}
+
+		switch op {
+		case "Tf":
+			if len(operands) >= 2 {
+				if n, ok := operands[0].(name); ok {
+					f, ok := fonts[n]
+					if !ok {
+						f = doc.font(fontDict[n])
+						fonts[n] = f
+					}
+					current = f
+				}
+			}
+		case "Tj":
+			if len(operands) >= 1 {
+				w.text(current.decode(operands[0]))
+			}
+		case "'", "\"":
+			w.newline()
+			if len(operands) >= 1 {
+				w.text(current.decode(operands[len(operands)-1]))
+			}
+		case "TJ":
+			if len(operands) >= 1 {
+				items, _ := operands[0].(array)
+				for _, item := range items {
+					// a large negative adjustment is how many producers
+					// write a space between words
+					if n, ok := number(item); ok {
+						if n < -200 {
+							w.space()
+						}
+						continue
+					}
+					w.text(current.decode(item))
+				}
+			}
+		case "Td", "TD":
+			if len(operands) >= 2 {
+				if ty, _ := number(operands[1]); ty != 0 {
+					w.newline()
+				} else {
+					w.space()
+				}
+			}
+		case "Tm":
+			if len(operands) >= 6 {
+				y, _ := number(operands[5])
+				if y != lastY {
+					w.newline()
+				} else {
+					w.space()
+				}
+				lastY = y
+			}
+		case "T*", "ET":
+			w.newline()
+		case "ID":
+			p.skipInlineImage()
+		case "Do":
+			if len(operands) >= 1 && depth < maxFormDepth {
+				if n, ok := operands[0].(name); ok {
+					if s, ok := doc.resolve(xobjects[n]).(stream); ok && s.dict["Subtype"] == name("Form") {
+						if data, err := doc.decode(s); err == nil {
+							res := doc.dict(s.dict["Resources"])
+							if res == nil {
+								res = resources
+							}
+							doc.run(w, data, res, depth+1)
+						}
+					}
+				}
+			}
+		}
+		operands = operands[:0]
+	}
+}
+
+func number(v any) (float64, bool) {
+	switch v := v.(type) {
+	case int:
+		return float64(v), true
+	case float64:
+		return v, true
+	}
+	return 0, false
+}
+
+// font knows how to turn the codes of a shown string into text.
+type font struct {
+	toUnicode map[string]string
+	codeLen   []int
+	twoByte   bool
+	encoding  *[256]rune
+}
+
+func (doc *document) font(v any) *font {
+	d := doc.dict(v)
+	if d == nil {
+		return nil
+	}
+
+	f := &font{twoByte: d["Subtype"] == name("Type0")}
+	if s, ok := doc.resolve(d["ToUnicode"]).(stream); ok {
+		if data, err := doc.decode(s); err == nil {
+			f.toUnicode, f.codeLen = parseCMap(data)
+		}
+	}
+
+	// other base encodings differ from WinAnsi almost only outside ASCII,
+	// which Romanian notices embed with ToUnicode maps anyway
+	f.encoding = &winAnsi
+	if enc, ok := doc.resolve(d["Encoding"]).(dict); ok {
+		if diffs, ok := doc.resolve(enc["Differences"]).(array); ok {
+			f.encoding = applyDifferences(f.encoding, diffs)
+		}
+	}
+	return f
+}
+
+func (f *font) decode(v any) string {
+	s, ok := v.(string)
+	if !ok {
+		return ""
+	}
+	if f == nil {
+		return decodeSimple(s, &winAnsi)
+	}
+
+	if f.toUnicode != nil {
+		var b strings.Builder
+		for i := 0; i < len(s); {
+			matched := false
+			for _, n := range f.codeLen {
+				if i+n <= len(s) {
+					if u, ok := f.toUnicode[s[i:i+n]]; ok {
+						b.WriteString(u)
+						i += n
+						matched = true
+						break
+					}
+				}
+			}
+			if !matched {
+				if f.twoByte {
+					i += 2
+				} else {
+					if r := f.encoding[s[i]]; r != 0 {
+						b.WriteRune(r)
+					}
+					i++
+				}
+			}
+		}
+		return b.String()
+	}
+
+	if f.twoByte {
+		// a composite font without a ToUnicode map: the codes are glyph
+		// ids and cannot be mapped back to text
+		return ""
+	}
+	return decodeSimple(s, f.encoding)
+}
+
+func decodeSimple(s string, enc *[256]rune) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if r := enc[s[i]]; r != 0 {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// parseCMap reads the bfchar and bfrange sections of a ToUnicode CMap. It
+// returns the map and the code lengths in use, longest first.
+func parseCMap(data []byte) (map[string]string, []int) {
+	m := map[string]string{}
+	lens := map[int]bool{}
+
+	add := func(code, text string) {
+		m[code] = text
+		lens[len(code)] = true
+	}
+
+	p := &parser{b: data}
+	var operands []any
+	for {
+		obj, err := p.object()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			p.pos++
+			continue
+		}
+		op, ok := obj.(keyword)
+		if !ok {
+			operands = append(operands, obj)
+			continue
+		}
+
+		switch op {
+		case "endbfchar":
+			for i := 0; i+1 < len(operands); i += 2 {
+				src, ok1 := operands[i].(string)
+				dst, ok2 := operands[i+1].(string)
+				if ok1 && ok2 {
+					add(src, utf16BE(dst))
+				}
+			}
+		case "endbfrange":
+			for i := 0; i+2 < len(operands); i += 3 {
+				lo, ok1 := operands[i].(string)
+				hi, ok2 := operands[i+1].(string)
+				if !ok1 || !ok2 || len(lo) != len(hi) || len(lo) == 0 || len(lo) > 4 {
+					continue
+				}
+				from, to := codeValue(lo), codeValue(hi)
+				if to < from || to-from > 0xffff {
+					continue
+				}
+				switch dst := operands[i+2].(type) {
+				case string:
+					for c := from; c <= to; c++ {
+						add(codeString(c, len(lo)), utf16BE(incrementLast(dst, int(c-from))))
+					}
+				case array:
+					for j, d := range dst {
+						if s, ok := d.(string); ok && from+uint32(j) <= to {
+							add(codeString(from+uint32(j), len(lo)), utf16BE(s))
+						}
+					}
+				}
+			}
+		}
+		if strings.HasPrefix(string(op), "begin") || strings.HasPrefix(string(op), "end") {
+			operands = operands[:0]
+		}
+	}
+
+	var codeLen []int
+	for n := 4; n >= 1; n-- {
+		if lens[n] {
+			codeLen = append(codeLen, n)
+		}
+	}
+	return m, codeLen
+}
+
+func codeValue(s string) uint32 {
+	var v uint32
+	for i := 0; i < len(s); i++ {
+		v = v<<8 | uint32(s[i])
+	}
+	return v
+}
+
+func codeString(v uint32, n int) string {
+	b := make([]byte, n)
+	for i := n - 1; i >= 0; i-- {
+		b[i] = byte(v)
+		v >>= 8
+	}
+	return string(b)
+}
+
+func incrementLast(s string, n int) string {
+	if s == "" {
+		return s
+	}
+	b := []byte(s)
+	v := int(b[len(b)-1]) + n
+	b[len(b)-1] = byte(v)
+	if len(b) >= 2 {
+		b[len(b)-2] += byte(v >> 8)
+	}
+	return string(b)
+}
+
+func utf16BE(s string) string {
+	var b strings.Builder
+	for i := 0; i+1 < len(s); i += 2 {
+		r := rune(s[i])<<8 | rune(s[i+1])
+		if r >= 0xd800 && r < 0xdc00 && i+3 < len(s) {
+			r2 := rune(s[i+2])<<8 | rune(s[i+3])
+			r = (r-0xd800)<<10 + (r2 - 0xdc00) + 0x10000
+			i += 2
+		}
+		if utf8.ValidRune(r) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,53 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Bucket is a token bucket that paces calls to an API with a quota. It
+// holds up to Burst tokens and gains Rate of them a second; Wait takes one,
+// blocking until it is there.
+type Bucket struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// NewBucket returns a full bucket. A burst below one is raised to one.
+func NewBucket(rate float64, burst int) *Bucket {
+	b := float64(max(burst, 1))
+	return &Bucket{rate: rate, burst: b, tokens: b, last: time.Now()}
+}
+
+// Wait takes a token, or returns the context's error if it is done first.
+func (b *Bucket) Wait(ctx context.Context) error {
+	for {
+		wait := b.take(time.Now())
+		if wait == 0 {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+}
+
+// take takes a token if there is one, or returns how long until there is.
+func (b *Bucket) take(now time.Time) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.tokens = min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+	if b.tokens >= 1 {
+		b.tokens--
+		return 0
+	}
+	return max(time.Duration((1-b.tokens)/b.rate*float64(time.Second)), time.Millisecond)
+}
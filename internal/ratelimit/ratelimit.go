@@ -0,0 +1,101 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Limiter decides whether another attempt for key is allowed right now.
+type Limiter interface {
+	Allow(ctx context.Context, key string) (bool, error)
+}
+
+// Rule allows Limit attempts per Window. Keys that keep trying past BanAfter
+// attempts in one window are refused for BanFor. A zero BanAfter disables bans.
+type Rule struct {
+	Limit    int
+	Window   time.Duration
+	BanAfter int
+	BanFor   time.Duration
+}
+
+// Entry is the state kept for a single key.
+type Entry struct {
+	Count       int
+	WindowEnd   time.Time
+	BannedUntil time.Time
+}
+
+// Apply records one attempt in e and reports whether it is allowed.
+func (r Rule) Apply(e *Entry, now time.Time) bool {
+	if !e.BannedUntil.IsZero() {
+		if now.Before(e.BannedUntil) {
+			return false
+		}
+		e.BannedUntil = time.Time{}
+		e.Count = 0
+	}
+
+	if e.Count == 0 || now.After(e.WindowEnd) {
+		e.Count = 1
+		e.WindowEnd = now.Add(r.Window)
+		return e.Count <= r.Limit
+	}
+
+	e.Count++
+	if r.BanAfter > 0 && e.Count > r.BanAfter {
+		e.BannedUntil = now.Add(r.BanFor)
+		return false
+	}
+
+	return e.Count <= r.Limit
+}
+
+// Expired reports whether e no longer affects future attempts.
+func (e Entry) Expired(now time.Time) bool {
+	return now.After(e.WindowEnd) && now.After(e.BannedUntil)
+}
+
+// Memory keeps entries in process memory. State is lost on restart and is
+// not shared between replicas.
+type Memory struct {
+	mu      sync.Mutex
+	entries map[string]*Entry
+	rule    Rule
+}
+
+func NewMemory(rule Rule) *Memory {
+	m := &Memory{
+		entries: make(map[string]*Entry),
+		rule:    rule,
+	}
+	go m.cleanup()
+	return m
+}
+
+func (m *Memory) Allow(_ context.Context, key string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	e, ok := m.entries[key]
+	if !ok {
+		e = &Entry{}
+		m.entries[key] = e
+	}
+	return m.rule.Apply(e, time.Now()), nil
+}
+
+func (m *Memory) cleanup() {
+	ticker := time.NewTicker(time.Hour)
+	for range ticker.C {
+		m.mu.Lock()
+		now := time.Now()
+		for key, e := range m.entries {
+			if e.Expired(now) {
+				delete(m.entries, key)
+			}
+		}
+		m.mu.Unlock()
+	}
+}
@@ -0,0 +1,104 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+var start = time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+func TestRuleLimit(t *testing.T) {
+	r := Rule{Limit: 3, Window: time.Hour}
+	var e Entry
+	for i := 1; i <= 5; i++ {
+		want := i <= 3
+		if got := r.Apply(&e, start.Add(time.Duration(i)*time.Minute)); got != want {
+			t.Errorf("attempt %d allowed = %v, want %v", i, got, want)
+		}
+	}
+}
+
+func TestRuleWindowRollover(t *testing.T) {
+	r := Rule{Limit: 1, Window: time.Hour}
+	var e Entry
+	if !r.Apply(&e, start) {
+		t.Fatal("first attempt refused")
+	}
+	if r.Apply(&e, start.Add(time.Hour)) {
+		t.Error("attempt at the end of the window allowed")
+	}
+	if !r.Apply(&e, start.Add(time.Hour+time.Second)) {
+		t.Error("attempt after the window refused")
+	}
+	if want := start.Add(2*time.Hour + time.Second); !e.WindowEnd.Equal(want) {
+		t.Errorf("new window ends at %v, want %v", e.WindowEnd, want)
+	}
+}
+
+func TestRuleBan(t *testing.T) {
+	r := Rule{Limit: 1, Window: time.Hour, BanAfter: 3, BanFor: 24 * time.Hour}
+	var e Entry
+	for i := 0; i < 4; i++ {
+		r.Apply(&e, start)
+	}
+	if e.BannedUntil.IsZero() {
+		t.Fatal("not banned after four attempts")
+	}
+
+	// the ban outlasts the window
+	if r.Apply(&e, start.Add(2*time.Hour)) {
+		t.Error("banned key allowed")
+	}
+	if e.Expired(start.Add(2 * time.Hour)) {
+		t.Error("banned entry expired")
+	}
+	if !r.Apply(&e, start.Add(25*time.Hour)) {
+		t.Error("attempt after the ban refused")
+	}
+}
+
+func TestMemory(t *testing.T) {
+	m := NewMemory(Rule{Limit: 2, Window: time.Hour})
+	ctx := context.Background()
+	for i, want := range []bool{true, true, false} {
+		if got, _ := m.Allow(ctx, "a"); got != want {
+			t.Errorf("attempt %d for a allowed = %v, want %v", i+1, got, want)
+		}
+	}
+	if got, _ := m.Allow(ctx, "b"); !got {
+		t.Error("b refused because of a")
+	}
+}
+
+// mapStore is a Store in memory.
+type mapStore map[string]*Entry
+
+func (s mapStore) UpdateRateLimit(_ context.Context, key string, update func(e *Entry) bool) (bool, error) {
+	e, ok := s[key]
+	if !ok {
+		e = &Entry{}
+		s[key] = e
+	}
+	return update(e), nil
+}
+
+func TestPersistent(t *testing.T) {
+	store := mapStore{}
+	ip := NewPersistent(store, "ip", Rule{Limit: 1, Window: time.Hour})
+	email := NewPersistent(store, "email", Rule{Limit: 1, Window: time.Hour})
+	ctx := context.Background()
+
+	if got, _ := ip.Allow(ctx, "x"); !got {
+		t.Error("first attempt refused")
+	}
+	if got, _ := ip.Allow(ctx, "x"); got {
+		t.Error("second attempt allowed")
+	}
+	if got, _ := email.Allow(ctx, "x"); !got {
+		t.Error("limiters sharing a store share keys")
+	}
+	if store["ip:x"] == nil || store["email:x"] == nil {
+		t.Errorf("store keys %v, want ip:x and email:x", store)
+	}
+}
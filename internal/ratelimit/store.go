@@ -0,0 +1,35 @@
+package ratelimit
+
+import (
+	"context"
+	"time"
+)
+
+// Store persists entries so limits survive restarts and are shared by every
+// replica using the same database. UpdateRateLimit must load the entry for
+// key, pass it to update and save the result atomically.
+type Store interface {
+	UpdateRateLimit(ctx context.Context, key string, update func(e *Entry) bool) (bool, error)
+}
+
+// Persistent is a Limiter backed by a Store. Its keys are prefixed with name
+// so several limiters can share one table.
+type Persistent struct {
+	store Store
+	name  string
+	rule  Rule
+}
+
+func NewPersistent(store Store, name string, rule Rule) *Persistent {
+	return &Persistent{
+		store: store,
+		name:  name,
+		rule:  rule,
+	}
+}
+
+func (p *Persistent) Allow(ctx context.Context, key string) (bool, error) {
+	return p.store.UpdateRateLimit(ctx, p.name+":"+key, func(e *Entry) bool {
+		return p.rule.Apply(e, time.Now().UTC())
+	})
+}
@@ -0,0 +1,329 @@
+package scraper
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/paluras/product-recall-system/internal/logging"
+	"github.com/paluras/product-recall-system/internal/utils"
+)
+
+// DefaultUserAgent identifies the scraper to site operators and gives them a
+// way to reach us.
+const DefaultUserAgent = "ProduseRetraseBot/1.0 (+https://produseretrase.eu)"
+
+// maxPageSize caps HTML pages; attachments have their own limit.
+const maxPageSize = 10 << 20
+
+var (
+	ErrNotModified = errors.New("scraper: not modified since the last scrape")
+	ErrDisallowed  = errors.New("scraper: disallowed by robots.txt")
+	ErrTooLarge    = errors.New("scraper: response is too large")
+)
+
+// StatusError is returned for responses other than 200 and 304.
+type StatusError struct {
+	URL        string
+	StatusCode int
+	retryAfter time.Duration
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("scraper: fetching %s: %d %s", e.URL, e.StatusCode, http.StatusText(e.StatusCode))
+}
+
+func (e *StatusError) temporary() bool {
+	return e.StatusCode >= 500 || e.StatusCode == http.StatusTooManyRequests
+}
+
+// ValidatorStore keeps the ETag and Last-Modified of pages between runs so
+// unchanged pages are not downloaded and parsed again.
+type ValidatorStore interface {
+	GetValidators(ctx context.Context, url string) (etag, lastModified string, err error)
+	SaveValidators(ctx context.Context, url, etag, lastModified string) error
+}
+
+type ClientConfig struct {
+	UserAgent string
+	// Timeout bounds each attempt; the caller's context bounds the whole
+	// request including retries.
+	Timeout time.Duration
+	Retries int
+	// Backoff is the wait before the first retry; it doubles every retry.
+	Backoff time.Duration
+	// CrawlDelay is the minimum time between requests to the same host.
+	// A longer Crawl-delay in robots.txt wins.
+	CrawlDelay time.Duration
+	Validators ValidatorStore
+	// Logger defaults to slog.Default.
+	Logger *slog.Logger
+}
+
+type Response struct {
+	Header http.Header
+	Body   []byte
+}
+
+// Client fetches pages politely: it identifies itself, obeys robots.txt,
+// spaces out requests, retries transient failures and makes conditional
+// requests for pages that are polled.
+type Client struct {
+	cfg  ClientConfig
+	http *http.Client
+
+	mu      sync.Mutex
+	robots  map[string]*robots
+	last    map[string]time.Time
+	pending map[string][2]string
+}
+
+func NewClient(cfg ClientConfig) *Client {
+	if cfg.UserAgent == "" {
+		cfg.UserAgent = DefaultUserAgent
+	}
+	if cfg.Backoff <= 0 {
+		cfg.Backoff = time.Second
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = slog.Default()
+	}
+	return &Client{
+		cfg:     cfg,
+		http:    utils.CreateHTTPClient(cfg.Timeout),
+		robots:  map[string]*robots{},
+		last:    map[string]time.Time{},
+		pending: map[string][2]string{},
+	}
+}
+
+// Get fetches link and returns the body of a 200 response, reading at most
+// maxSize bytes.
+func (c *Client) Get(ctx context.Context, link string, maxSize int64) (*Response, error) {
+	return c.get(ctx, link, maxSize, false)
+}
+
+// GetIfModified is Get with the validators saved for link, returning
+// ErrNotModified when the server says nothing changed. New validators are
+// only stored by SaveValidators, once the caller has processed the page.
+func (c *Client) GetIfModified(ctx context.Context, link string, maxSize int64) (*Response, error) {
+	return c.get(ctx, link, maxSize, true)
+}
+
+// SaveValidators stores the validators of the pages fetched with
+// GetIfModified.
+func (c *Client) SaveValidators(ctx context.Context) error {
+	if c.cfg.Validators == nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for link, v := range c.pending {
+		if err := c.cfg.Validators.SaveValidators(ctx, link, v[0], v[1]); err != nil {
+			return err
+		}
+		delete(c.pending, link)
+	}
+	return nil
+}
+
+func (c *Client) get(ctx context.Context, link string, maxSize int64, conditional bool) (*Response, error) {
+	u, err := url.Parse(link)
+	if err != nil {
+		return nil, err
+	}
+
+	rules := c.robotsFor(ctx, u)
+	if !rules.allowed(u.EscapedPath()) {
+		return nil, ErrDisallowed
+	}
+
+	header := http.Header{}
+	if conditional && c.cfg.Validators != nil {
+		etag, lastModified, err := c.cfg.Validators.GetValidators(ctx, link)
+		if err != nil {
+			return nil, err
+		}
+		if etag != "" {
+			header.Set("If-None-Match", etag)
+		}
+		if lastModified != "" {
+			header.Set("If-Modified-Since", lastModified)
+		}
+	}
+
+	resp, err := c.retry(ctx, u, header, maxSize, rules.delay)
+	if err != nil {
+		return nil, err
+	}
+
+	if conditional {
+		c.mu.Lock()
+		c.pending[link] = [2]string{resp.Header.Get("ETag"), resp.Header.Get("Last-Modified")}
+		c.mu.Unlock()
+	}
+	return resp, nil
+}
+
+// retry makes the request, retrying network errors and 5xx/429 responses
+// with exponential backoff until the retries or the context run out.
+func (c *Client) retry(ctx context.Context, u *url.URL, header http.Header, maxSize int64, crawlDelay time.Duration) (*Response, error) {
+	backoff := c.cfg.Backoff
+	for attempt := 0; ; attempt++ {
+		if err := c.wait(ctx, u.Host, crawlDelay); err != nil {
+			return nil, err
+		}
+
+		resp, err := c.attempt(ctx, u, header, maxSize)
+		if err == nil {
+			return resp, nil
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+
+		delay := backoff
+		var statusErr *StatusError
+		switch {
+		case errors.As(err, &statusErr):
+			if !statusErr.temporary() {
+				return nil, err
+			}
+			delay = max(delay, statusErr.retryAfter)
+		case errors.Is(err, ErrNotModified), errors.Is(err, ErrTooLarge):
+			return nil, err
+		}
+		if attempt >= c.cfg.Retries {
+			return nil, err
+		}
+
+		c.cfg.Logger.Warn("fetch failed, retrying", "url", u.String(), logging.Err(err), "delay", delay)
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(delay):
+		}
+		backoff *= 2
+	}
+}
+
+func (c *Client) attempt(ctx context.Context, u *url.URL, header http.Header, maxSize int64) (*Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header = header.Clone()
+	req.Header.Set("User-Agent", c.cfg.UserAgent)
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotModified:
+		return nil, ErrNotModified
+	default:
+		io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+		return nil, &StatusError{
+			URL:        u.String(),
+			StatusCode: resp.StatusCode,
+			retryAfter: retryAfter(resp.Header.Get("Retry-After")),
+		}
+	}
+
+	if resp.ContentLength > maxSize {
+		return nil, ErrTooLarge
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(body)) > maxSize {
+		return nil, ErrTooLarge
+	}
+
+	return &Response{Header: resp.Header, Body: body}, nil
+}
+
+// wait blocks until delay has passed since the previous request to host.
+func (c *Client) wait(ctx context.Context, host string, delay time.Duration) error {
+	delay = max(delay, c.cfg.CrawlDelay)
+
+	c.mu.Lock()
+	next := c.last[host].Add(delay)
+	now := time.Now()
+	if next.Before(now) {
+		next = now
+	}
+	c.last[host] = next
+	c.mu.Unlock()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(time.Until(next)):
+		return nil
+	}
+}
+
+// robotsFor returns the robots.txt rules of u's host, fetching them on
+// first use. A missing or unreachable robots.txt allows everything, but a
+// server error disallows everything, as RFC 9309 requires.
+func (c *Client) robotsFor(ctx context.Context, u *url.URL) *robots {
+	c.mu.Lock()
+	r, ok := c.robots[u.Host]
+	c.mu.Unlock()
+	if ok {
+		return r
+	}
+
+	r = &robots{}
+	robotsURL := &url.URL{Scheme: u.Scheme, Host: u.Host, Path: "/robots.txt"}
+	resp, err := c.attempt(ctx, robotsURL, http.Header{}, 512<<10)
+	var statusErr *StatusError
+	switch {
+	case err == nil:
+		r = parseRobots(string(resp.Body), c.cfg.UserAgent)
+	case errors.As(err, &statusErr) && statusErr.StatusCode < 500:
+	case statusErr != nil:
+		c.cfg.Logger.Warn("robots.txt unavailable, assuming full disallow", "url", robotsURL.String(), logging.Err(err))
+		r = &robots{disallow: []string{"/"}}
+	default:
+		c.cfg.Logger.Warn("fetching robots.txt failed, assuming no restrictions", "url", robotsURL.String(), logging.Err(err))
+	}
+
+	c.mu.Lock()
+	c.robots[u.Host] = r
+	c.last[u.Host] = time.Now()
+	c.mu.Unlock()
+	return r
+}
+
+func retryAfter(v string) time.Duration {
+	if v == "" {
+		return 0
+	}
+	if s, err := strconv.Atoi(strings.TrimSpace(v)); err == nil {
+		return time.Duration(s) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return time.Until(t)
+	}
+	return 0
+}
+
+func (r *Response) reader() io.Reader {
+	return bytes.NewReader(r.Body)
+}
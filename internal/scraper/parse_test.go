@@ -0,0 +1,114 @@
+package scraper
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/paluras/product-recall-system/internal/scraper/scrapertest"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// golden compares got, encoded as JSON, with testdata/name.golden.json.
+func golden(t *testing.T, name string, got any) {
+	t.Helper()
+
+	data, err := json.MarshalIndent(got, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data = append(data, '\n')
+
+	path := filepath.Join("testdata", name+".golden.json")
+	if *update {
+		if err := os.WriteFile(path, data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("%v (run go test -update to create it)", err)
+	}
+	if !bytes.Equal(data, want) {
+		t.Errorf("%s does not match the golden file:\n got: %s\nwant: %s", name, data, want)
+	}
+}
+
+func fixture(t *testing.T, name string) []byte {
+	t.Helper()
+	data, err := scrapertest.Fixture(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestParseListing(t *testing.T) {
+	items, err := ParseListing(bytes.NewReader(fixture(t, "listing.html")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	golden(t, "listing", items)
+}
+
+func TestParseListingWithoutRecalls(t *testing.T) {
+	for _, name := range []string{"not-found.html", "detail-ulei.html"} {
+		if _, err := ParseListing(bytes.NewReader(fixture(t, name))); err == nil {
+			t.Errorf("ParseListing(%s) returned no error", name)
+		}
+	}
+}
+
+func TestParseDetail(t *testing.T) {
+	tests := []struct {
+		fixture string
+		link    string
+	}{
+		{"detail-biscuiti.html", "https://www.ansvsa.ro/blog/rechemare-biscuiti-crema-cacao/"},
+		// no .entry-content, a relative and upper-case PDF link
+		{"detail-salam.html", "https://www.ansvsa.ro/blog/retragere-salam-de-porc-afumat/"},
+		{"detail-ulei.html", "https://www.ansvsa.ro/blog/informare-ulei-floarea-soarelui/"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.fixture, func(t *testing.T) {
+			detail, err := ParseDetail(bytes.NewReader(fixture(t, tt.fixture)), tt.link)
+			if err != nil {
+				t.Fatal(err)
+			}
+			golden(t, strings.TrimSuffix(tt.fixture, ".html"), detail)
+		})
+	}
+}
+
+func TestCovered(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2025, 3, d, 0, 0, 0, 0, time.UTC) }
+
+	for _, tt := range []struct {
+		dates []time.Time
+		want  time.Time
+	}{
+		// more recalls from the 10th may have fallen off the page, so one
+		// that is missing from it has not necessarily been withdrawn
+		{[]time.Time{day(12), day(10), day(10)}, day(11)},
+		{[]time.Time{day(12), {}, day(11)}, day(12)},
+		{[]time.Time{day(12)}, day(13)},
+		{[]time.Time{{}}, time.Time{}},
+		{nil, time.Time{}},
+	} {
+		items := make([]ScrapedData, len(tt.dates))
+		for i, d := range tt.dates {
+			items[i] = ScrapedData{Date: d}
+		}
+		if got := Covered(items); !got.Equal(tt.want) {
+			t.Errorf("Covered(%v) = %v, want %v", tt.dates, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package scraper
+
+import (
+	"bufio"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// robots holds the robots.txt rules that apply to us.
+type robots struct {
+	allow    []string
+	disallow []string
+	delay    time.Duration
+}
+
+// parseRobots picks the group of a robots.txt whose User-agent is the
+// product token of userAgent, then one whose User-agent is a prefix of it,
+// falling back to the "*" group. Names are compared case-insensitively.
+func parseRobots(body, userAgent string) *robots {
+	token := productToken(userAgent)
+
+	var (
+		ours, prefixed, wildcard *robots
+		current                  []*robots
+		inAgents                 bool
+	)
+	scanner := bufio.NewScanner(strings.NewReader(body))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+
+		if key == "user-agent" {
+			if !inAgents {
+				current = nil
+			}
+			inAgents = true
+			agent := productToken(value)
+			switch {
+			case agent == "*":
+				if wildcard == nil {
+					wildcard = &robots{}
+				}
+				current = append(current, wildcard)
+			case agent == token:
+				if ours == nil {
+					ours = &robots{}
+				}
+				current = append(current, ours)
+			case agent != "" && strings.HasPrefix(token, agent):
+				if prefixed == nil {
+					prefixed = &robots{}
+				}
+				current = append(current, prefixed)
+			}
+			continue
+		}
+		inAgents = false
+
+		for _, r := range current {
+			switch key {
+			case "allow":
+				if value != "" {
+					r.allow = append(r.allow, value)
+				}
+			case "disallow":
+				if value != "" {
+					r.disallow = append(r.disallow, value)
+				}
+			case "crawl-delay":
+				if s, err := strconv.ParseFloat(value, 64); err == nil && s > 0 {
+					r.delay = time.Duration(s * float64(time.Second))
+				}
+			}
+		}
+	}
+
+	switch {
+	case ours != nil:
+		return ours
+	case prefixed != nil:
+		return prefixed
+	case wildcard != nil:
+		return wildcard
+	}
+	return &robots{}
+}
+
+// productToken returns the lower-cased name of a User-Agent, without its
+// version and comments: "ProduseRetraseBot/1.0 (+https://…)" is
+// "produseretrasebot".
+func productToken(userAgent string) string {
+	token := strings.ToLower(strings.TrimSpace(userAgent))
+	if i := strings.IndexAny(token, "/ "); i >= 0 {
+		token = token[:i]
+	}
+	return token
+}
+
+// allowed applies the longest matching rule; Allow wins ties.
+func (r *robots) allowed(path string) bool {
+	if path == "" {
+		path = "/"
+	}
+	best, allowed := -1, true
+	for _, p := range r.disallow {
+		if matchRobots(p, path) && len(p) > best {
+			best, allowed = len(p), false
+		}
+	}
+	for _, p := range r.allow {
+		if matchRobots(p, path) && len(p) >= best {
+			best, allowed = len(p), true
+		}
+	}
+	return allowed
+}
+
+// matchRobots matches a robots.txt path pattern, which may contain * and
+// end with $.
+func matchRobots(pattern, path string) bool {
+	anchored := strings.HasSuffix(pattern, "$")
+	pattern = strings.TrimSuffix(pattern, "$")
+
+	parts := strings.Split(pattern, "*")
+	if !strings.HasPrefix(path, parts[0]) {
+		return false
+	}
+	rest := path[len(parts[0]):]
+	for _, part := range parts[1:] {
+		i := strings.Index(rest, part)
+		if i < 0 {
+			return false
+		}
+		rest = rest[i+len(part):]
+	}
+	if anchored && len(parts) == 1 {
+		return rest == ""
+	}
+	return !anchored || rest == "" || strings.HasSuffix(path, parts[len(parts)-1])
+}
@@ -0,0 +1,81 @@
+package scraper
+
+import (
+	"testing"
+	"time"
+)
+
+const testRobots = `# our bot gets its own group
+User-agent: *
+Disallow: /private/
+
+User-agent: ProduseRetrase
+Disallow: /prefix/
+
+User-agent: bot
+Disallow: /bots/
+
+User-agent: produseretrasebot/2.0
+Disallow: /ours/
+Allow: /ours/public/
+Crawl-delay: 3
+`
+
+func TestParseRobotsGroups(t *testing.T) {
+	for _, tt := range []struct {
+		name      string
+		body      string
+		userAgent string
+		disallow  []string
+	}{
+		{"exact token, any case and version", testRobots, DefaultUserAgent, []string{"/ours/"}},
+		{"prefix of the token", `
+User-agent: *
+Disallow: /private/
+
+User-agent: PRODUSERETRASE
+Disallow: /prefix/
+`, DefaultUserAgent, []string{"/prefix/"}},
+		{"substring is not a match", `
+User-agent: bot
+Disallow: /bots/
+
+User-agent: *
+Disallow: /private/
+`, DefaultUserAgent, []string{"/private/"}},
+		{"token longer than ours", `
+User-agent: ProduseRetraseBotNews
+Disallow: /news/
+`, DefaultUserAgent, nil},
+		{"other crawler falls back to *", testRobots, "Googlebot/2.1", []string{"/private/"}},
+		{"grouped user agents", `
+User-agent: otherbot
+User-agent: produseretrasebot
+Disallow: /shared/
+`, DefaultUserAgent, []string{"/shared/"}},
+	} {
+		r := parseRobots(tt.body, tt.userAgent)
+		if len(r.disallow) != len(tt.disallow) || (len(tt.disallow) > 0 && r.disallow[0] != tt.disallow[0]) {
+			t.Errorf("%s: disallow = %q, want %q", tt.name, r.disallow, tt.disallow)
+		}
+	}
+}
+
+func TestRobotsAllowed(t *testing.T) {
+	r := parseRobots(testRobots, DefaultUserAgent)
+	if r.delay != 3*time.Second {
+		t.Errorf("crawl delay = %v, want 3s", r.delay)
+	}
+	for path, want := range map[string]bool{
+		"":                  true,
+		"/private/x":        true,
+		"/ours/":            false,
+		"/ours/page":        false,
+		"/ours/public/page": true,
+		"/somewhere/ours/":  true,
+	} {
+		if got := r.allowed(path); got != want {
+			t.Errorf("allowed(%q) = %v, want %v", path, got, want)
+		}
+	}
+}
@@ -1,13 +1,16 @@
 package scraper
 
 import (
-	"log"
-	"net/http"
+	"context"
+	"errors"
+	"io"
+	"net/url"
+	"slices"
 	"sort"
+	"strings"
 	"time"
 
 	"github.com/PuerkitoBio/goquery"
-	"github.com/paluras/product-recall-system/internal/utils"
 )
 
 type ScrapedData struct {
@@ -18,23 +21,41 @@ type ScrapedData struct {
 
 const ScraperURL = "https://www.ansvsa.ro/informatii-pentru-public/produse-rechemateretrase/"
 
-func Scrape() ([]ScrapedData, error) {
-
-	client := utils.CreateHTTPClient()
+// Scraper reads recalls from the ANSVSA website.
+type Scraper struct {
+	client     *Client
+	listingURL string
+}
 
-	req, err := http.NewRequest("GET", ScraperURL, nil)
-	if err != nil {
-		return nil, err
+// New returns a Scraper that reads the listing at listingURL, or at
+// ScraperURL when it is empty.
+func New(client *Client, listingURL string) *Scraper {
+	if listingURL == "" {
+		listingURL = ScraperURL
 	}
-	req.Header.Set("User-Agent", "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36")
+	return &Scraper{client: client, listingURL: listingURL}
+}
 
-	resp, err := client.Do(req)
+// Scrape returns the recalls on the listing page. It returns ErrNotModified
+// when the page has not changed since the validators were last saved.
+func (s *Scraper) Scrape(ctx context.Context) ([]ScrapedData, error) {
+	resp, err := s.client.GetIfModified(ctx, s.listingURL, maxPageSize)
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
+	items, err := ParseListing(resp.reader())
+	for _, item := range items {
+		if item.Date.IsZero() {
+			s.client.cfg.Logger.Warn("listing item has no valid date", "url", item.Link)
+		}
+	}
+	return items, err
+}
 
-	doc, err := goquery.NewDocumentFromReader(resp.Body)
+// ParseListing reads the recalls from the HTML of the listing page, newest
+// first. Items whose date cannot be parsed have a zero Date.
+func ParseListing(r io.Reader) ([]ScrapedData, error) {
+	doc, err := goquery.NewDocumentFromReader(r)
 	if err != nil {
 		return nil, err
 	}
@@ -49,9 +70,7 @@ func Scrape() ([]ScrapedData, error) {
 
 		parsedDate, err := time.Parse("02/01/2006", date)
 		if err != nil {
-			log.Printf("Failed to parse date %q: %v", date, err)
 			parsedDate = time.Time{}
-
 		}
 
 		results = append(results, ScrapedData{
@@ -61,9 +80,80 @@ func Scrape() ([]ScrapedData, error) {
 		})
 	})
 
+	// a maintenance or error page served with 200 must not look like a
+	// listing that lost all its recalls
+	if len(results) == 0 {
+		return nil, errors.New("scraper: no recalls found on the listing page")
+	}
+
 	sort.Slice(results, func(i, j int) bool {
 		return results[i].Date.After(results[j].Date)
 	})
 
 	return results, nil
 }
+
+// Covered returns the earliest date from which items lists every recall.
+// The listing shows a fixed number of items and dates them by day, so the
+// oldest day on it may have had more recalls that no longer fit; only the
+// days after it are complete. It returns the zero time when no item has a
+// date.
+func Covered(items []ScrapedData) time.Time {
+	var oldest time.Time
+	for _, item := range items {
+		if !item.Date.IsZero() && (oldest.IsZero() || item.Date.Before(oldest)) {
+			oldest = item.Date
+		}
+	}
+	if oldest.IsZero() {
+		return oldest
+	}
+	return oldest.AddDate(0, 0, 1)
+}
+
+// Detail is what a recall's article page holds beyond the listing.
+type Detail struct {
+	Text        string
+	Attachments []string
+}
+
+// ScrapeDetail returns the text of a recall's article page and the absolute
+// URLs of the PDFs it links to.
+func (s *Scraper) ScrapeDetail(ctx context.Context, link string) (Detail, error) {
+	resp, err := s.client.Get(ctx, link, maxPageSize)
+	if err != nil {
+		return Detail{}, err
+	}
+	return ParseDetail(resp.reader(), link)
+}
+
+// ParseDetail reads the HTML of the article page at link. Relative
+// attachment links are resolved against link.
+func ParseDetail(r io.Reader, link string) (Detail, error) {
+	base, err := url.Parse(link)
+	if err != nil {
+		return Detail{}, err
+	}
+	doc, err := goquery.NewDocumentFromReader(r)
+	if err != nil {
+		return Detail{}, err
+	}
+
+	content := doc.Find(".entry-content").First()
+	if content.Length() == 0 {
+		content = doc.Find("article").First()
+	}
+
+	detail := Detail{Text: strings.Join(strings.Fields(content.Text()), " ")}
+	content.Find("a[href]").Each(func(i int, s *goquery.Selection) {
+		href, _ := s.Attr("href")
+		u, err := base.Parse(strings.TrimSpace(href))
+		if err != nil || !strings.HasSuffix(strings.ToLower(u.Path), ".pdf") {
+			return
+		}
+		if !slices.Contains(detail.Attachments, u.String()) {
+			detail.Attachments = append(detail.Attachments, u.String())
+		}
+	})
+	return detail, nil
+}
@@ -0,0 +1,174 @@
+package scraper
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/paluras/product-recall-system/internal/scraper/scrapertest"
+)
+
+// memoryValidators is a ValidatorStore for tests.
+type memoryValidators struct {
+	mu sync.Mutex
+	v  map[string][2]string
+}
+
+func (m *memoryValidators) GetValidators(ctx context.Context, url string) (string, string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	v := m.v[url]
+	return v[0], v[1], nil
+}
+
+func (m *memoryValidators) SaveValidators(ctx context.Context, url, etag, lastModified string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.v == nil {
+		m.v = map[string][2]string{}
+	}
+	m.v[url] = [2]string{etag, lastModified}
+	return nil
+}
+
+func newTestScraper(t *testing.T) (*Scraper, *Client, *scrapertest.Server) {
+	t.Helper()
+	srv := scrapertest.NewServer()
+	t.Cleanup(srv.Close)
+
+	client := NewClient(ClientConfig{
+		Timeout:    5 * time.Second,
+		Retries:    2,
+		Backoff:    time.Millisecond,
+		Validators: &memoryValidators{},
+	})
+	return New(client, srv.ListingURL()), client, srv
+}
+
+func TestScrapeFakeServer(t *testing.T) {
+	s, _, srv := newTestScraper(t)
+	ctx := context.Background()
+
+	items, err := s.Scrape(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 3 {
+		t.Fatalf("got %d items, want 3", len(items))
+	}
+	if !strings.HasPrefix(items[0].Link, srv.URL+"/blog/") {
+		t.Errorf("link %q does not point at the fake server", items[0].Link)
+	}
+
+	detail, err := s.ScrapeDetail(ctx, items[0].Link)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(detail.Attachments) != 1 {
+		t.Fatalf("got attachments %v, want one", detail.Attachments)
+	}
+
+	a, err := s.FetchAttachment(ctx, detail.Attachments[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"EAN: 5941234567899", "Lot: L24256, L24257"} {
+		if !strings.Contains(a.Text, want) {
+			t.Errorf("attachment text %q does not contain %q", a.Text, want)
+		}
+	}
+	if a.SHA256 == "" || a.Size == 0 {
+		t.Errorf("attachment %+v has no hash or size", a)
+	}
+
+	// the second notice links to an HTML error page
+	detail, err = s.ScrapeDetail(ctx, items[1].Link)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.FetchAttachment(ctx, detail.Attachments[0]); !errors.Is(err, ErrNotPDF) {
+		t.Errorf("got error %v, want ErrNotPDF", err)
+	}
+
+	if _, err := s.ScrapeDetail(ctx, srv.URL+"/blog/missing/"); err == nil {
+		t.Error("missing page returned no error")
+	}
+}
+
+func TestScrapeNotModified(t *testing.T) {
+	s, client, _ := newTestScraper(t)
+	ctx := context.Background()
+
+	if _, err := s.Scrape(ctx); err != nil {
+		t.Fatal(err)
+	}
+	// validators are only kept once the caller has stored the items
+	if _, err := s.Scrape(ctx); err != nil {
+		t.Fatalf("second scrape before SaveValidators: %v", err)
+	}
+
+	if err := client.SaveValidators(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Scrape(ctx); !errors.Is(err, ErrNotModified) {
+		t.Fatalf("got error %v, want ErrNotModified", err)
+	}
+}
+
+func TestScrapeRetries(t *testing.T) {
+	s, _, srv := newTestScraper(t)
+	ctx := context.Background()
+
+	srv.FailNext(scrapertest.ListingPath, 2)
+	if _, err := s.Scrape(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if hits := srv.Hits(scrapertest.ListingPath); hits != 3 {
+		t.Errorf("listing was requested %d times, want 3", hits)
+	}
+
+	srv.FailNext(scrapertest.ListingPath, 3)
+	_, err := s.Scrape(ctx)
+	var statusErr *StatusError
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != 503 {
+		t.Errorf("got error %v, want a 503 StatusError", err)
+	}
+}
+
+func TestScrapeObeysRobots(t *testing.T) {
+	s, _, srv := newTestScraper(t)
+
+	_, err := s.ScrapeDetail(context.Background(), srv.URL+"/wp-admin/edit.php")
+	if !errors.Is(err, ErrDisallowed) {
+		t.Errorf("got error %v, want ErrDisallowed", err)
+	}
+	if hits := srv.Hits("/wp-admin/edit.php"); hits != 0 {
+		t.Errorf("disallowed page was requested %d times", hits)
+	}
+}
+
+func TestScrapeRobotsServerError(t *testing.T) {
+	s, _, srv := newTestScraper(t)
+
+	// a robots.txt that keeps failing with 503 disallows the whole site
+	srv.FailNext("/robots.txt", 3)
+	if _, err := s.Scrape(context.Background()); !errors.Is(err, ErrDisallowed) {
+		t.Errorf("got error %v, want ErrDisallowed", err)
+	}
+	if hits := srv.Hits(scrapertest.ListingPath); hits != 0 {
+		t.Errorf("listing was requested %d times", hits)
+	}
+}
+
+func TestScrapeCancelled(t *testing.T) {
+	s, _, _ := newTestScraper(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := s.Scrape(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("got error %v, want context.Canceled", err)
+	}
+}
@@ -0,0 +1,135 @@
+// Package scrapertest serves recorded ANSVSA pages so the scraper, and the
+// pipeline behind it, can run without the real site.
+package scrapertest
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+)
+
+//go:embed testdata
+var files embed.FS
+
+// origin is replaced in the recorded pages by the address they are served
+// from, so links lead back to the fake server.
+const origin = "https://www.ansvsa.ro"
+
+// ListingPath is where the recall listing is served, as on the real site.
+const ListingPath = "/informatii-pentru-public/produse-rechemateretrase/"
+
+type page struct {
+	file        string
+	contentType string
+}
+
+var pages = map[string]page{
+	"/robots.txt": {"robots.txt", "text/plain; charset=utf-8"},
+	ListingPath:   {"listing.html", "text/html; charset=UTF-8"},
+
+	"/blog/rechemare-biscuiti-crema-cacao/":               {"detail-biscuiti.html", "text/html; charset=UTF-8"},
+	"/blog/retragere-salam-de-porc-afumat/":               {"detail-salam.html", "text/html; charset=UTF-8"},
+	"/blog/informare-ulei-floarea-soarelui/":              {"detail-ulei.html", "text/html; charset=UTF-8"},
+	"/wp-content/uploads/2026/10/notificare-biscuiti.pdf": {"notificare-biscuiti.pdf", "application/pdf"},
+	// WordPress answers some missing uploads with an HTML page and a 200
+	"/wp-content/uploads/2026/10/Comunicat-Salam.PDF": {"not-found.html", "text/html; charset=UTF-8"},
+}
+
+// Fixture returns a recorded file by name, e.g. "listing.html".
+func Fixture(name string) ([]byte, error) {
+	return files.ReadFile("testdata/" + name)
+}
+
+// Handler serves the recorded pages. The listing carries an ETag and
+// answers conditional requests with 304.
+type Handler struct {
+	mu   sync.Mutex
+	hits map[string]int
+	fail map[string]int
+}
+
+func NewHandler() *Handler {
+	return &Handler{
+		hits: map[string]int{},
+		fail: map[string]int{},
+	}
+}
+
+// FailNext makes the next n requests for path fail with 503.
+func (h *Handler) FailNext(path string, n int) {
+	h.mu.Lock()
+	h.fail[path] = n
+	h.mu.Unlock()
+}
+
+// Hits returns the number of requests made for path.
+func (h *Handler) Hits(path string) int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.hits[path]
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mu.Lock()
+	h.hits[r.URL.Path]++
+	fail := h.fail[r.URL.Path] > 0
+	if fail {
+		h.fail[r.URL.Path]--
+	}
+	h.mu.Unlock()
+
+	if fail {
+		http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+		return
+	}
+
+	p, ok := pages[r.URL.Path]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	body, err := Fixture(p.file)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if p.contentType != "application/pdf" {
+		body = bytes.ReplaceAll(body, []byte(origin), []byte("http://"+r.Host))
+	}
+
+	if r.URL.Path == ListingPath {
+		sum := sha256.Sum256(body)
+		etag := `"` + hex.EncodeToString(sum[:8]) + `"`
+		w.Header().Set("ETag", etag)
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", p.contentType)
+	w.Write(body)
+}
+
+// Server is a running fake ANSVSA site. Close it when done.
+type Server struct {
+	*httptest.Server
+	*Handler
+}
+
+func NewServer() *Server {
+	h := NewHandler()
+	return &Server{
+		Server:  httptest.NewServer(h),
+		Handler: h,
+	}
+}
+
+// ListingURL is the URL to pass to scraper.New.
+func (s *Server) ListingURL() string {
+	return s.URL + ListingPath
+}
@@ -3,14 +3,20 @@ package utils
 import (
 	"crypto/tls"
 	"net/http"
+	"time"
 )
 
-func CreateHTTPClient() *http.Client {
+func CreateHTTPClient(timeout time.Duration) *http.Client {
 	return &http.Client{
+		Timeout: timeout,
 		Transport: &http.Transport{
+			Proxy: http.ProxyFromEnvironment,
 			TLSClientConfig: &tls.Config{
 				MinVersion: tls.VersionTLS12,
 			},
+			TLSHandshakeTimeout:   10 * time.Second,
+			ResponseHeaderTimeout: timeout,
+			IdleConnTimeout:       90 * time.Second,
 		},
 	}
 }
@@ -0,0 +1,80 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/paluras/product-recall-system/internal/models"
+)
+
+type apiItem struct {
+	ID    int          `json:"id"`
+	Title string       `json:"title"`
+	Link  string       `json:"link"`
+	Date  time.Time    `json:"date"`
+	Tags  []models.Tag `json:"tags"`
+}
+
+func toAPIItems(items []models.ScrapedItem) []apiItem {
+	result := make([]apiItem, len(items))
+	for i, item := range items {
+		tags := item.Tags
+		if tags == nil {
+			tags = []models.Tag{}
+		}
+		result[i] = apiItem{
+			ID:    item.ID,
+			Title: item.Title,
+			Link:  item.Link,
+			Date:  item.Date,
+			Tags:  tags,
+		}
+	}
+	return result
+}
+
+func (app *application) writeJSON(w http.ResponseWriter, r *http.Request, status int, v any) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(b)
+}
+
+// apiItems lists the newest recalls, optionally only those matching ?q= or
+// with ?tag=.
+func (app *application) apiItems(w http.ResponseWriter, r *http.Request) {
+	limit := 20
+	if s := r.URL.Query().Get("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 || n > 100 {
+			app.writeJSON(w, r, http.StatusBadRequest, map[string]string{"error": "limit must be between 1 and 100"})
+			return
+		}
+		limit = n
+	}
+
+	var (
+		items []models.ScrapedItem
+		err   error
+	)
+	if q := strings.TrimSpace(r.URL.Query().Get("q")); q != "" {
+		items, err = app.db.SearchItems(r.Context(), q, limit)
+	} else if tag := r.URL.Query().Get("tag"); tag != "" {
+		items, err = app.db.GetItemsByTag(r.Context(), tag, limit)
+	} else {
+		items, err = app.db.GetLatestItems(r.Context(), limit)
+	}
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	app.writeJSON(w, r, http.StatusOK, map[string]any{"items": toAPIItems(items)})
+}
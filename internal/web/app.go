@@ -0,0 +1,126 @@
+package web
+
+import (
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/alexedwards/scs/v2"
+	"github.com/paluras/product-recall-system/configs"
+	"github.com/paluras/product-recall-system/internal/challenge"
+	"github.com/paluras/product-recall-system/internal/i18n"
+	"github.com/paluras/product-recall-system/internal/logging"
+	"github.com/paluras/product-recall-system/internal/models"
+	"github.com/paluras/product-recall-system/internal/notify"
+	"github.com/paluras/product-recall-system/ui"
+)
+
+type application struct {
+	templates      templateCache
+	catalog        *i18n.Catalog
+	db             *models.DB
+	session        *scs.SessionManager
+	logger         *slog.Logger
+	emailService   *notify.EmailService
+	limiters       limiters
+	trustedProxies []*net.IPNet
+	verifier       challenge.Verifier
+	abuse          *abuseDetector
+
+	addr            string
+	confirmCooldown time.Duration
+	tokenKey        []byte
+	// vapidPublicKey is empty when browser notifications are off.
+	vapidPublicKey string
+	// dev reloads the templates from disk on every request.
+	dev bool
+}
+
+// Serve runs the website until it receives SIGINT or SIGTERM.
+func Serve(conf *configs.Config, logger *slog.Logger) error {
+	session := scs.New()
+	session.Lifetime = 24 * time.Hour
+	session.Cookie.Persist = true
+	session.Cookie.SameSite = http.SameSiteLaxMode
+	session.Cookie.Secure = true
+
+	catalog, err := i18n.Load()
+	if err != nil {
+		return err
+	}
+
+	templates, err := newTemplateCache(ui.Files, catalog)
+	if err != nil {
+		return err
+	}
+
+	dsn := conf.DSN()
+
+	db, err := models.NewDB(dsn, logger)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	lims, err := newLimiters(conf, db, logger)
+	if err != nil {
+		return err
+	}
+
+	trustedProxies, err := parseTrustedProxies(conf.TrustedProxies)
+	if err != nil {
+		return err
+	}
+
+	verifier, err := newVerifier(conf)
+	if err != nil {
+		return err
+	}
+
+	tokenKey := []byte(conf.TokenSigningKey)
+	if len(tokenKey) == 0 {
+		return errors.New("token-signing-key (TOKEN_SIGNING_KEY) is required")
+	}
+
+	var vapidPublicKey string
+	if conf.VAPIDPrivateKey != "" {
+		if vapidPublicKey, err = notify.VAPIDPublicKey(conf.VAPIDPrivateKey); err != nil {
+			return err
+		}
+	}
+
+	var emailService *notify.EmailService
+	if conf.ResendAPIKey != "" {
+		emailService, err = notify.NewEmailService(notify.EmailConfig{
+			APIKey:    conf.ResendAPIKey,
+			FromEmail: conf.MailFrom,
+			BaseURL:   conf.BaseURL,
+		}, db, logger)
+		if err != nil {
+			logger.Error("initializing email service failed", logging.Err(err))
+		}
+	}
+
+	app := &application{
+		templates:      templates,
+		catalog:        catalog,
+		db:             db,
+		session:        session,
+		logger:         logger,
+		emailService:   emailService,
+		limiters:       lims,
+		trustedProxies: trustedProxies,
+		verifier:       verifier,
+		abuse:          newAbuseDetector(conf.ChallengeMode, conf.ChallengeThreshold),
+
+		addr:            conf.Addr,
+		confirmCooldown: conf.ConfirmCooldown,
+		tokenKey:        tokenKey,
+		vapidPublicKey:  vapidPublicKey,
+		dev:             conf.Dev,
+	}
+
+	return app.serve()
+}
@@ -0,0 +1,94 @@
+package web
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/paluras/product-recall-system/configs"
+	"github.com/paluras/product-recall-system/internal/challenge"
+	"github.com/paluras/product-recall-system/internal/ratelimit"
+)
+
+// abuseDetector switches the subscription challenge on for a cooldown period
+// once the site sees more attempts than usual or a bot trips the honeypot.
+type abuseDetector struct {
+	mode     string
+	attempts ratelimit.Limiter
+	cooldown time.Duration
+
+	mu           sync.Mutex
+	trippedUntil time.Time
+}
+
+func newAbuseDetector(mode string, threshold int) *abuseDetector {
+	return &abuseDetector{
+		mode:     mode,
+		attempts: ratelimit.NewMemory(ratelimit.Rule{Limit: threshold, Window: time.Hour}),
+		cooldown: time.Hour,
+	}
+}
+
+func (d *abuseDetector) trip() {
+	d.mu.Lock()
+	d.trippedUntil = time.Now().Add(d.cooldown)
+	d.mu.Unlock()
+}
+
+// attempt counts one subscription attempt across all clients.
+func (d *abuseDetector) attempt(ctx context.Context) {
+	if ok, _ := d.attempts.Allow(ctx, "subscribe"); !ok {
+		d.trip()
+	}
+}
+
+func (d *abuseDetector) active() bool {
+	switch d.mode {
+	case "always":
+		return true
+	case "auto":
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		return time.Now().Before(d.trippedUntil)
+	default:
+		return false
+	}
+}
+
+func newVerifier(conf *configs.Config) (challenge.Verifier, error) {
+	switch conf.ChallengeMode {
+	case "off":
+		return nil, nil
+	case "auto", "always":
+	default:
+		return nil, fmt.Errorf("unknown challenge mode %q", conf.ChallengeMode)
+	}
+
+	key := []byte(conf.ChallengeKey)
+	if len(key) == 0 {
+		// without a configured key, challenges only verify on the replica
+		// that issued them
+		key = make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			return nil, err
+		}
+	}
+	return challenge.NewProofOfWork(key, conf.ChallengeDifficulty, 10*time.Minute), nil
+}
+
+// issueChallenge returns the JSON for the form's data-challenge attribute, or
+// an empty string when no challenge is currently required.
+func (app *application) issueChallenge() (string, error) {
+	if app.verifier == nil || !app.abuse.active() {
+		return "", nil
+	}
+	c, err := app.verifier.Issue()
+	if err != nil {
+		return "", err
+	}
+	b, err := json.Marshal(c)
+	return string(b), err
+}
@@ -0,0 +1,91 @@
+package web
+
+import (
+	"net/http"
+
+	"github.com/paluras/product-recall-system/internal/barcode"
+	"github.com/paluras/product-recall-system/internal/models"
+)
+
+const (
+	checkRecalled = "recalled"
+	checkNotFound = "not_found"
+)
+
+// checkQuery reads and normalises ?ean= and ?lot=. The returned error is an
+// i18n key.
+func checkQuery(r *http.Request) (gtin, lot, errKey string) {
+	ean := r.URL.Query().Get("ean")
+	lot = barcode.NormalizeLot(r.URL.Query().Get("lot"))
+
+	if ean != "" {
+		var ok bool
+		gtin, ok = barcode.NormalizeGTIN(ean)
+		if !ok {
+			return "", "", "check.invalid_ean"
+		}
+	}
+	if gtin == "" && lot == "" {
+		return "", "", "check.missing"
+	}
+	return gtin, lot, ""
+}
+
+func checkStatus(items []models.ScrapedItem) string {
+	if len(items) > 0 {
+		return checkRecalled
+	}
+	return checkNotFound
+}
+
+func (app *application) check(w http.ResponseWriter, r *http.Request) {
+	data := struct {
+		Locale  string
+		EAN     string
+		Lot     string
+		Status  string
+		Recalls []models.ScrapedItem
+		Error   string
+	}{
+		Locale: app.locale(r),
+		EAN:    r.URL.Query().Get("ean"),
+		Lot:    r.URL.Query().Get("lot"),
+	}
+
+	if data.EAN != "" || data.Lot != "" {
+		gtin, lot, errKey := checkQuery(r)
+		if errKey != "" {
+			data.Error = errKey
+		} else {
+			items, err := app.db.CheckProduct(r.Context(), gtin, lot)
+			if err != nil {
+				app.serverError(w, r, err)
+				return
+			}
+			data.Recalls = items
+			data.Status = checkStatus(items)
+		}
+	}
+
+	app.render(w, r, http.StatusOK, "check.html", data)
+}
+
+// apiCheck is the JSON version of check.
+func (app *application) apiCheck(w http.ResponseWriter, r *http.Request) {
+	gtin, lot, errKey := checkQuery(r)
+	if errKey != "" {
+		app.writeJSON(w, r, http.StatusBadRequest, map[string]string{"error": app.catalog.T("en", errKey)})
+		return
+	}
+
+	items, err := app.db.CheckProduct(r.Context(), gtin, lot)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	app.writeJSON(w, r, http.StatusOK, map[string]any{
+		"status": checkStatus(items),
+		"items":  toAPIItems(items),
+	})
+}
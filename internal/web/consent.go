@@ -0,0 +1,24 @@
+package web
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+
+	"github.com/paluras/product-recall-system/internal/models"
+)
+
+// subscribeFormVersion identifies the wording subscribers agreed to. Bump it
+// whenever the text of the subscription form or privacy notice changes.
+const subscribeFormVersion = "2026-10"
+
+func (app *application) consent(r *http.Request) models.Consent {
+	mac := hmac.New(sha256.New, app.tokenKey)
+	mac.Write([]byte(app.realIP(r)))
+
+	return models.Consent{
+		IPHash:      hex.EncodeToString(mac.Sum(nil)),
+		FormVersion: subscribeFormVersion,
+	}
+}
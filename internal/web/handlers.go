@@ -0,0 +1,302 @@
+package web
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/paluras/product-recall-system/internal/logging"
+	"github.com/paluras/product-recall-system/internal/models"
+	"github.com/paluras/product-recall-system/internal/ratelimit"
+)
+
+func (app *application) home(w http.ResponseWriter, r *http.Request) {
+	tag := r.URL.Query().Get("tag")
+	q := strings.TrimSpace(r.URL.Query().Get("q"))
+
+	var (
+		recalls []models.ScrapedItem
+		err     error
+	)
+	switch {
+	case q != "":
+		recalls, err = app.db.SearchItems(r.Context(), q, 20)
+	case tag != "":
+		recalls, err = app.db.GetItemsByTag(r.Context(), tag, 20)
+	default:
+		recalls, err = app.db.GetLatest20Items(r.Context())
+	}
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	challenge, err := app.issueChallenge()
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	data := struct {
+		Locale    string
+		Locales   []string
+		Recalls   []models.ScrapedItem
+		Tag       string
+		Query     string
+		Error     string
+		Success   string
+		Challenge string
+		// VAPIDPublicKey turns on the browser notifications opt-in.
+		VAPIDPublicKey string
+	}{
+		Locale:    app.locale(r),
+		Locales:   app.catalog.Locales(),
+		Recalls:   recalls,
+		Tag:       tag,
+		Query:     q,
+		Error:     app.session.PopString(r.Context(), "error"),
+		Success:   app.session.PopString(r.Context(), "success"),
+		Challenge: challenge,
+
+		VAPIDPublicKey: app.vapidPublicKey,
+	}
+
+	app.render(w, r, http.StatusOK, "home.html", data)
+}
+
+func (app *application) unsubscribe(w http.ResponseWriter, r *http.Request) {
+	token := r.URL.Query().Get("token")
+	if token == "" {
+		app.logger.Warn("unsubscribe link without token")
+		return
+	}
+
+	err := app.db.UnsubscribeWithToken(r.Context(), token)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	app.renderMessage(w, r, app.locale(r), "unsubscribe.title", "unsubscribe.success")
+}
+
+func (app *application) PostSubscriber(w http.ResponseWriter, r *http.Request) {
+	// the same message is shown whatever state the address is in, so the
+	// form cannot be used to find out who is subscribed
+	const neutral = "flash.check_email"
+
+	email, ok := app.checkEmailForm(w, r, "subscribe", neutral)
+	if !ok {
+		return
+	}
+
+	sub, err := app.db.GetSubscriberByEmail(r.Context(), email)
+	if err != nil && !errors.Is(err, models.ErrNoRecord) {
+		app.session.Put(r.Context(), "error", "flash.server_error")
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return
+	}
+
+	if !app.allow(w, r, app.limiters.global, "confirmation") {
+		return
+	}
+
+	switch {
+	case sub == nil:
+		confirmToken, err := app.db.AddSubscriber(r.Context(), email, app.locale(r), app.consent(r))
+		if err != nil {
+			app.session.Put(r.Context(), "error", "flash.subscribe_failed")
+			http.Redirect(w, r, "/", http.StatusSeeOther)
+			return
+		}
+		app.sendConfirmation(r.Context(), email, confirmToken, app.locale(r))
+	case !sub.Confirmed:
+		app.resendConfirmation(r.Context(), sub)
+	default:
+		app.sendManageLink(r.Context(), sub)
+	}
+
+	app.session.Put(r.Context(), "success", neutral)
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
+
+func (app *application) PostManage(w http.ResponseWriter, r *http.Request) {
+	const neutral = "flash.manage_sent"
+
+	email, ok := app.checkEmailForm(w, r, "manage", neutral)
+	if !ok {
+		return
+	}
+
+	sub, err := app.db.GetSubscriberByEmail(r.Context(), email)
+	if err != nil && !errors.Is(err, models.ErrNoRecord) {
+		app.session.Put(r.Context(), "error", "flash.server_error")
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return
+	}
+
+	if sub != nil {
+		if !app.allow(w, r, app.limiters.global, "confirmation") {
+			return
+		}
+		if sub.Confirmed {
+			app.sendManageLink(r.Context(), sub)
+		} else {
+			app.resendConfirmation(r.Context(), sub)
+		}
+	}
+
+	app.session.Put(r.Context(), "success", neutral)
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
+
+// checkEmailForm runs the anti-abuse checks shared by every form that makes
+// us send an email, and returns the validated address from field. When it
+// returns false a response has already been written.
+func (app *application) checkEmailForm(w http.ResponseWriter, r *http.Request, field, neutral string) (string, bool) {
+	err := r.ParseForm()
+	if err != nil {
+		app.session.Put(r.Context(), "error", "flash.form_error")
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return "", false
+	}
+
+	// honeypot: bots fill hidden fields, real users don't
+	if r.PostForm.Get("website") != "" {
+		app.abuse.trip()
+		app.session.Put(r.Context(), "success", neutral)
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return "", false
+	}
+
+	app.abuse.attempt(r.Context())
+	if !app.allow(w, r, app.limiters.ip, app.realIP(r)) {
+		app.abuse.trip()
+		return "", false
+	}
+
+	if app.verifier != nil && app.abuse.active() {
+		if err := app.verifier.Verify(r.PostForm.Get("altcha")); err != nil {
+			app.session.Put(r.Context(), "error", "flash.challenge_failed")
+			http.Redirect(w, r, "/", http.StatusSeeOther)
+			return "", false
+		}
+	}
+
+	email := r.PostForm.Get(field)
+
+	if !Match(email, EmailRegex) {
+		app.session.Put(r.Context(), "error", "flash.invalid_email")
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return "", false
+	}
+
+	if !app.allow(w, r, app.limiters.email, emailKey(email)) {
+		return "", false
+	}
+
+	return email, true
+}
+
+// resendConfirmation re-issues the confirmation token of a pending address
+// unless one was sent within the cooldown.
+func (app *application) resendConfirmation(ctx context.Context, sub *models.Subscriber) {
+	confirmToken, err := app.db.RenewConfirmationToken(ctx, sub.Email, app.confirmCooldown)
+	if errors.Is(err, models.ErrCooldown) {
+		return
+	}
+	if err != nil {
+		app.logger.Error("renewing confirmation token failed", logging.SubscriberID(sub.ID), logging.Err(err))
+		return
+	}
+	app.sendConfirmation(ctx, sub.Email, confirmToken, sub.Locale)
+}
+
+func (app *application) sendConfirmation(ctx context.Context, email, confirmToken, locale string) {
+	if app.emailService == nil {
+		return
+	}
+	go func() {
+		ctx, cancel := detach(ctx)
+		defer cancel()
+		if err := app.emailService.SendConfirmationEmail(ctx, email, confirmToken, locale); err != nil {
+			app.logger.Error("sending confirmation email failed", logging.Email(email), logging.Err(err))
+		}
+	}()
+}
+
+func (app *application) sendManageLink(ctx context.Context, sub *models.Subscriber) {
+	if app.emailService == nil {
+		return
+	}
+	token := models.SignToken(app.tokenKey, "manage", sub.ID, time.Now().Add(24*time.Hour))
+	go func() {
+		ctx, cancel := detach(ctx)
+		defer cancel()
+		if err := app.emailService.SendManageLink(ctx, sub.Email, token, sub.Locale); err != nil {
+			app.logger.Error("sending manage link failed", logging.SubscriberID(sub.ID), logging.Err(err))
+		}
+	}()
+}
+
+func (app *application) confirmSubscriber(w http.ResponseWriter, r *http.Request) {
+	token := r.URL.Query().Get("token")
+	if token == "" {
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+
+	err := app.db.ConfirmSubscriber(r.Context(), token, app.consent(r))
+	if err != nil {
+		app.logger.Warn("confirmation failed", logging.Err(err))
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+
+	app.renderMessage(w, r, app.locale(r), "confirm.title", "confirm.success")
+}
+
+// renderMessage shows a single translated message with a link back home.
+func (app *application) renderMessage(w http.ResponseWriter, r *http.Request, locale, titleKey, messageKey string) {
+	data := struct {
+		Locale  string
+		Title   string
+		Success string
+	}{
+		Locale:  locale,
+		Title:   titleKey,
+		Success: messageKey,
+	}
+
+	app.render(w, r, http.StatusOK, "unsubscribe.html", data)
+}
+
+// allow consumes one attempt from l. When the attempt is refused it flashes
+// the error and redirects, so the caller only has to return.
+func (app *application) allow(w http.ResponseWriter, r *http.Request, l ratelimit.Limiter, key string) bool {
+	ok, err := l.Allow(r.Context(), key)
+	if err != nil {
+		app.logger.Error("rate limit check failed", logging.Err(err))
+		app.session.Put(r.Context(), "error", "flash.server_error")
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return false
+	}
+	if !ok {
+		app.session.Put(r.Context(), "error", "flash.rate_limited")
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return false
+	}
+	return true
+}
+
+var EmailRegex = regexp.MustCompile(`^[a-zA-Z0-9.!#$%&'*+/=?^_` + "`" + `{|}~-]+@[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?(?:\.[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?)*$`)
+
+func Match(value string, rx *regexp.Regexp) bool {
+	if value == "" {
+		return false
+	}
+	return rx.MatchString(value)
+}
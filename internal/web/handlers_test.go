@@ -0,0 +1,89 @@
+package web
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alexedwards/scs/v2"
+	"github.com/paluras/product-recall-system/internal/challenge"
+	"github.com/paluras/product-recall-system/internal/ratelimit"
+)
+
+// postSubscriber posts form to PostSubscriber and returns the status and the
+// error flash it left in the session.
+func postSubscriber(app *application, form url.Values) (int, string) {
+	var flash string
+	h := app.session.LoadAndSave(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		app.PostSubscriber(w, r)
+		flash = app.session.GetString(r.Context(), "error")
+	}))
+
+	r := httptest.NewRequest("POST", "/subscribe", strings.NewReader(form.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	r.RemoteAddr = "203.0.113.5:1234"
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w.Code, flash
+}
+
+func TestPostSubscriberChallenge(t *testing.T) {
+	unlimited := ratelimit.Rule{Limit: 100, Window: time.Hour}
+	// the email limiter refuses everything, so a request that gets past the
+	// challenge stops there instead of reaching the database
+	refused := ratelimit.Rule{Limit: 0, Window: time.Hour}
+
+	for _, tt := range []struct {
+		name     string
+		mode     string
+		verifier challenge.Verifier
+		email    string
+		altcha   string
+		want     string
+	}{
+		{"wrong answer", "always", challenge.Stub{Answer: "42"}, "a@example.org", "41", "flash.challenge_failed"},
+		{"no answer", "always", challenge.Stub{Answer: "42"}, "a@example.org", "", "flash.challenge_failed"},
+		{"right answer", "always", challenge.Stub{Answer: "42"}, "a@example.org", "42", "flash.rate_limited"},
+		{"right answer, invalid address", "always", challenge.Stub{Answer: "42"}, "not-an-address", "42", "flash.invalid_email"},
+		{"not tripped", "auto", challenge.Stub{Answer: "42"}, "a@example.org", "", "flash.rate_limited"},
+		{"challenge off", "off", nil, "a@example.org", "", "flash.rate_limited"},
+	} {
+		app := &application{
+			session: scs.New(),
+			logger:  slog.New(slog.NewTextHandler(io.Discard, nil)),
+			limiters: limiters{
+				ip:     ratelimit.NewMemory(unlimited),
+				email:  ratelimit.NewMemory(refused),
+				global: ratelimit.NewMemory(unlimited),
+			},
+			verifier: tt.verifier,
+			abuse:    newAbuseDetector(tt.mode, 100),
+		}
+
+		code, flash := postSubscriber(app, url.Values{"subscribe": {tt.email}, "altcha": {tt.altcha}})
+		if code != http.StatusSeeOther || flash != tt.want {
+			t.Errorf("%s: status %d, flash %q, want %d, %q", tt.name, code, flash, http.StatusSeeOther, tt.want)
+		}
+	}
+}
+
+func TestPostSubscriberHoneypot(t *testing.T) {
+	app := &application{
+		session: scs.New(),
+		logger:  slog.New(slog.NewTextHandler(io.Discard, nil)),
+		abuse:   newAbuseDetector("auto", 100),
+	}
+
+	code, flash := postSubscriber(app, url.Values{"subscribe": {"a@example.org"}, "website": {"spam"}})
+	if code != http.StatusSeeOther || flash != "" {
+		t.Errorf("status %d, flash %q, want a neutral redirect", code, flash)
+	}
+	if !app.abuse.active() {
+		t.Error("honeypot did not switch the challenge on")
+	}
+}
@@ -0,0 +1,28 @@
+package web
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/paluras/product-recall-system/internal/logging"
+)
+
+// sendTimeout bounds emails that are sent after the response.
+const sendTimeout = time.Minute
+
+func (app *application) serverError(w http.ResponseWriter, r *http.Request, err error) {
+	var (
+		method = r.Method
+		uri    = r.URL.RequestURI()
+	)
+
+	app.logger.Error("server error", logging.Err(err), "method", method, "uri", uri)
+	http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+}
+
+// detach returns a context for work that outlives the request, such as
+// sending an email, keeping its values but not its cancellation.
+func detach(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.WithoutCancel(ctx), sendTimeout)
+}
@@ -0,0 +1,22 @@
+package web
+
+import "net/http"
+
+// locale returns the language chosen with ?lang= earlier in the session, or
+// the best match for the browser's Accept-Language header.
+func (app *application) locale(r *http.Request) string {
+	if locale := app.session.GetString(r.Context(), "locale"); app.catalog.Supported(locale) {
+		return locale
+	}
+	return app.catalog.Negotiate(r.Header.Get("Accept-Language"))
+}
+
+// rememberLocale stores an explicit ?lang= choice in the session.
+func (app *application) rememberLocale(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if lang := r.URL.Query().Get("lang"); app.catalog.Supported(lang) {
+			app.session.Put(r.Context(), "locale", lang)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,47 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alexedwards/scs/v2"
+	"github.com/paluras/product-recall-system/internal/i18n"
+)
+
+func TestLocale(t *testing.T) {
+	catalog, err := i18n.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct {
+		name   string
+		lang   string
+		header string
+		want   string
+	}{
+		{"no preference", "", "", "ro"},
+		{"from the browser", "", "en-US,en;q=0.9", "en"},
+		{"region falls back to the language", "", "hu-HU", "hu"},
+		{"unknown language", "", "fr-FR", "ro"},
+		{"?lang= over the browser", "hu", "en", "hu"},
+		{"unsupported ?lang= ignored", "xx", "en", "en"},
+	} {
+		app := &application{session: scs.New(), catalog: catalog}
+
+		var got string
+		h := app.session.LoadAndSave(app.rememberLocale(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			got = app.locale(r)
+		})))
+
+		r := httptest.NewRequest("GET", "/?lang="+tt.lang, nil)
+		if tt.header != "" {
+			r.Header.Set("Accept-Language", tt.header)
+		}
+		h.ServeHTTP(httptest.NewRecorder(), r)
+		if got != tt.want {
+			t.Errorf("%s: locale = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
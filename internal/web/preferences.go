@@ -0,0 +1,283 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"slices"
+	"time"
+
+	"github.com/paluras/product-recall-system/internal/classify"
+	"github.com/paluras/product-recall-system/internal/logging"
+	"github.com/paluras/product-recall-system/internal/models"
+	"github.com/paluras/product-recall-system/internal/ratelimit"
+)
+
+var pauseOptions = map[string]time.Duration{
+	"1w": 7 * 24 * time.Hour,
+	"1m": 30 * 24 * time.Hour,
+	"3m": 90 * 24 * time.Hour,
+}
+
+type allergenOption struct {
+	Slug    string
+	Checked bool
+}
+
+// subscriberFromToken resolves the signed token of a magic link. When it
+// returns nil a response has already been written.
+func (app *application) subscriberFromToken(w http.ResponseWriter, r *http.Request, token string) *models.Subscriber {
+	id, err := models.VerifyToken(app.tokenKey, "manage", token)
+	if err != nil {
+		http.Error(w, app.catalog.T(app.locale(r), "link.invalid"), http.StatusBadRequest)
+		return nil
+	}
+
+	sub, err := app.db.GetSubscriber(r.Context(), id)
+	if errors.Is(err, models.ErrNoRecord) {
+		http.Error(w, app.catalog.T(app.locale(r), "link.invalid"), http.StatusBadRequest)
+		return nil
+	}
+	if err != nil {
+		app.serverError(w, r, err)
+		return nil
+	}
+	return sub
+}
+
+func (app *application) preferences(w http.ResponseWriter, r *http.Request) {
+	token := r.URL.Query().Get("token")
+	sub := app.subscriberFromToken(w, r, token)
+	if sub == nil {
+		return
+	}
+
+	unsubscribeToken, err := app.db.UnsubscribeToken(r.Context(), sub.Email)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	allergens := make([]allergenOption, len(classify.Allergens))
+	for i, slug := range classify.Allergens {
+		allergens[i] = allergenOption{Slug: slug, Checked: slices.Contains(sub.Allergens, slug)}
+	}
+
+	data := struct {
+		Locale           string
+		Subscriber       *models.Subscriber
+		Token            string
+		UnsubscribeToken string
+		Locales          []string
+		Allergens        []allergenOption
+		Error            string
+		Success          string
+	}{
+		Locale:           sub.Locale,
+		Subscriber:       sub,
+		Token:            token,
+		UnsubscribeToken: unsubscribeToken,
+		Locales:          app.catalog.Locales(),
+		Allergens:        allergens,
+		Error:            app.session.PopString(r.Context(), "error"),
+		Success:          app.session.PopString(r.Context(), "success"),
+	}
+
+	app.render(w, r, http.StatusOK, "preferences.html", data)
+}
+
+func (app *application) PostPreferences(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+
+	token := r.PostForm.Get("token")
+	sub := app.subscriberFromToken(w, r, token)
+	if sub == nil {
+		return
+	}
+	back := "/preferences?token=" + url.QueryEscape(token)
+
+	switch r.PostForm.Get("action") {
+	case "pause":
+		d, ok := pauseOptions[r.PostForm.Get("period")]
+		if !ok {
+			app.session.Put(r.Context(), "error", "flash.invalid_period")
+			break
+		}
+		if err := app.db.PauseSubscriber(r.Context(), sub.ID, time.Now().Add(d)); err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+		app.session.Put(r.Context(), "success", "flash.paused")
+
+	case "resume":
+		if err := app.db.PauseSubscriber(r.Context(), sub.ID, time.Time{}); err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+		app.session.Put(r.Context(), "success", "flash.resumed")
+
+	case "locale":
+		locale := r.PostForm.Get("locale")
+		if !app.catalog.Supported(locale) {
+			app.session.Put(r.Context(), "error", "flash.invalid_locale")
+			break
+		}
+		if err := app.db.SetSubscriberLocale(r.Context(), sub.ID, locale); err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+		app.session.Put(r.Context(), "success", "flash.locale_saved")
+
+	case "allergens":
+		var allergens []string
+		for _, slug := range r.PostForm["allergen"] {
+			if !slices.Contains(classify.Allergens, slug) {
+				app.session.Put(r.Context(), "error", "flash.invalid_allergen")
+				http.Redirect(w, r, back, http.StatusSeeOther)
+				return
+			}
+			allergens = append(allergens, slug)
+		}
+		only := r.PostForm.Get("only") == "on"
+		if err := app.db.SetSubscriberAllergens(r.Context(), sub.ID, allergens, only); err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+		app.session.Put(r.Context(), "success", "flash.allergens_saved")
+
+	case "email":
+		app.changeEmail(w, r, sub)
+
+	case "export":
+		app.exportData(r, sub)
+
+	case "delete":
+		if err := app.db.EraseSubscriber(r.Context(), sub.ID, "subscriber"); err != nil && !errors.Is(err, models.ErrNoRecord) {
+			app.serverError(w, r, err)
+			return
+		}
+		app.renderMessage(w, r, sub.Locale, "erase.title", "erase.success")
+		return
+
+	default:
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+
+	http.Redirect(w, r, back, http.StatusSeeOther)
+}
+
+// changeEmail sends a confirmation to the new address; the old one stays in
+// use until that link is followed.
+func (app *application) changeEmail(w http.ResponseWriter, r *http.Request, sub *models.Subscriber) {
+	email := r.PostForm.Get("email")
+	if !Match(email, EmailRegex) {
+		app.session.Put(r.Context(), "error", "flash.invalid_email")
+		return
+	}
+
+	if app.limited(r, app.limiters.email, emailKey(email)) || app.limited(r, app.limiters.global, "confirmation") {
+		app.session.Put(r.Context(), "error", "flash.rate_limited")
+		return
+	}
+
+	const neutral = "flash.email_change_sent"
+
+	// an address that is already subscribed gets no email, but the
+	// answer is the same so the form does not reveal it
+	if _, err := app.db.GetSubscriberByEmail(r.Context(), email); !errors.Is(err, models.ErrNoRecord) {
+		app.session.Put(r.Context(), "success", neutral)
+		return
+	}
+
+	changeToken, err := app.db.RequestEmailChange(r.Context(), sub.ID, email)
+	if err != nil {
+		app.logger.Error("requesting email change failed", logging.SubscriberID(sub.ID), logging.Err(err))
+		app.session.Put(r.Context(), "error", "flash.server_error")
+		return
+	}
+
+	if app.emailService != nil {
+		go func() {
+			ctx, cancel := detach(r.Context())
+			defer cancel()
+			if err := app.emailService.SendEmailChangeConfirmation(ctx, email, changeToken, sub.Locale); err != nil {
+				app.logger.Error("sending email change confirmation failed", logging.SubscriberID(sub.ID), logging.Err(err))
+			}
+		}()
+	}
+	app.session.Put(r.Context(), "success", neutral)
+}
+
+func (app *application) confirmEmailChange(w http.ResponseWriter, r *http.Request) {
+	token := r.URL.Query().Get("token")
+	if token == "" {
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+
+	err := app.db.ConfirmEmailChange(r.Context(), token)
+	if err != nil {
+		app.logger.Warn("email change failed", logging.Err(err))
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+
+	app.renderMessage(w, r, app.locale(r), "email_change.title", "email_change.success")
+}
+
+func (app *application) limited(r *http.Request, l ratelimit.Limiter, key string) bool {
+	ok, err := l.Allow(r.Context(), key)
+	if err != nil {
+		app.logger.Error("rate limit check failed", logging.Err(err))
+		return true
+	}
+	return !ok
+}
+
+// manage keeps links sent before the preferences page existed working.
+func (app *application) manage(w http.ResponseWriter, r *http.Request) {
+	http.Redirect(w, r, "/preferences?"+r.URL.RawQuery, http.StatusMovedPermanently)
+}
+
+// exportData emails the subscriber a JSON copy of everything we hold about
+// them.
+func (app *application) exportData(r *http.Request, sub *models.Subscriber) {
+	if app.limited(r, app.limiters.email, emailKey(sub.Email)) {
+		app.session.Put(r.Context(), "error", "flash.rate_limited")
+		return
+	}
+
+	export, err := app.db.ExportSubscriber(r.Context(), sub.ID)
+	if err != nil {
+		app.logger.Error("exporting subscriber failed", logging.SubscriberID(sub.ID), logging.Err(err))
+		app.session.Put(r.Context(), "error", "flash.server_error")
+		return
+	}
+	data, err := json.MarshalIndent(export, "", "  ")
+	if err != nil {
+		app.logger.Error("encoding export failed", logging.SubscriberID(sub.ID), logging.Err(err))
+		app.session.Put(r.Context(), "error", "flash.server_error")
+		return
+	}
+
+	if err := app.db.RecordAudit(r.Context(), "export", sub.ID, "subscriber"); err != nil {
+		app.logger.Error("recording audit entry failed", logging.SubscriberID(sub.ID), logging.Err(err))
+	}
+
+	if app.emailService != nil {
+		go func() {
+			ctx, cancel := detach(r.Context())
+			defer cancel()
+			if err := app.emailService.SendDataExport(ctx, sub.Email, sub.Locale, data); err != nil {
+				app.logger.Error("sending data export failed", logging.SubscriberID(sub.ID), logging.Err(err))
+			}
+		}()
+	}
+	app.session.Put(r.Context(), "success", "flash.export_sent")
+}
@@ -0,0 +1,115 @@
+package web
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+
+	"github.com/paluras/product-recall-system/internal/models"
+	"github.com/paluras/product-recall-system/internal/notify"
+	"github.com/paluras/product-recall-system/ui"
+)
+
+// serviceWorker serves the worker that shows push notifications. It has to
+// come from the root for its scope to cover the whole site.
+func (app *application) serviceWorker(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/javascript; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-cache")
+	http.ServeFileFS(w, r, ui.Files, "static/sw.js")
+}
+
+// pushRequest is the JSON form of a browser's PushSubscription.
+type pushRequest struct {
+	Endpoint string `json:"endpoint"`
+	Keys     struct {
+		P256dh string `json:"p256dh"`
+		Auth   string `json:"auth"`
+	} `json:"keys"`
+}
+
+// decodePush reads a subscription from a JSON request. Requiring the JSON
+// content type keeps other sites from posting it with a plain form.
+func (app *application) decodePush(w http.ResponseWriter, r *http.Request) (*pushRequest, bool) {
+	if app.vapidPublicKey == "" {
+		http.NotFound(w, r)
+		return nil, false
+	}
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType != "application/json" {
+		app.writeJSON(w, r, http.StatusUnsupportedMediaType, map[string]string{"error": "expected application/json"})
+		return nil, false
+	}
+
+	var req pushRequest
+	if err := json.NewDecoder(io.LimitReader(r.Body, 8<<10)).Decode(&req); err != nil {
+		app.writeJSON(w, r, http.StatusBadRequest, map[string]string{"error": "invalid JSON"})
+		return nil, false
+	}
+	u, err := url.Parse(req.Endpoint)
+	if err != nil || u.Scheme != "https" || u.Host == "" || len(req.Endpoint) > 2048 {
+		app.writeJSON(w, r, http.StatusBadRequest, map[string]string{"error": "endpoint must be an https URL"})
+		return nil, false
+	}
+	return &req, true
+}
+
+func (app *application) pushSubscribe(w http.ResponseWriter, r *http.Request) {
+	req, ok := app.decodePush(w, r)
+	if !ok {
+		return
+	}
+	p256dh, err := base64.RawURLEncoding.DecodeString(req.Keys.P256dh)
+	validKey := err == nil && len(p256dh) == 65
+	auth, err := base64.RawURLEncoding.DecodeString(req.Keys.Auth)
+	if !validKey || err != nil || len(auth) != 16 {
+		app.writeJSON(w, r, http.StatusBadRequest, map[string]string{"error": "invalid subscription keys"})
+		return
+	}
+
+	allowed, err := app.limiters.ip.Allow(r.Context(), app.realIP(r))
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	if !allowed {
+		app.writeJSON(w, r, http.StatusTooManyRequests, map[string]string{"error": app.catalog.T(app.locale(r), "flash.rate_limited")})
+		return
+	}
+	// resolved after the rate limit, so the form cannot be used to make us
+	// look up names in bulk
+	if err := notify.CheckPushEndpoint(r.Context(), req.Endpoint); err != nil {
+		app.writeJSON(w, r, http.StatusBadRequest, map[string]string{"error": notify.ErrEndpointNotAllowed.Error()})
+		return
+	}
+
+	id, err := app.db.AddPushSubscription(r.Context(), models.PushSubscription{
+		Endpoint: req.Endpoint,
+		P256dh:   req.Keys.P256dh,
+		Auth:     req.Keys.Auth,
+		Locale:   app.locale(r),
+	})
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	app.logger.Info("push subscription added", "channel_id", id)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (app *application) pushUnsubscribe(w http.ResponseWriter, r *http.Request) {
+	req, ok := app.decodePush(w, r)
+	if !ok {
+		return
+	}
+
+	err := app.db.RemovePushSubscription(r.Context(), req.Endpoint)
+	if err != nil && !errors.Is(err, models.ErrNoRecord) {
+		app.serverError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
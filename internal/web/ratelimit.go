@@ -0,0 +1,124 @@
+package web
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/paluras/product-recall-system/configs"
+	"github.com/paluras/product-recall-system/internal/logging"
+	"github.com/paluras/product-recall-system/internal/models"
+	"github.com/paluras/product-recall-system/internal/ratelimit"
+)
+
+type limiters struct {
+	ip     ratelimit.Limiter
+	email  ratelimit.Limiter
+	global ratelimit.Limiter
+}
+
+func newLimiters(conf *configs.Config, db *models.DB, logger *slog.Logger) (limiters, error) {
+	ipRule := ratelimit.Rule{
+		Limit:    conf.IPLimit,
+		Window:   time.Hour,
+		BanAfter: conf.IPBanAfter,
+		BanFor:   24 * time.Hour,
+	}
+	emailRule := ratelimit.Rule{Limit: conf.EmailLimit, Window: 24 * time.Hour}
+	globalRule := ratelimit.Rule{Limit: conf.GlobalLimit, Window: time.Hour}
+
+	switch conf.RateLimitStore {
+	case "memory":
+		return limiters{
+			ip:     ratelimit.NewMemory(ipRule),
+			email:  ratelimit.NewMemory(emailRule),
+			global: ratelimit.NewMemory(globalRule),
+		}, nil
+	case "db":
+		go pruneRateLimits(db, logger)
+		return limiters{
+			ip:     ratelimit.NewPersistent(db, "ip", ipRule),
+			email:  ratelimit.NewPersistent(db, "email", emailRule),
+			global: ratelimit.NewPersistent(db, "global", globalRule),
+		}, nil
+	default:
+		return limiters{}, fmt.Errorf("unknown rate limit store %q", conf.RateLimitStore)
+	}
+}
+
+func pruneRateLimits(db *models.DB, logger *slog.Logger) {
+	ticker := time.NewTicker(time.Hour)
+	for range ticker.C {
+		if err := db.DeleteExpiredRateLimits(context.Background(), time.Now().UTC()); err != nil {
+			logger.Error("pruning rate limits failed", logging.Err(err))
+		}
+	}
+}
+
+// emailKey keeps plain addresses out of the rate limit table.
+func emailKey(email string) string {
+	sum := sha256.Sum256([]byte(strings.ToLower(strings.TrimSpace(email))))
+	return hex.EncodeToString(sum[:])
+}
+
+func parseTrustedProxies(s string) ([]*net.IPNet, error) {
+	var nets []*net.IPNet
+	for _, cidr := range strings.Split(s, ",") {
+		cidr = strings.TrimSpace(cidr)
+		if cidr == "" {
+			continue
+		}
+		_, n, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q: %w", cidr, err)
+		}
+		nets = append(nets, n)
+	}
+	return nets, nil
+}
+
+func (app *application) trusted(ip string) bool {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return false
+	}
+	for _, n := range app.trustedProxies {
+		if n.Contains(parsed) {
+			return true
+		}
+	}
+	return false
+}
+
+// realIP only honours forwarding headers when the direct peer is a trusted
+// proxy. X-Forwarded-For is walked right to left so a client cannot prepend a
+// spoofed address.
+func (app *application) realIP(r *http.Request) string {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		ip = r.RemoteAddr
+	}
+	if !app.trusted(ip) {
+		return ip
+	}
+
+	if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
+		hops := strings.Split(xff, ",")
+		for i := len(hops) - 1; i >= 0; i-- {
+			hop := strings.TrimSpace(hops[i])
+			if !app.trusted(hop) {
+				return hop
+			}
+		}
+	}
+	if xrip := r.Header.Get("X-Real-IP"); xrip != "" {
+		return xrip
+	}
+	return ip
+}
@@ -0,0 +1,52 @@
+package web
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRealIP(t *testing.T) {
+	proxies, err := parseTrustedProxies("10.0.0.0/8, 192.168.1.1/32")
+	if err != nil {
+		t.Fatal(err)
+	}
+	app := &application{trustedProxies: proxies}
+
+	for _, tt := range []struct {
+		name   string
+		remote string
+		xff    string
+		xrip   string
+		want   string
+	}{
+		{"direct", "203.0.113.5:1234", "", "", "203.0.113.5"},
+		{"spoofed by untrusted peer", "203.0.113.5:1234", "1.2.3.4", "", "203.0.113.5"},
+		{"spoofed X-Real-IP by untrusted peer", "203.0.113.5:1234", "", "1.2.3.4", "203.0.113.5"},
+		{"trusted proxy", "10.0.0.2:1234", "198.51.100.7", "", "198.51.100.7"},
+		{"client prepends a spoofed hop", "10.0.0.2:1234", "1.2.3.4, 198.51.100.7", "", "198.51.100.7"},
+		{"chain of trusted proxies", "10.0.0.2:1234", "198.51.100.7, 192.168.1.1, 10.0.0.3", "", "198.51.100.7"},
+		{"trusted proxy with X-Real-IP", "10.0.0.2:1234", "", "198.51.100.7", "198.51.100.7"},
+		{"trusted proxy without headers", "10.0.0.2:1234", "", "", "10.0.0.2"},
+	} {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.RemoteAddr = tt.remote
+		if tt.xff != "" {
+			r.Header.Set("X-Forwarded-For", tt.xff)
+		}
+		if tt.xrip != "" {
+			r.Header.Set("X-Real-IP", tt.xrip)
+		}
+		if got := app.realIP(r); got != tt.want {
+			t.Errorf("%s: realIP = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestParseTrustedProxies(t *testing.T) {
+	if nets, err := parseTrustedProxies(""); err != nil || len(nets) != 0 {
+		t.Errorf("empty list = %v, %v", nets, err)
+	}
+	if _, err := parseTrustedProxies("10.0.0.1"); err == nil {
+		t.Error("address without a prefix length accepted")
+	}
+}
@@ -0,0 +1,55 @@
+package web
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/paluras/product-recall-system/internal/models"
+)
+
+// recall shows one recall with the article text and the text extracted from
+// its PDF attachments.
+func (app *application) recall(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id < 1 {
+		http.NotFound(w, r)
+		return
+	}
+
+	item, err := app.db.GetItem(r.Context(), id)
+	if errors.Is(err, models.ErrNoRecord) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	attachments, err := app.db.GetAttachments(r.Context(), id)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	revisions, err := app.db.GetRevisions(r.Context(), id)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	data := struct {
+		Locale      string
+		Item        *models.ScrapedItem
+		Attachments []models.Attachment
+		Revisions   []models.Revision
+	}{
+		Locale:      app.locale(r),
+		Item:        item,
+		Attachments: attachments,
+		Revisions:   revisions,
+	}
+
+	app.render(w, r, http.StatusOK, "recall.html", data)
+}
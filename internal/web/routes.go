@@ -0,0 +1,25 @@
+package web
+
+import "net/http"
+
+func (app *application) routes() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /", app.home)
+	mux.HandleFunc("POST /subscribe", app.PostSubscriber)
+	mux.HandleFunc("GET /unsubscribe", app.unsubscribe)
+	mux.HandleFunc("GET /confirm", app.confirmSubscriber)
+	mux.HandleFunc("POST /manage", app.PostManage)
+	mux.HandleFunc("GET /manage", app.manage)
+	mux.HandleFunc("GET /preferences", app.preferences)
+	mux.HandleFunc("POST /preferences", app.PostPreferences)
+	mux.HandleFunc("GET /preferences/email", app.confirmEmailChange)
+	mux.HandleFunc("GET /recalls/{id}", app.recall)
+	mux.HandleFunc("GET /check", app.check)
+	mux.HandleFunc("GET /api/items", app.apiItems)
+	mux.HandleFunc("GET /api/check", app.apiCheck)
+	mux.HandleFunc("GET /sw.js", app.serviceWorker)
+	mux.HandleFunc("POST /push/subscribe", app.pushSubscribe)
+	mux.HandleFunc("POST /push/unsubscribe", app.pushUnsubscribe)
+
+	return app.rememberLocale(mux)
+}
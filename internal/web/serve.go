@@ -0,0 +1,39 @@
+package web
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+func (app *application) serve() error {
+	srv := &http.Server{
+		Addr:    app.addr,
+		Handler: app.session.LoadAndSave(app.routes()),
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	shutdownErr := make(chan error, 1)
+	go func() {
+		<-ctx.Done()
+		app.logger.Info("shutting down server")
+		// in-flight requests get a little time to finish; their contexts
+		// are cancelled when it runs out
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		shutdownErr <- srv.Shutdown(shutdownCtx)
+	}()
+
+	app.logger.Info("starting server", "addr", srv.Addr)
+	err := srv.ListenAndServe()
+	if !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return <-shutdownErr
+}
@@ -0,0 +1,67 @@
+package web
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+
+	"github.com/paluras/product-recall-system/internal/i18n"
+)
+
+// templateCache holds every page composed with the base layout and the
+// shared partials, keyed by the file name of the page, e.g. "home.html".
+type templateCache map[string]*template.Template
+
+// newTemplateCache parses the pages of fsys, which holds the ui directory.
+func newTemplateCache(fsys fs.FS, catalog *i18n.Catalog) (templateCache, error) {
+	pages, err := fs.Glob(fsys, "html/pages/*.html")
+	if err != nil {
+		return nil, err
+	}
+
+	cache := templateCache{}
+	for _, page := range pages {
+		name := path.Base(page)
+		ts, err := template.New(name).Funcs(template.FuncMap{"t": catalog.T}).
+			ParseFS(fsys, "html/layouts/base.html", "html/partials/*.html", page)
+		if err != nil {
+			return nil, err
+		}
+		cache[name] = ts
+	}
+	return cache, nil
+}
+
+// render executes a page into a buffer before writing it, so a template
+// that fails halfway gives a 500 rather than half a page. In dev mode the
+// templates are parsed again from ./ui on every request.
+func (app *application) render(w http.ResponseWriter, r *http.Request, status int, page string, data any) {
+	templates := app.templates
+	if app.dev {
+		var err error
+		if templates, err = newTemplateCache(os.DirFS("ui"), app.catalog); err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+	}
+
+	ts, ok := templates[page]
+	if !ok {
+		app.serverError(w, r, fmt.Errorf("template %s does not exist", page))
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := ts.ExecuteTemplate(&buf, "base", data); err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	buf.WriteTo(w)
+}
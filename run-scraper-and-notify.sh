@@ -1,3 +1,5 @@
 #!/bin/bash
-/app/scraper -dbuser "$DB_USER" -dbpass "$DB_PASSWORD" -dbhost "$DB_HOST" -dbport "$DB_PORT" -dbname "$DB_NAME"
-/app/notify -dbuser "$DB_USER" -dbpass "$DB_PASSWORD" -dbhost "$DB_HOST" -dbport "$DB_PORT" -dbname "$DB_NAME"
+# settings come from the environment, see docker/cron-entrypoint.sh
+/app/recall migrate || exit 1
+/app/recall scrape
+/app/recall notify
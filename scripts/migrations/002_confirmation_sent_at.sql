@@ -0,0 +1 @@
+ALTER TABLE subscribers ADD COLUMN confirmation_sent_at DATETIME;
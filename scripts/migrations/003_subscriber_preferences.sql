@@ -0,0 +1,5 @@
+ALTER TABLE subscribers
+    ADD COLUMN paused_until DATETIME,
+    ADD COLUMN locale VARCHAR(8) NOT NULL DEFAULT 'ro',
+    ADD COLUMN pending_email VARCHAR(255),
+    ADD COLUMN email_change_token VARCHAR(64);
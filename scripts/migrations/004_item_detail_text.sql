@@ -0,0 +1 @@
+ALTER TABLE scraped_items ADD COLUMN detail_text TEXT;
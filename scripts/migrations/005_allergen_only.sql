@@ -0,0 +1 @@
+ALTER TABLE subscribers ADD COLUMN allergen_only BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS item_codes (
+    item_id INT NOT NULL,
+    kind VARCHAR(8) NOT NULL,
+    code VARCHAR(64) NOT NULL,
+    PRIMARY KEY (item_id, kind, code),
+    INDEX (kind, code),
+    FOREIGN KEY (item_id) REFERENCES scraped_items(id) ON DELETE CASCADE
+);
@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS item_attachments (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    item_id INT NOT NULL,
+    url VARCHAR(500) NOT NULL,
+    sha256 CHAR(64) NOT NULL,
+    size INT NOT NULL,
+    text MEDIUMTEXT NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE KEY (item_id, url),
+    INDEX (sha256),
+    FOREIGN KEY (item_id) REFERENCES scraped_items(id) ON DELETE CASCADE
+);
@@ -0,0 +1,19 @@
+ALTER TABLE scraped_items
+    ADD COLUMN content_hash CHAR(64),
+    ADD COLUMN last_seen_at DATETIME,
+    ADD COLUMN withdrawn_at DATETIME;
+
+CREATE TABLE IF NOT EXISTS item_revisions (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    item_id INT NOT NULL,
+    change_type VARCHAR(16) NOT NULL,
+    old_title VARCHAR(500),
+    new_title VARCHAR(500),
+    old_date DATETIME,
+    new_date DATETIME,
+    notified BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    INDEX (item_id),
+    INDEX (notified),
+    FOREIGN KEY (item_id) REFERENCES scraped_items(id) ON DELETE CASCADE
+);
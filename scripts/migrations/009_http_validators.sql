@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS http_validators (
+    url VARCHAR(500) NOT NULL PRIMARY KEY,
+    etag VARCHAR(255) NOT NULL DEFAULT '',
+    last_modified VARCHAR(64) NOT NULL DEFAULT '',
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP
+);
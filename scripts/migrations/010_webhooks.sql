@@ -0,0 +1,22 @@
+CREATE TABLE IF NOT EXISTS webhooks (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    url VARCHAR(500) NOT NULL,
+    secret VARCHAR(64) NOT NULL,
+    failures INT NOT NULL DEFAULT 0,
+    disabled_at DATETIME,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    webhook_id INT NOT NULL,
+    item_id INT NOT NULL,
+    attempt INT NOT NULL,
+    status VARCHAR(16) NOT NULL,
+    status_code INT,
+    error VARCHAR(500),
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    INDEX (webhook_id, item_id),
+    FOREIGN KEY (webhook_id) REFERENCES webhooks(id) ON DELETE CASCADE,
+    FOREIGN KEY (item_id) REFERENCES scraped_items(id) ON DELETE CASCADE
+);
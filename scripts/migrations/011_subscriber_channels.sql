@@ -0,0 +1,27 @@
+CREATE TABLE IF NOT EXISTS subscriber_channels (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    channel VARCHAR(16) NOT NULL,
+    address VARCHAR(255) NOT NULL,
+    locale VARCHAR(8) NOT NULL DEFAULT 'ro',
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE KEY (channel, address)
+);
+
+CREATE TABLE IF NOT EXISTS channel_tags (
+    channel_id INT NOT NULL,
+    tag VARCHAR(64) NOT NULL,
+    PRIMARY KEY (channel_id, tag),
+    FOREIGN KEY (channel_id) REFERENCES subscriber_channels(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS channel_deliveries (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    channel_id INT NOT NULL,
+    item_id INT NOT NULL,
+    status VARCHAR(16) NOT NULL,
+    error VARCHAR(500),
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    INDEX (channel_id, item_id),
+    FOREIGN KEY (channel_id) REFERENCES subscriber_channels(id) ON DELETE CASCADE,
+    FOREIGN KEY (item_id) REFERENCES scraped_items(id) ON DELETE CASCADE
+);
@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS push_subscriptions (
+    channel_id INT PRIMARY KEY,
+    endpoint VARCHAR(2048) NOT NULL,
+    p256dh VARCHAR(128) NOT NULL,
+    auth VARCHAR(64) NOT NULL,
+    FOREIGN KEY (channel_id) REFERENCES subscriber_channels(id) ON DELETE CASCADE
+);
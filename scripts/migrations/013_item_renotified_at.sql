@@ -0,0 +1 @@
+ALTER TABLE scraped_items ADD COLUMN renotified_at DATETIME;
@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS rate_limits (
+    limiter_key VARCHAR(191) PRIMARY KEY,
+    count INT NOT NULL DEFAULT 0,
+    window_end DATETIME NOT NULL,
+    banned_until DATETIME
+);
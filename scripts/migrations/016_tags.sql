@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS tags (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    kind VARCHAR(32) NOT NULL,
+    slug VARCHAR(64) NOT NULL,
+    UNIQUE KEY (kind, slug)
+);
+
+CREATE TABLE IF NOT EXISTS item_tags (
+    item_id INT NOT NULL,
+    tag_id INT NOT NULL,
+    PRIMARY KEY (item_id, tag_id),
+    INDEX (tag_id),
+    FOREIGN KEY (item_id) REFERENCES scraped_items(id) ON DELETE CASCADE,
+    FOREIGN KEY (tag_id) REFERENCES tags(id) ON DELETE CASCADE
+);
@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS subscriber_allergens (
+    subscriber_id INT NOT NULL,
+    allergen VARCHAR(32) NOT NULL,
+    PRIMARY KEY (subscriber_id, allergen),
+    FOREIGN KEY (subscriber_id) REFERENCES subscribers(id) ON DELETE CASCADE
+);
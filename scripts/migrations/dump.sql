@@ -4,7 +4,12 @@ CREATE TABLE IF NOT EXISTS scraped_items (
     link VARCHAR(500) NOT NULL UNIQUE,
     date DATETIME,
     created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-    notified BOOLEAN DEFAULT FALSE
+    notified BOOLEAN DEFAULT FALSE,
+    detail_text TEXT,
+    content_hash CHAR(64),
+    last_seen_at DATETIME,
+    withdrawn_at DATETIME,
+    renotified_at DATETIME
 );
 
 CREATE TABLE IF NOT EXISTS subscribers (
@@ -13,5 +18,174 @@ CREATE TABLE IF NOT EXISTS subscribers (
     created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
     unsubscribe_token VARCHAR(64),
     confirmed BOOLEAN DEFAULT FALSE,
-    confirmation_token VARCHAR(64)
+    confirmation_token VARCHAR(64),
+    confirmation_sent_at DATETIME,
+    paused_until DATETIME,
+    locale VARCHAR(8) NOT NULL DEFAULT 'ro',
+    pending_email VARCHAR(255),
+    email_change_token VARCHAR(64),
+    allergen_only BOOLEAN NOT NULL DEFAULT FALSE
+);
+
+CREATE TABLE IF NOT EXISTS rate_limits (
+    limiter_key VARCHAR(191) PRIMARY KEY,
+    count INT NOT NULL DEFAULT 0,
+    window_end DATETIME NOT NULL,
+    banned_until DATETIME
+);
+
+CREATE TABLE IF NOT EXISTS consent_records (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    subscriber_id INT NOT NULL,
+    action VARCHAR(32) NOT NULL,
+    ip_hash VARCHAR(64) NOT NULL,
+    form_version VARCHAR(32) NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (subscriber_id) REFERENCES subscribers(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS deliveries (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    subscriber_id INT NOT NULL,
+    item_id INT NOT NULL,
+    channel VARCHAR(32) NOT NULL,
+    status VARCHAR(16) NOT NULL,
+    error VARCHAR(500),
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    INDEX (item_id),
+    FOREIGN KEY (subscriber_id) REFERENCES subscribers(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS audit_log (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    action VARCHAR(32) NOT NULL,
+    subscriber_id INT NOT NULL,
+    actor VARCHAR(32) NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS tags (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    kind VARCHAR(32) NOT NULL,
+    slug VARCHAR(64) NOT NULL,
+    UNIQUE KEY (kind, slug)
+);
+
+CREATE TABLE IF NOT EXISTS item_tags (
+    item_id INT NOT NULL,
+    tag_id INT NOT NULL,
+    PRIMARY KEY (item_id, tag_id),
+    INDEX (tag_id),
+    FOREIGN KEY (item_id) REFERENCES scraped_items(id) ON DELETE CASCADE,
+    FOREIGN KEY (tag_id) REFERENCES tags(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS subscriber_allergens (
+    subscriber_id INT NOT NULL,
+    allergen VARCHAR(32) NOT NULL,
+    PRIMARY KEY (subscriber_id, allergen),
+    FOREIGN KEY (subscriber_id) REFERENCES subscribers(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS item_codes (
+    item_id INT NOT NULL,
+    kind VARCHAR(8) NOT NULL,
+    code VARCHAR(64) NOT NULL,
+    PRIMARY KEY (item_id, kind, code),
+    INDEX (kind, code),
+    FOREIGN KEY (item_id) REFERENCES scraped_items(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS item_attachments (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    item_id INT NOT NULL,
+    url VARCHAR(500) NOT NULL,
+    sha256 CHAR(64) NOT NULL,
+    size INT NOT NULL,
+    text MEDIUMTEXT NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE KEY (item_id, url),
+    INDEX (sha256),
+    FOREIGN KEY (item_id) REFERENCES scraped_items(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS item_revisions (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    item_id INT NOT NULL,
+    change_type VARCHAR(16) NOT NULL,
+    old_title VARCHAR(500),
+    new_title VARCHAR(500),
+    old_date DATETIME,
+    new_date DATETIME,
+    notified BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    INDEX (item_id),
+    INDEX (notified),
+    FOREIGN KEY (item_id) REFERENCES scraped_items(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS http_validators (
+    url VARCHAR(500) NOT NULL PRIMARY KEY,
+    etag VARCHAR(255) NOT NULL DEFAULT '',
+    last_modified VARCHAR(64) NOT NULL DEFAULT '',
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS webhooks (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    url VARCHAR(500) NOT NULL,
+    secret VARCHAR(64) NOT NULL,
+    failures INT NOT NULL DEFAULT 0,
+    disabled_at DATETIME,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    webhook_id INT NOT NULL,
+    item_id INT NOT NULL,
+    attempt INT NOT NULL,
+    status VARCHAR(16) NOT NULL,
+    status_code INT,
+    error VARCHAR(500),
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    INDEX (webhook_id, item_id),
+    FOREIGN KEY (webhook_id) REFERENCES webhooks(id) ON DELETE CASCADE,
+    FOREIGN KEY (item_id) REFERENCES scraped_items(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS subscriber_channels (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    channel VARCHAR(16) NOT NULL,
+    address VARCHAR(255) NOT NULL,
+    locale VARCHAR(8) NOT NULL DEFAULT 'ro',
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE KEY (channel, address)
+);
+
+CREATE TABLE IF NOT EXISTS channel_tags (
+    channel_id INT NOT NULL,
+    tag VARCHAR(64) NOT NULL,
+    PRIMARY KEY (channel_id, tag),
+    FOREIGN KEY (channel_id) REFERENCES subscriber_channels(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS channel_deliveries (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    channel_id INT NOT NULL,
+    item_id INT NOT NULL,
+    status VARCHAR(16) NOT NULL,
+    error VARCHAR(500),
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    INDEX (channel_id, item_id),
+    FOREIGN KEY (channel_id) REFERENCES subscriber_channels(id) ON DELETE CASCADE,
+    FOREIGN KEY (item_id) REFERENCES scraped_items(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS push_subscriptions (
+    channel_id INT PRIMARY KEY,
+    endpoint VARCHAR(2048) NOT NULL,
+    p256dh VARCHAR(128) NOT NULL,
+    auth VARCHAR(64) NOT NULL,
+    FOREIGN KEY (channel_id) REFERENCES subscriber_channels(id) ON DELETE CASCADE
 );
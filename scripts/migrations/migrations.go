@@ -0,0 +1,8 @@
+// Package migrations holds the database schema: dump.sql creates it from
+// scratch and the numbered files bring older databases up to date.
+package migrations
+
+import "embed"
+
+//go:embed *.sql
+var Files embed.FS
@@ -0,0 +1,6 @@
+package ui
+
+import "embed"
+
+//go:embed "email" "html" "static"
+var Files embed.FS
@@ -0,0 +1,23 @@
+// Service worker for recall notifications. The server sends JSON with the
+// title, body, url and tag of each new recall.
+self.addEventListener("push", (event) => {
+  if (!event.data) {
+    return;
+  }
+  const message = event.data.json();
+  event.waitUntil(
+    self.registration.showNotification(message.title, {
+      body: message.body,
+      tag: message.tag,
+      data: { url: message.url },
+    })
+  );
+});
+
+self.addEventListener("notificationclick", (event) => {
+  event.notification.close();
+  const url = event.notification.data && event.notification.data.url;
+  if (url) {
+    event.waitUntil(self.clients.openWindow(url));
+  }
+});